@@ -32,7 +32,7 @@ func generateSolutionProto(bpObj, bpDpObj *bpmetadata.BlueprintMetadata) (*gen_p
 	addDocumentationLink(solution)
 	addIsSingleton(solution)
 	addLocationConfigs(solution)
-	addOrgPolicyChecks(solution)
+	addOrgPolicyChecks(solution, bpObj)
 	addCloudProductIdentifiers(solution)
 
 	return solution, nil
@@ -124,25 +124,32 @@ func addVariables(solution *gen_protos.Solution, bpObj, bpDpObj *bpmetadata.Blue
 			IsHidden:   bpVariable.Invisible,
 			Validation: bpVariable.RegExValidation,
 		}
-		switch variable.VarType {
+		// prefer the structured type since VarType holds the full
+		// constraint e.g. list(string)
+		kind := variable.VarType
+		if variable.TypeInfo != nil {
+			kind = string(variable.TypeInfo.Kind)
+		}
+
+		switch kind {
 		case "string":
 			property.Type = gen_protos.Property_STRING
 			if variable.DefaultValue != nil {
 				property.DefaultValue = fmt.Sprintf("%v", variable.DefaultValue)
 			}
 			property.Pattern = bpVariable.RegExValidation
-			property.MaxLength = int32(bpVariable.Maximum)
-			property.MinLength = int32(bpVariable.Minimum)
+			property.MaxLength = int32(bpVariable.MaximumLength)
+			property.MinLength = int32(bpVariable.MinimumLength)
 
 		case "bool":
 			property.Type = gen_protos.Property_BOOLEAN
 			if variable.DefaultValue != nil {
 				property.DefaultValue = fmt.Sprintf("%v", variable.DefaultValue)
 			}
-		case "list":
+		case "list", "set", "tuple":
 			property.Type = gen_protos.Property_ARRAY
-			property.MaxItems = int32(bpVariable.Maximum)
-			property.MinItems = int32(bpVariable.Minimum)
+			property.MaxItems = int32(bpVariable.MaximumItems)
+			property.MinItems = int32(bpVariable.MinimumItems)
 		case "number":
 			// Note: tf metadata uses "number" type for both "integer" and "number" type.
 			// Hence, this might require manual update of textproto file.
@@ -150,8 +157,12 @@ func addVariables(solution *gen_protos.Solution, bpObj, bpDpObj *bpmetadata.Blue
 			if variable.DefaultValue != nil {
 				property.DefaultValue = fmt.Sprintf("%v", variable.DefaultValue)
 			}
-			property.Maximum = float32(bpVariable.Maximum)
-			property.Minimum = float32(bpVariable.Minimum)
+			if bpVariable.Maximum != nil {
+				property.Maximum = float32(*bpVariable.Maximum)
+			}
+			if bpVariable.Minimum != nil {
+				property.Minimum = float32(*bpVariable.Minimum)
+			}
 		}
 		solution.DeployData.InputSections = append(solution.DeployData.InputSections, &gen_protos.Section{
 			Properties: []*gen_protos.Property{property},
@@ -198,8 +209,26 @@ func addLocationConfigs(solution *gen_protos.Solution) {
 	solution.DeployData.LocationConfigs = []gen_protos.DeployData_DeployLocationConfig{gen_protos.DeployData_UNSPECIFIED}
 }
 
-// addOrgPolicyChecks adds org policy checks to the solution object.
-func addOrgPolicyChecks(solution *gen_protos.Solution) {
+// addOrgPolicyChecks adds org policy checks to the solution object from the
+// BlueprintMetadata object.
+func addOrgPolicyChecks(solution *gen_protos.Solution, bpObj *bpmetadata.BlueprintMetadata) {
+	for _, c := range bpObj.Spec.Info.OrgPolicyChecks {
+		id := c.PolicyId
+		if !strings.HasPrefix(id, "constraints/") {
+			id = "constraints/" + id
+		}
+
+		solution.DeployData.OrgPolicyChecks = append(solution.DeployData.OrgPolicyChecks, &gen_protos.OrgPolicyCheck{
+			Id:             id,
+			RequiredValues: c.RequiredValues,
+		})
+	}
+
+	if len(solution.DeployData.OrgPolicyChecks) > 0 {
+		return
+	}
+
+	// Placeholder for blueprints without org policy checks
 	solution.DeployData.OrgPolicyChecks = []*gen_protos.OrgPolicyCheck{{
 		Id:             "<Org policy constraint e.g. constraints/gcp.resourceLocations>",
 		RequiredValues: []string{"<required value 1>", "<required value 2>"},
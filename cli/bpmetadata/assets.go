@@ -0,0 +1,287 @@
+package bpmetadata
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gomarkdown/markdown"
+	"github.com/gomarkdown/markdown/ast"
+)
+
+const (
+	assetsDirPath = "assets"
+
+	// constraints for local assets referenced by metadata
+	iconMinPx       = 80
+	iconMaxBytes    = 1 << 20
+	diagramMinPx    = 200
+	diagramMaxBytes = 5 << 20
+)
+
+// assetContentTypes are the supported image formats for assets by
+// extension along with their detected content type
+var assetContentTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".svg":  "image/svg+xml",
+}
+
+// badgeHosts serve status badges, which aren't diagrams
+var badgeHosts = map[string]bool{
+	"img.shields.io": true,
+	"badge.fury.io":  true,
+}
+
+// getDiagrams discovers the diagrams for a blueprint from images in its
+// README.md and, for root modules, from image files in the assets/ dir.
+// Local diagrams are named by their path relative to the root module.
+func getDiagrams(bpPath, rootPath string, readmeContent []byte, cfg *metadataConfig) []BlueprintDiagram {
+	// images in the readme come with alt text so they take precedence
+	var diagrams []BlueprintDiagram
+	found := make(map[string]bool)
+	for _, d := range getReadmeDiagrams(bpPath, rootPath, readmeContent) {
+		if !found[d.Name] {
+			found[d.Name] = true
+			diagrams = append(diagrams, d)
+		}
+	}
+
+	if bpPath != rootPath {
+		return diagrams
+	}
+
+	files, err := os.ReadDir(path.Join(rootPath, assetsDirPath))
+	if err != nil {
+		return diagrams
+	}
+
+	for _, f := range files {
+		name := path.Join(assetsDirPath, f.Name())
+		if f.IsDir() || found[name] || name == cfg.IconFile || assetContentTypes[strings.ToLower(path.Ext(name))] == "" {
+			continue
+		}
+
+		diagrams = append(diagrams, BlueprintDiagram{
+			Name:    name,
+			AltText: altTextFromFileName(name),
+		})
+	}
+
+	return diagrams
+}
+
+// getReadmeDiagrams returns a diagram for each image in README.md that
+// isn't a badge. Images nested in links are treated as badges.
+func getReadmeDiagrams(bpPath, rootPath string, readmeContent []byte) []BlueprintDiagram {
+	var diagrams []BlueprintDiagram
+	ast.WalkFunc(markdown.Parse(readmeContent, nil), func(n ast.Node, entering bool) ast.WalkStatus {
+		if !entering {
+			return ast.GoToNext
+		}
+
+		if _, isLink := n.(*ast.Link); isLink {
+			return ast.SkipChildren
+		}
+
+		img, isImage := n.(*ast.Image)
+		if !isImage {
+			return ast.GoToNext
+		}
+
+		dest := string(img.Destination)
+		if u, err := url.Parse(dest); err != nil || badgeHosts[u.Host] {
+			return ast.SkipChildren
+		}
+
+		if isLocalAsset(dest) {
+			rel, err := filepath.Rel(rootPath, filepath.Join(bpPath, dest))
+			if err != nil {
+				return ast.SkipChildren
+			}
+
+			dest = filepath.ToSlash(rel)
+		}
+
+		var alt []string
+		for _, c := range img.Children {
+			if t := mdText(c); t != "" {
+				alt = append(alt, t)
+			}
+		}
+
+		diagrams = append(diagrams, BlueprintDiagram{
+			Name:        dest,
+			AltText:     strings.Join(alt, " "),
+			Description: string(img.Title),
+		})
+
+		return ast.SkipChildren
+	})
+
+	return diagrams
+}
+
+// altTextFromFileName creates alt text from an asset's file name
+// e.g. "Architecture diagram" for "assets/architecture_diagram.png"
+func altTextFromFileName(name string) string {
+	base := strings.TrimSuffix(path.Base(name), path.Ext(name))
+	words := strings.Fields(strings.NewReplacer("-", " ", "_", " ", ".", " ").Replace(base))
+	if len(words) == 0 {
+		return ""
+	}
+
+	alt := strings.Join(words, " ")
+	return strings.ToUpper(alt[:1]) + alt[1:]
+}
+
+// isLocalAsset returns true if the reference is a path rather than a URL
+func isLocalAsset(ref string) bool {
+	u, err := url.Parse(ref)
+	return err == nil && u.Scheme == "" && u.Host == "" && ref != ""
+}
+
+// assetRef is a local asset referenced by a metadata field, relative
+// to dir
+type assetRef struct {
+	field    []string
+	ref      string
+	dir      string
+	minPx    int
+	maxBytes int
+	square   bool
+}
+
+// validateMetadataAssets checks that the local assets referenced by the
+// core metadata of the blueprint at bpPath exist and meet the constraints
+// for their use. The architecture diagram is resolved relative to bpPath
+// as it comes from the blueprint's README, while other assets are resolved
+// relative to the root module at rootPath. Problems are returned as
+// warnings.
+func validateMetadataAssets(bpPath, rootPath string) []validationFinding {
+	bpObj, err := UnmarshalMetadata(bpPath, metadataFileName)
+	if err != nil {
+		return nil
+	}
+
+	var refs []assetRef
+	if bpObj.Spec.Info.Icon != "" {
+		refs = append(refs, assetRef{[]string{"spec", "info", "icon"}, bpObj.Spec.Info.Icon, rootPath, iconMinPx, iconMaxBytes, true})
+	}
+
+	if u := bpObj.Spec.Content.Architecture.DiagramURL; u != "" {
+		refs = append(refs, assetRef{[]string{"spec", "content", "architecture", "diagramUrl"}, u, bpPath, diagramMinPx, diagramMaxBytes, false})
+	}
+
+	for i, d := range bpObj.Spec.Content.Diagrams {
+		refs = append(refs, assetRef{[]string{"spec", "content", "diagrams", strconv.Itoa(i), "name"}, d.Name, rootPath, diagramMinPx, diagramMaxBytes, false})
+	}
+
+	m := path.Join(bpPath, metadataFileName)
+	var findings []validationFinding
+	for _, r := range refs {
+		if !isLocalAsset(r.ref) {
+			continue
+		}
+
+		for _, p := range checkAsset(path.Join(r.dir, r.ref), r) {
+			line, col := fieldPosition(m, r.field)
+			f := validationFinding{
+				File:    m,
+				Line:    line,
+				Column:  col,
+				Field:   strings.Join(r.field, "."),
+				Rule:    "asset",
+				Level:   levelWarning,
+				Message: fmt.Sprintf("%s: %s", r.ref, p),
+			}
+
+			Log.Warn("asset validation warning", "path", f.location(), "err", f.Message)
+			findings = append(findings, f)
+		}
+	}
+
+	return findings
+}
+
+// checkAsset returns the problems with the asset file at filePath
+func checkAsset(filePath string, r assetRef) []string {
+	info, err := os.Stat(filePath)
+	if err != nil || info.IsDir() {
+		return []string{"file does not exist"}
+	}
+
+	var problems []string
+	if info.Size() > int64(r.maxBytes) {
+		problems = append(problems, fmt.Sprintf("file size of %d bytes exceeds the maximum of %d bytes", info.Size(), r.maxBytes))
+	}
+
+	ext := strings.ToLower(path.Ext(filePath))
+	want, ok := assetContentTypes[ext]
+	if !ok {
+		return append(problems, fmt.Sprintf("unsupported format %q, use one of: %s", ext, strings.Join(supportedAssetExts(), ", ")))
+	}
+
+	b, err := os.ReadFile(filePath)
+	if err != nil {
+		return append(problems, fmt.Sprintf("unable to read file: %v", err))
+	}
+
+	if got := detectAssetContentType(b); got != want {
+		return append(problems, fmt.Sprintf("content is %s but the extension is %s", got, ext))
+	}
+
+	// dimensions aren't fixed for vector images
+	if want == assetContentTypes[".svg"] {
+		return problems
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return append(problems, fmt.Sprintf("unable to decode image: %v", err))
+	}
+
+	if cfg.Width < r.minPx || cfg.Height < r.minPx {
+		problems = append(problems, fmt.Sprintf("dimensions of %dx%d are below the minimum of %dx%d", cfg.Width, cfg.Height, r.minPx, r.minPx))
+	}
+
+	if r.square && cfg.Width != cfg.Height {
+		problems = append(problems, fmt.Sprintf("dimensions of %dx%d are not square", cfg.Width, cfg.Height))
+	}
+
+	return problems
+}
+
+// detectAssetContentType sniffs the content type of an asset, which
+// isn't detected for SVG images by http.DetectContentType
+func detectAssetContentType(b []byte) string {
+	t := strings.Split(http.DetectContentType(b), ";")[0]
+	if strings.HasPrefix(t, "text/") && bytes.Contains(b, []byte("<svg")) {
+		return assetContentTypes[".svg"]
+	}
+
+	return t
+}
+
+func supportedAssetExts() []string {
+	var exts []string
+	for ext := range assetContentTypes {
+		exts = append(exts, ext)
+	}
+
+	sort.Strings(exts)
+	return exts
+}
@@ -0,0 +1,153 @@
+package bpmetadata
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const diagramsReadme = `# Blueprint
+
+[![license](https://img.shields.io/badge/license-Apache-blue.svg)](LICENSE)
+![build](https://img.shields.io/badge/build-passing-green.svg)
+
+## Architecture
+![Architecture of the blueprint](assets/architecture.png "Request flow")
+
+![](https://example.com/remote.png)
+`
+
+func TestGetDiagrams(t *testing.T) {
+	root := t.TempDir()
+	sub := path.Join(root, "modules", "sub")
+	require.NoError(t, os.MkdirAll(path.Join(root, assetsDirPath), 0755))
+	require.NoError(t, os.MkdirAll(sub, 0755))
+	for _, f := range []string{"architecture.png", "icon.png", "data_flow-diagram.svg", "notes.txt"} {
+		require.NoError(t, os.WriteFile(path.Join(root, assetsDirPath, f), nil, 0644))
+	}
+
+	cfg := &metadataConfig{IconFile: iconFilePath}
+	got := getDiagrams(root, root, []byte(diagramsReadme), cfg)
+	assert.Equal(t, []BlueprintDiagram{
+		{Name: "assets/architecture.png", AltText: "Architecture of the blueprint", Description: "Request flow"},
+		{Name: "https://example.com/remote.png"},
+		{Name: "assets/data_flow-diagram.svg", AltText: "Data flow diagram"},
+	}, got)
+
+	// submodules only have diagrams from their readme, relative to the root
+	got = getDiagrams(sub, root, []byte("![Sub](../../assets/sub.png)\n\n![Again](../../assets/sub.png)"), cfg)
+	assert.Equal(t, []BlueprintDiagram{{Name: "assets/sub.png", AltText: "Sub"}}, got)
+}
+
+func TestDiagramsAreOwnedPerItem(t *testing.T) {
+	stale := BlueprintDiagram{Name: "assets/old.png", AltText: "Old"}
+	staleSum, err := checksum(stale)
+	require.NoError(t, err)
+
+	onDisk := &BlueprintMetadata{}
+	onDisk.Spec.Content.Diagrams = []BlueprintDiagram{
+		{Name: "assets/architecture.png", AltText: "Manual alt text"},
+		{Name: "https://example.com/manual.png"},
+		stale,
+	}
+
+	generated := &BlueprintMetadata{}
+	generated.Spec.Content.Diagrams = []BlueprintDiagram{
+		{Name: "assets/architecture.png", AltText: "Architecture"},
+		{Name: "assets/flow.png", AltText: "Flow"},
+	}
+
+	o := &metadataOwnership{Fields: map[string]*fieldOwnership{
+		"spec.content.diagrams[assets/architecture.png]":        {Owner: ownerManual},
+		"spec.content.diagrams[https://example.com/manual.png]": {Owner: ownerManual},
+		"spec.content.diagrams[assets/old.png]":                 {Owner: ownerAuto, Checksum: staleSum},
+	}}
+	_, err = mergeOwnedFields(onDisk, generated, o)
+	require.NoError(t, err)
+
+	// manually owned diagrams are kept and autogenerated ones that are no
+	// longer found are dropped
+	assert.Equal(t, []BlueprintDiagram{
+		{Name: "assets/architecture.png", AltText: "Manual alt text"},
+		{Name: "assets/flow.png", AltText: "Flow"},
+		{Name: "https://example.com/manual.png"},
+	}, generated.Spec.Content.Diagrams)
+	assert.NotContains(t, o.Fields, "spec.content.diagrams[assets/old.png]")
+}
+
+func TestValidateMetadataAssets(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(root, assetsDirPath), 0755))
+	writePNG(t, path.Join(root, "assets", "icon.png"), 100, 80)
+	writePNG(t, path.Join(root, "assets", "small.png"), 100, 100)
+	writePNG(t, path.Join(root, "assets", "ok.png"), 400, 300)
+	require.NoError(t, os.WriteFile(path.Join(root, "assets", "text.png"), []byte("not an image"), 0644))
+	require.NoError(t, os.WriteFile(path.Join(root, "assets", "flow.svg"), []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"></svg>`), 0644))
+	require.NoError(t, os.WriteFile(path.Join(root, "assets", "notes.txt"), []byte("notes"), 0644))
+
+	bpObj := &BlueprintMetadata{}
+	bpObj.APIVersion = metadataApiVersion
+	bpObj.Kind = metadataKind
+	bpObj.Spec.Info.Icon = "assets/icon.png"
+	bpObj.Spec.Content.Architecture.DiagramURL = "https://example.com/remote.png"
+	bpObj.Spec.Content.Diagrams = []BlueprintDiagram{
+		{Name: "assets/ok.png"},
+		{Name: "assets/flow.svg"},
+		{Name: "assets/small.png"},
+		{Name: "assets/missing.png"},
+		{Name: "assets/text.png"},
+		{Name: "assets/notes.txt"},
+	}
+	require.NoError(t, WriteMetadata(bpObj, root, metadataFileName))
+
+	got := validateMetadataAssets(root, root)
+	var msgs []string
+	for _, f := range got {
+		assert.Equal(t, levelWarning, f.Level)
+		assert.Equal(t, "asset", f.Rule)
+		assert.NotZero(t, f.Line)
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+
+	assert.Equal(t, []string{
+		"spec.info.icon: assets/icon.png: dimensions of 100x80 are not square",
+		"spec.content.diagrams.2.name: assets/small.png: dimensions of 100x100 are below the minimum of 200x200",
+		"spec.content.diagrams.3.name: assets/missing.png: file does not exist",
+		"spec.content.diagrams.4.name: assets/text.png: content is text/plain but the extension is .png",
+		`spec.content.diagrams.5.name: assets/notes.txt: unsupported format ".txt", use one of: .gif, .jpeg, .jpg, .png, .svg`,
+	}, msgs)
+}
+
+func writePNG(t *testing.T, filePath string, width, height int) {
+	f, err := os.Create(filePath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	require.NoError(t, png.Encode(f, image.NewRGBA(image.Rect(0, 0, width, height))))
+}
+
+func TestValidateSubmoduleAssets(t *testing.T) {
+	root := t.TempDir()
+	sub := path.Join(root, "modules", "sub")
+	require.NoError(t, os.MkdirAll(path.Join(sub, "assets"), 0755))
+	writePNG(t, path.Join(sub, "assets", "arch.png"), 400, 300)
+
+	// the architecture diagram is relative to the submodule's README
+	bpObj := &BlueprintMetadata{}
+	bpObj.APIVersion = metadataApiVersion
+	bpObj.Kind = metadataKind
+	bpObj.Spec.Content.Architecture.DiagramURL = "assets/arch.png"
+	require.NoError(t, WriteMetadata(bpObj, sub, metadataFileName))
+	assert.Empty(t, validateMetadataAssets(sub, root))
+
+	bpObj.Spec.Content.Architecture.DiagramURL = "assets/missing.png"
+	require.NoError(t, WriteMetadata(bpObj, sub, metadataFileName))
+	got := validateMetadataAssets(sub, root)
+	require.Len(t, got, 1)
+	assert.Equal(t, "assets/missing.png: file does not exist", got[0].Message)
+}
@@ -0,0 +1,241 @@
+package bpmetadata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+const (
+	statusGenerated = "generated"
+	statusUnchanged = "unchanged"
+	statusFailed    = "failed"
+)
+
+// skipRootDirs are dirs that never hold blueprint roots when
+// discovering blueprints recursively
+var skipRootDirs = map[string]bool{
+	"node_modules": true,
+	"test":         true,
+	"examples":     true,
+}
+
+// generationResult is the outcome of generating metadata for a single
+// blueprint or submodule
+type generationResult struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// findBlueprintRoots returns every dir under rootPath that has a readme and
+// Terraform config. Dirs under a blueprint root are not searched since
+// submodules are discovered for each root along with its config.
+func findBlueprintRoots(rootPath string) ([]string, error) {
+	var roots []string
+	err := filepath.WalkDir(rootPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		if p != rootPath && (strings.HasPrefix(d.Name(), ".") || skipRootDirs[d.Name()]) {
+			return filepath.SkipDir
+		}
+
+		isRoot, err := isBlueprintRoot(p)
+		if err != nil {
+			return err
+		}
+
+		if isRoot {
+			roots = append(roots, p)
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error discovering blueprints under path: %s. Details: %w", rootPath, err)
+	}
+
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("no blueprints found under path: %s", rootPath)
+	}
+
+	return roots, nil
+}
+
+func isBlueprintRoot(dir string) (bool, error) {
+	if _, err := os.Stat(filepath.Join(dir, readmeFileName)); err != nil {
+		return false, nil
+	}
+
+	tfFiles, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return false, err
+	}
+
+	return len(tfFiles) > 0, nil
+}
+
+// generateAll generates metadata for each path with at most parallelism
+// paths being generated at a time. gen reports whether it changed any
+// metadata files on disk. Results are in the order of paths.
+func generateAll(paths []string, parallelism int, gen func(string) (bool, error)) []generationResult {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	results := make([]generationResult, len(paths))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = generateWithStatus(paths[i], gen)
+			}
+		}()
+	}
+
+	for i := range paths {
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// generateWithStatus generates metadata for bpPath and reports whether any
+// of the metadata files for it changed on disk
+func generateWithStatus(bpPath string, gen func(string) (bool, error)) generationResult {
+	changed, err := gen(bpPath)
+	if err != nil {
+		return generationResult{Path: bpPath, Status: statusFailed, Reason: err.Error()}
+	}
+
+	if !changed {
+		return generationResult{Path: bpPath, Status: statusUnchanged}
+	}
+
+	return generationResult{Path: bpPath, Status: statusGenerated}
+}
+
+// failResult marks the result for bpPath as failed with the reason,
+// adding a result if there isn't one
+func failResult(results []generationResult, bpPath, reason string) []generationResult {
+	for i, r := range results {
+		if r.Path != bpPath {
+			continue
+		}
+
+		results[i].Status = statusFailed
+		results[i].Reason = strings.TrimSpace(strings.Join([]string{reason, r.Reason}, "\n"))
+		return results
+	}
+
+	return append(results, generationResult{Path: bpPath, Status: statusFailed, Reason: reason})
+}
+
+// countFailed returns the number of results that failed
+func countFailed(results []generationResult) int {
+	n := 0
+	for _, r := range results {
+		if r.Status == statusFailed {
+			n++
+		}
+	}
+
+	return n
+}
+
+// validateSummaryFormat checks that the summary can be written in format
+func validateSummaryFormat(format string) error {
+	switch format {
+	case formatText, formatJSON:
+		return nil
+	}
+
+	return fmt.Errorf("unsupported output format for generating metadata: %s. Supported formats are: %s, %s", format, formatText, formatJSON)
+}
+
+// relativeResults returns the results sorted by path, with paths relative
+// to the working dir
+func relativeResults(results []generationResult, wdPath string) []generationResult {
+	rel := make([]generationResult, 0, len(results))
+	for _, r := range results {
+		if p, err := filepath.Rel(wdPath, r.Path); err == nil {
+			r.Path = p
+		}
+
+		rel = append(rel, r)
+	}
+
+	sort.SliceStable(rel, func(i, j int) bool { return rel[i].Path < rel[j].Path })
+	return rel
+}
+
+// writeFailures writes the full reason for each failed result to w as is,
+// since reasons such as drift diffs span several lines
+func writeFailures(w io.Writer, results []generationResult, wdPath string) error {
+	for _, r := range relativeResults(results, wdPath) {
+		if r.Status != statusFailed {
+			continue
+		}
+
+		if _, err := fmt.Fprintf(w, "Error generating metadata for %s:\n%s\n\n", r.Path, r.Reason); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeSummary writes a summary of the results to w in the requested
+// format. Paths are reported relative to the working dir. Only the first
+// line of each reason is in the text table since full reasons are written
+// separately with writeFailures.
+func writeSummary(w io.Writer, results []generationResult, format, wdPath string) error {
+	rel := relativeResults(results, wdPath)
+	if format == formatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Blueprints []generationResult `json:"blueprints"`
+		}{rel})
+	}
+
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BLUEPRINT\tSTATUS\tREASON")
+	for _, r := range rel {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Path, r.Status, strings.SplitN(r.Reason, "\n", 2)[0])
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// trim the padding of rows without a reason
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n") {
+		if _, err := fmt.Fprintln(w, strings.TrimRight(line, " ")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
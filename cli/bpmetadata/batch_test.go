@@ -0,0 +1,189 @@
+package bpmetadata
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/cli/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindBlueprintRoots(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		"bp1/README.md",
+		"bp1/main.tf",
+		"bp1/modules/sub/README.md",
+		"bp1/modules/sub/main.tf",
+		"group/bp2/README.md",
+		"group/bp2/main.tf",
+		"group/README.md",
+		"docs-only/README.md",
+		"tf-only/main.tf",
+		"test/fixtures/README.md",
+		"test/fixtures/main.tf",
+		".terraform/modules/bp/README.md",
+		".terraform/modules/bp/main.tf",
+	}
+
+	for _, f := range files {
+		require.NoError(t, os.MkdirAll(path.Join(dir, path.Dir(f)), 0755))
+		require.NoError(t, os.WriteFile(path.Join(dir, f), nil, 0644))
+	}
+
+	got, err := findBlueprintRoots(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{path.Join(dir, "bp1"), path.Join(dir, "group", "bp2")}, got)
+
+	_, err = findBlueprintRoots(path.Join(dir, "docs-only"))
+	assert.Error(t, err)
+}
+
+func TestGenerateAll(t *testing.T) {
+	paths := []string{"a", "b", "c", "d", "e", "f"}
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	gen := func(p string) (bool, error) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+
+		if p == "c" {
+			return false, errors.New("bad config\nmore details")
+		}
+
+		return p == "b", nil
+	}
+
+	got := generateAll(paths, 2, gen)
+	require.Len(t, got, len(paths))
+	for i, r := range got {
+		assert.Equal(t, paths[i], r.Path)
+	}
+
+	assert.Equal(t, generationResult{Path: "c", Status: statusFailed, Reason: "bad config\nmore details"}, got[2])
+	assert.Equal(t, statusUnchanged, got[0].Status)
+	assert.Equal(t, statusGenerated, got[1].Status)
+	assert.LessOrEqual(t, maxRunning, 2)
+	assert.Equal(t, 1, countFailed(got))
+}
+
+func TestGenerateWithStatus(t *testing.T) {
+	dir := t.TempDir()
+	write := func(s string) func(string) (bool, error) {
+		return func(p string) (bool, error) {
+			return writeFileIfChanged(path.Join(p, metadataFileName), []byte(s))
+		}
+	}
+
+	assert.Equal(t, statusGenerated, generateWithStatus(dir, write("a")).Status)
+	assert.Equal(t, statusUnchanged, generateWithStatus(dir, write("a")).Status)
+	assert.Equal(t, statusGenerated, generateWithStatus(dir, write("b")).Status)
+}
+
+func TestGenerateAllBlueprints(t *testing.T) {
+	gitTags = util.NewTagCache()
+	t.Cleanup(func() { gitTags = nil })
+
+	var paths []string
+	for i := 0; i < 4; i++ {
+		paths = append(paths, tempBlueprint(t))
+	}
+
+	// blueprints are generated concurrently with the caches shared
+	// across them, and only report changes on the first run
+	for _, want := range []string{statusGenerated, statusUnchanged} {
+		got := generateAll(paths, 4, generateMetadataForBpPath)
+		require.Len(t, got, len(paths))
+		for i, r := range got {
+			assert.Equal(t, generationResult{Path: paths[i], Status: want}, r)
+		}
+	}
+
+	for _, p := range paths {
+		bpObj, err := UnmarshalMetadata(p, metadataFileName)
+		require.NoError(t, err)
+		assert.Equal(t, "terraform-google-simple-bucket", bpObj.Name)
+	}
+}
+
+func TestFailResult(t *testing.T) {
+	results := []generationResult{
+		{Path: "/bp", Status: statusGenerated},
+		{Path: "/bp/modules/sub", Status: statusFailed, Reason: "bad submodule"},
+	}
+
+	results = failResult(results, "/bp", "versions differ")
+	results = failResult(results, "/bp/modules/sub", "versions differ")
+	results = failResult(results, "/other", "no readme")
+	assert.Equal(t, []generationResult{
+		{Path: "/bp", Status: statusFailed, Reason: "versions differ"},
+		{Path: "/bp/modules/sub", Status: statusFailed, Reason: "versions differ\nbad submodule"},
+		{Path: "/other", Status: statusFailed, Reason: "no readme"},
+	}, results)
+}
+
+func TestWriteSummary(t *testing.T) {
+	results := []generationResult{
+		{Path: "/repo/bp2", Status: statusFailed, Reason: "bad config\nmore details"},
+		{Path: "/repo/bp1", Status: statusGenerated},
+		{Path: "/repo/bp1/modules/sub", Status: statusUnchanged},
+	}
+
+	t.Run("text", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, writeSummary(&b, results, formatText, "/repo"))
+		assert.Equal(t, `BLUEPRINT        STATUS     REASON
+bp1              generated
+bp1/modules/sub  unchanged
+bp2              failed     bad config
+`, b.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, writeSummary(&b, results, formatJSON, "/repo"))
+		assert.JSONEq(t, `{"blueprints": [
+			{"path": "bp1", "status": "generated"},
+			{"path": "bp1/modules/sub", "status": "unchanged"},
+			{"path": "bp2", "status": "failed", "reason": "bad config\nmore details"}
+		]}`, b.String())
+	})
+
+	assert.Error(t, validateSummaryFormat(formatSARIF))
+}
+
+func TestWriteFailures(t *testing.T) {
+	results := []generationResult{
+		{Path: "/repo/bp2", Status: statusFailed, Reason: "metadata.yaml is out of date\n-  title: a\n+  title: b"},
+		{Path: "/repo/bp1", Status: statusGenerated},
+		{Path: "/repo/bp0", Status: statusFailed, Reason: "no readme"},
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, writeFailures(&b, results, "/repo"))
+	assert.Equal(t, `Error generating metadata for bp0:
+no readme
+
+Error generating metadata for bp2:
+metadata.yaml is out of date
+-  title: a
++  title: b
+
+`, b.String())
+}
@@ -0,0 +1,56 @@
+package bpmetadata
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// checkMetadata compares generated metadata with the metadata file present
+// on disk for the blueprint and returns an error with a readable diff if
+// the two have drifted apart. The file on disk is never modified.
+func checkMetadata(generated *BlueprintMetadata, bpPath, fileName string) error {
+	metaFilePath := path.Join(bpPath, fileName)
+	if _, err := os.Stat(metaFilePath); err != nil {
+		return fmt.Errorf("%s does not exist for blueprint at path: %s", fileName, bpPath)
+	}
+
+	onDisk, err := UnmarshalMetadata(bpPath, fileName)
+	if err != nil {
+		return fmt.Errorf("error reading %s for blueprint at path: %s. Details: %w", fileName, bpPath, err)
+	}
+
+	d, err := diffMetadata(onDisk, generated)
+	if err != nil {
+		return fmt.Errorf("error comparing %s for blueprint at path: %s. Details: %w", fileName, bpPath, err)
+	}
+
+	if d != "" {
+		return fmt.Errorf("%s is out of date for blueprint at path: %s. Diff (-on disk +generated):\n%s", fileName, bpPath, d)
+	}
+
+	Log.Info("metadata is up to date", "path", metaFilePath)
+	return nil
+}
+
+// diffMetadata returns a structured diff between the two metadata objects
+// or an empty string if they are equivalent. The generated object is round
+// tripped through YAML before comparison so that values read back from disk
+// (e.g. numeric defaults) have the same types as freshly generated ones.
+func diffMetadata(onDisk, generated *BlueprintMetadata) (string, error) {
+	y, err := yaml.Marshal(generated)
+	if err != nil {
+		return "", err
+	}
+
+	normalized := BlueprintMetadata{}
+	if err := yaml.Unmarshal(y, &normalized); err != nil {
+		return "", err
+	}
+
+	return cmp.Diff(onDisk, &normalized, cmpopts.EquateEmpty()), nil
+}
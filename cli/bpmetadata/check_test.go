@@ -0,0 +1,139 @@
+package bpmetadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffMetadata(t *testing.T) {
+	tests := []struct {
+		name      string
+		onDisk    *BlueprintMetadata
+		generated *BlueprintMetadata
+		wantDiff  bool
+		wantParts []string
+	}{
+		{
+			name: "no drift",
+			onDisk: &BlueprintMetadata{
+				Spec: BlueprintMetadataSpec{
+					Info: BlueprintInfo{
+						Title: "Foo Blueprint",
+					},
+				},
+			},
+			generated: &BlueprintMetadata{
+				Spec: BlueprintMetadataSpec{
+					Info: BlueprintInfo{
+						Title: "Foo Blueprint",
+					},
+				},
+			},
+		},
+		{
+			name: "no drift with numeric default",
+			onDisk: &BlueprintMetadata{
+				Spec: BlueprintMetadataSpec{
+					Interfaces: BlueprintInterface{
+						Variables: []BlueprintVariable{
+							{
+								Name:         "node_count",
+								DefaultValue: 3,
+							},
+						},
+					},
+				},
+			},
+			generated: &BlueprintMetadata{
+				Spec: BlueprintMetadataSpec{
+					Interfaces: BlueprintInterface{
+						Variables: []BlueprintVariable{
+							{
+								Name:         "node_count",
+								DefaultValue: float64(3),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "no drift with empty lists",
+			onDisk: &BlueprintMetadata{
+				Spec: BlueprintMetadataSpec{
+					Requirements: BlueprintRequirements{
+						Services: []string{},
+					},
+				},
+			},
+			generated: &BlueprintMetadata{},
+		},
+		{
+			name: "title drift",
+			onDisk: &BlueprintMetadata{
+				Spec: BlueprintMetadataSpec{
+					Info: BlueprintInfo{
+						Title: "Foo Blueprint",
+					},
+				},
+			},
+			generated: &BlueprintMetadata{
+				Spec: BlueprintMetadataSpec{
+					Info: BlueprintInfo{
+						Title: "Bar Blueprint",
+					},
+				},
+			},
+			wantDiff:  true,
+			wantParts: []string{"Foo Blueprint", "Bar Blueprint"},
+		},
+		{
+			name: "new variable",
+			onDisk: &BlueprintMetadata{
+				Spec: BlueprintMetadataSpec{
+					Interfaces: BlueprintInterface{
+						Variables: []BlueprintVariable{
+							{
+								Name: "project_id",
+							},
+						},
+					},
+				},
+			},
+			generated: &BlueprintMetadata{
+				Spec: BlueprintMetadataSpec{
+					Interfaces: BlueprintInterface{
+						Variables: []BlueprintVariable{
+							{
+								Name: "project_id",
+							},
+							{
+								Name: "region",
+							},
+						},
+					},
+				},
+			},
+			wantDiff:  true,
+			wantParts: []string{"region"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := diffMetadata(tt.onDisk, tt.generated)
+			require.NoError(t, err)
+			if !tt.wantDiff {
+				assert.Empty(t, got)
+				return
+			}
+
+			assert.NotEmpty(t, got)
+			for _, p := range tt.wantParts {
+				assert.Contains(t, got, p)
+			}
+		})
+	}
+}
@@ -1,13 +1,16 @@
 package bpmetadata
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
 	"path"
+	"runtime"
 	"strings"
 
 	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/cli/util"
+	log "github.com/inconshreveable/log15"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
@@ -19,22 +22,43 @@ var mdFlags struct {
 	force    bool
 	display  bool
 	validate bool
+	check    bool
+	format   string
+
+	outputFormats []string
+	recursive     bool
+	parallelism   int
+
+	rolesFile       string
+	servicesFile    string
+	iconFile        string
+	modulesPath     string
+	examplesPath    string
+	rolesLocals     []string
+	servicesModules []string
 }
 
 const (
-	readmeFileName          = "README.md"
-	tfVersionsFileName      = "versions.tf"
-	tfRolesFileName         = "test/setup/iam.tf"
-	tfServicesFileName      = "test/setup/main.tf"
-	iconFilePath            = "assets/icon.png"
-	modulesPath             = "modules"
-	examplesPath            = "examples"
-	metadataFileName        = "metadata.yaml"
-	metadataDisplayFileName = "metadata.display.yaml"
-	metadataApiVersion      = "blueprints.cloud.google.com/v1alpha1"
-	metadataKind            = "BlueprintMetadata"
+	readmeFileName            = "README.md"
+	tfVersionsFileName        = "versions.tf"
+	tfRolesFileName           = "test/setup/iam.tf"
+	tfServicesFileName        = "test/setup/main.tf"
+	iconFilePath              = "assets/icon.png"
+	modulesPath               = "modules"
+	examplesPath              = "examples"
+	metadataFileName          = "metadata.yaml"
+	metadataDisplayFileName   = "metadata.display.yaml"
+	metadataOwnershipFileName = "metadata.ownership.yaml"
+	metadataConfigFileName    = ".cft/metadata.yaml"
+	metadataApiVersion        = metadataApiGroup + "/v1alpha1"
+	metadataKind              = "BlueprintMetadata"
 )
 
+// gitTags caches the git tags of repos for one run of generate so that
+// they are resolved once per repo. Tags are resolved on every call when
+// it isn't set.
+var gitTags *util.TagCache
+
 func init() {
 	viper.AutomaticEnv()
 
@@ -42,15 +66,71 @@ func init() {
 	Cmd.Flags().BoolVarP(&mdFlags.force, "force", "f", false, "Force the generation of fresh metadata.")
 	Cmd.Flags().StringVarP(&mdFlags.path, "path", "p", ".", "Path to the blueprint for generating metadata.")
 	Cmd.Flags().BoolVar(&mdFlags.nested, "nested", true, "Flag for generating metadata for nested blueprint, if any.")
-	Cmd.Flags().BoolVarP(&mdFlags.validate, "validate", "v", false, "Validate metadata against the schema definition.")
+	Cmd.Flags().BoolVarP(&mdFlags.validate, "validate", "v", false, "Validate metadata against the schema definition, along with references across metadata files, local assets, module dependencies and versions.")
+	Cmd.Flags().StringVar(&mdFlags.format, "format", formatText, "Output format for validation results or the generation summary. One of: text, json, sarif. sarif is only supported for validation.")
+	Cmd.Flags().BoolVar(&mdFlags.check, "check", false, "Check that metadata on disk is up to date without writing any files. Manually owned fields that conflict with the generated value and versions that disagree across the blueprint fail the check.")
+	Cmd.Flags().BoolVarP(&mdFlags.recursive, "recursive", "r", false, "Generate metadata for every blueprint found under the path. A blueprint is a dir with a readme and Terraform config.")
+	Cmd.Flags().IntVar(&mdFlags.parallelism, "parallelism", runtime.NumCPU(), "Number of blueprints and submodules to generate metadata for at a time.")
+	Cmd.Flags().StringSliceVar(&mdFlags.outputFormats, "output-formats", nil, "Additional formats to write metadata in alongside YAML. Any of: json, textproto, proto.")
+	Cmd.Flags().StringVar(&mdFlags.rolesFile, "roles-file", "", "Path to the Terraform config listing required roles, relative to the blueprint root. Defaults to "+tfRolesFileName+".")
+	Cmd.Flags().StringVar(&mdFlags.servicesFile, "services-file", "", "Path to the Terraform config listing required services, relative to the blueprint root. Defaults to "+tfServicesFileName+".")
+	Cmd.Flags().StringVar(&mdFlags.iconFile, "icon-file", "", "Path to the blueprint icon, relative to the blueprint root. Defaults to "+iconFilePath+".")
+	Cmd.Flags().StringVar(&mdFlags.modulesPath, "modules-path", "", "Path to the dir holding submodules, relative to the blueprint root. Defaults to "+modulesPath+".")
+	Cmd.Flags().StringVar(&mdFlags.examplesPath, "examples-path", "", "Path to the dir holding examples, relative to the blueprint root. Defaults to "+examplesPath+".")
+	Cmd.Flags().StringSliceVar(&mdFlags.rolesLocals, "roles-locals", nil, "Keys of the locals in the roles file that hold required roles. Defaults to all lists of roles.")
+	Cmd.Flags().StringSliceVar(&mdFlags.servicesModules, "services-modules", nil, "Names of the module blocks in the services file whose activate_apis hold required services. Defaults to the first one.")
 }
 
 var Cmd = &cobra.Command{
 	Use:   "metadata",
 	Short: "Generates blueprint metatda",
-	Long:  `Generates metadata.yaml for specified blueprint`,
-	Args:  cobra.NoArgs,
-	RunE:  generate,
+	Long: `Generates metadata.yaml for specified blueprint
+
+	Generate core and display metadata for a blueprint and its submodules:
+	  cft blueprint metadata -p <SOLUTION_ROOT_PATH> -d
+
+	Generate metadata for every blueprint under a path e.g. in a monorepo, where a blueprint
+	is a dir with a README.md and Terraform config. Hidden, test and examples dirs are skipped:
+	  cft blueprint metadata -p <REPO_ROOT_PATH> --recursive --parallelism 8 --format json
+
+	Check that metadata is up to date without writing any files, e.g. in CI. A diff is printed
+	for every file that is out of date:
+	  cft blueprint metadata -d --check
+
+	Validate metadata and write the results as SARIF for annotating code reviews:
+	  cft blueprint metadata -v --format sarif > results.sarif
+
+	Write metadata as JSON, textproto or binary proto next to the YAML files:
+	  cft blueprint metadata --output-formats json,textproto,proto
+
+	Autogenerated fields that are edited by hand, or set to "manual" in metadata.ownership.yaml,
+	are kept on later runs. A warning is logged when they conflict with the generated value.
+
+	Blueprints that don't follow the CFT module template can set where metadata is discovered
+	from in .cft/metadata.yaml, found in the blueprint dir or its parents up to the repo root:
+	  rolesFile: infra/setup/iam.tf
+	  servicesFile: infra/setup/main.tf
+	  iconFile: assets/icon.png
+	  modulesPath: modules
+	  examplesPath: examples
+	  tagPrefix: foo-
+	  rolesLocals: [required_roles]
+	  servicesModules: [project]
+	  orgPolicyRules:
+	  - policy: storage.uniformBucketLevelAccess
+	    resourceTypes: [google_storage_bucket]
+	    attributes: [uniform_bucket_level_access]
+
+	Flags take precedence over the config file. Org policy rules replace the built-in rules
+	for the same policy.
+
+	The blueprint version is read from the provider_meta block in versions.tf, falling back to
+	the nearest semver git tag, .release-please-manifest.json and CHANGELOG.md in that order.
+	Blueprints in a monorepo are tagged with the name of their dir e.g. foo-v1.2.0 unless
+	tagPrefix is set.
+	`,
+	Args: cobra.NoArgs,
+	RunE: generate,
 }
 
 // The top-level command function that generates metadata based on the provided flags
@@ -62,47 +142,156 @@ func generate(cmd *cobra.Command, args []string) error {
 
 	// validate metadata if there is an argument passed into the command
 	if mdFlags.validate {
-		if err := validateMetadata(mdFlags.path, wdPath); err != nil {
+		if err := validateFormat(mdFlags.format); err != nil {
+			return err
+		}
+
+		// keep stdout free of logs when it carries a machine readable report
+		if mdFlags.format != formatText {
+			Log.SetHandler(log.StderrHandler)
+		}
+
+		if err := validateMetadata(cmd.OutOrStdout(), mdFlags.path, wdPath, mdFlags.format); err != nil {
 			return err
 		}
 
 		return nil
 	}
 
+	if err := validateOutputFormats(mdFlags.outputFormats); err != nil {
+		return err
+	}
+
+	if err := validateSummaryFormat(mdFlags.format); err != nil {
+		return err
+	}
+
+	// keep stdout free of logs when it carries a machine readable summary
+	if mdFlags.format != formatText {
+		Log.SetHandler(log.StderrHandler)
+	}
+
 	currBpPath := mdFlags.path
 	if !path.IsAbs(mdFlags.path) {
 		currBpPath = path.Join(wdPath, mdFlags.path)
 	}
 
+	gitTags = util.NewTagCache()
+	defer func() { gitTags = nil }()
+
+	rootPaths := []string{currBpPath}
+	if mdFlags.recursive {
+		rootPaths, err = findBlueprintRoots(currBpPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// errors found before generation are reported along with the
+	// results for the blueprint they were found for
+	rootErrs := make(map[string]string)
 	var allBpPaths []string
-	_, err = os.Stat(path.Join(currBpPath, readmeFileName))
+	for _, rootPath := range rootPaths {
+		bpPaths, errs := getGenerationPaths(rootPath)
+		allBpPaths = append(allBpPaths, bpPaths...)
+		if errs != nil {
+			rootErrs[rootPath] = errs.Error()
+		}
+	}
+
+	results := generateAll(allBpPaths, mdFlags.parallelism, generateMetadataForBpPath)
+	for _, rootPath := range rootPaths {
+		if reason, ok := rootErrs[rootPath]; ok {
+			results = failResult(results, rootPath, reason)
+		}
+	}
+
+	// without --recursive, failures are returned as is unless a summary
+	// is explicitly requested in a machine readable format
+	if !mdFlags.recursive && mdFlags.format == formatText {
+		var errs []string
+		for _, r := range results {
+			if r.Status == statusFailed {
+				errs = append(errs, r.Reason)
+			}
+		}
+
+		return joinErrors(errs)
+	}
+
+	if err := writeSummary(cmd.OutOrStdout(), results, mdFlags.format, wdPath); err != nil {
+		return err
+	}
+
+	if err := writeFailures(cmd.ErrOrStderr(), results, wdPath); err != nil {
+		return err
+	}
+
+	if failed := countFailed(results); failed > 0 {
+		return fmt.Errorf("metadata generation failed for %d of %d blueprints", failed, len(results))
+	}
+
+	return nil
+}
+
+// getGenerationPaths returns the paths to generate metadata for under a
+// blueprint root, along with any errors for the root itself
+func getGenerationPaths(rootPath string) ([]string, error) {
+	cfg, err := loadMetadataConfig(rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []string
+
+	// check that the versions declared across the blueprint agree
+	// when checking for drift
+	if mdFlags.check {
+		if err := checkVersionConsistency(rootPath, cfg); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	allBpPaths, err := getBlueprintPaths(rootPath, mdFlags.nested, cfg)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	return allBpPaths, joinErrors(errs)
+}
+
+// getBlueprintPaths returns the path for the blueprint at rootPath and,
+// if nested, the paths for its submodules. Submodules without a readme are
+// internal and skipped. Paths discovered before an error are returned along
+// with it.
+func getBlueprintPaths(rootPath string, nested bool, cfg *metadataConfig) ([]string, error) {
+	_, err := os.Stat(path.Join(rootPath, readmeFileName))
 
 	// throw an error and exit if root level readme.md doesn't exist
 	if err != nil {
-		return fmt.Errorf("Top-level module does not have a readme. Details: %w\n", err)
+		return nil, fmt.Errorf("Top-level module does not have a readme. Details: %w\n", err)
 	}
 
-	allBpPaths = append(allBpPaths, currBpPath)
-	var errors []string
+	allBpPaths := []string{rootPath}
+	if !nested {
+		return allBpPaths, nil
+	}
 
 	// if nested, check if modules/ exists and create paths
 	// for submodules
-	if mdFlags.nested {
-		modulesPathforBp := path.Join(currBpPath, modulesPath)
-		_, err = os.Stat(modulesPathforBp)
-		if os.IsNotExist(err) {
-			Log.Info("sub-modules do not exist for this blueprint")
-		} else {
-			moduleDirs, err := util.WalkTerraformDirs(modulesPathforBp)
-			if err != nil {
-				errors = append(errors, err.Error())
-			} else {
-				allBpPaths = append(allBpPaths, moduleDirs...)
-			}
-		}
+	modulesPathforBp := path.Join(rootPath, cfg.ModulesPath)
+	_, err = os.Stat(modulesPathforBp)
+	if os.IsNotExist(err) {
+		Log.Info("sub-modules do not exist for this blueprint")
+		return allBpPaths, nil
 	}
 
-	for _, modPath := range allBpPaths {
+	moduleDirs, err := util.WalkTerraformDirs(modulesPathforBp)
+	if err != nil {
+		return allBpPaths, err
+	}
+
+	for _, modPath := range moduleDirs {
 		// check if module path has readme.md
 		_, err := os.Stat(path.Join(modPath, readmeFileName))
 
@@ -112,67 +301,144 @@ func generate(cmd *cobra.Command, args []string) error {
 			continue
 		}
 
-		err = generateMetadataForBpPath(modPath)
-		if err != nil {
-			errors = append(errors, err.Error())
-		}
-	}
-
-	if len(errors) > 0 {
-		return fmt.Errorf(strings.Join(errors, "\n"))
+		allBpPaths = append(allBpPaths, modPath)
 	}
 
-	return nil
+	return allBpPaths, nil
 }
 
-func generateMetadataForBpPath(bpPath string) error {
+// generateMetadataForBpPath generates metadata for the blueprint at bpPath
+// and reports whether any of its metadata files changed on disk
+func generateMetadataForBpPath(bpPath string) (bool, error) {
 	//try to read existing metadata.yaml
 	bpObj, err := UnmarshalMetadata(bpPath, metadataFileName)
 	if err != nil && !mdFlags.force {
-		return err
+		return false, err
 	}
 
+	// keep a copy of the metadata on disk since bpObj is updated in place
+	// during creation
+	bpDiskObj, _ := UnmarshalMetadata(bpPath, metadataFileName)
+
 	// create core metadata
 	bpMetaObj, err := CreateBlueprintMetadata(bpPath, bpObj)
 	if err != nil {
-		return fmt.Errorf("error creating metadata for blueprint at path: %s. Details: %w", bpPath, err)
+		return false, fmt.Errorf("error creating metadata for blueprint at path: %s. Details: %w", bpPath, err)
 	}
 
-	// write core metadata to disk
-	err = WriteMetadata(bpMetaObj, bpPath, metadataFileName)
+	// preserve manually owned fields from the metadata on disk
+	ownership, err := readOwnership(bpPath)
 	if err != nil {
-		return fmt.Errorf("error writing metadata to disk for blueprint at path: %s. Details: %w", bpPath, err)
+		return false, fmt.Errorf("error reading metadata ownership for blueprint at path: %s. Details: %w", bpPath, err)
+	}
+
+	conflicts, err := mergeOwnedFields(bpDiskObj, bpMetaObj, ownership)
+	if err != nil {
+		return false, fmt.Errorf("error merging metadata for blueprint at path: %s. Details: %w", bpPath, err)
+	}
+
+	// track whether any of the files written for the blueprint changed
+	changed := false
+	written := func(c bool, err error) error {
+		changed = changed || c
+		return err
+	}
+
+	// compare core metadata with the file on disk if checking for drift,
+	// else write core metadata to disk. Conflicts fail the check since a
+	// manually owned value is stale once its source disagrees with it.
+	var errs []string
+	if mdFlags.check {
+		for _, c := range conflicts {
+			errs = append(errs, fmt.Sprintf("metadata conflict for blueprint at path: %s. Details: %s", bpPath, c))
+		}
+
+		if err := checkMetadata(bpMetaObj, bpPath, metadataFileName); err != nil {
+			errs = append(errs, err.Error())
+		}
+	} else {
+		for _, c := range conflicts {
+			Log.Warn("metadata conflict", "path", bpPath, "details", c)
+		}
+
+		err = written(writeMetadata(bpMetaObj, bpPath, metadataFileName))
+		if err != nil {
+			return changed, fmt.Errorf("error writing metadata to disk for blueprint at path: %s. Details: %w", bpPath, err)
+		}
+
+		err = written(writeOwnership(ownership, bpPath))
+		if err != nil {
+			return changed, fmt.Errorf("error writing metadata ownership to disk for blueprint at path: %s. Details: %w", bpPath, err)
+		}
+
+		err = written(writeMetadataFormats(bpMetaObj, bpPath, metadataFileName, mdFlags.outputFormats))
+		if err != nil {
+			return changed, fmt.Errorf("error writing metadata formats to disk for blueprint at path: %s. Details: %w", bpPath, err)
+		}
 	}
 
 	// continue with creating display metadata if the flag is set,
 	// else let the command exit
 	if !mdFlags.display {
-		return nil
+		return changed, joinErrors(errs)
 	}
 
 	bpDpObj, err := UnmarshalMetadata(bpPath, metadataDisplayFileName)
 	if err != nil && !mdFlags.force {
-		return err
+		return changed, err
 	}
 
 	// create display metadata
-	bpMetaDpObj, err := CreateBlueprintDisplayMetadata(bpPath, bpDpObj, bpMetaObj)
+	bpMetaDpObj, err := CreateBlueprintDisplayMetadata(bpPath, bpDpObj, bpMetaObj, ownership)
 	if err != nil {
-		return fmt.Errorf("error creating display metadata for blueprint at path: %s. Details: %w", bpPath, err)
+		return changed, fmt.Errorf("error creating display metadata for blueprint at path: %s. Details: %w", bpPath, err)
+	}
+
+	if mdFlags.check {
+		if err := checkMetadata(bpMetaDpObj, bpPath, metadataDisplayFileName); err != nil {
+			errs = append(errs, err.Error())
+		}
+
+		return changed, joinErrors(errs)
 	}
 
 	// write display metadata to disk
-	err = WriteMetadata(bpMetaDpObj, bpPath, metadataDisplayFileName)
+	err = written(writeMetadata(bpMetaDpObj, bpPath, metadataDisplayFileName))
 	if err != nil {
-		return fmt.Errorf("error writing display metadata to disk for blueprint at path: %s. Details: %w", bpPath, err)
+		return changed, fmt.Errorf("error writing display metadata to disk for blueprint at path: %s. Details: %w", bpPath, err)
 	}
 
-	return nil
+	err = written(writeMetadataFormats(bpMetaDpObj, bpPath, metadataDisplayFileName, mdFlags.outputFormats))
+	if err != nil {
+		return changed, fmt.Errorf("error writing display metadata formats to disk for blueprint at path: %s. Details: %w", bpPath, err)
+	}
+
+	// the ownership of generated display fields is tracked as well
+	err = written(writeOwnership(ownership, bpPath))
+	if err != nil {
+		return changed, fmt.Errorf("error writing metadata ownership to disk for blueprint at path: %s. Details: %w", bpPath, err)
+	}
+
+	return changed, nil
+}
+
+// joinErrors combines error messages into a single error, if any
+func joinErrors(errs []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errors.New(strings.Join(errs, "\n"))
 }
 
 func CreateBlueprintMetadata(bpPath string, bpMetadataObj *BlueprintMetadata) (*BlueprintMetadata, error) {
+	cfg, err := loadMetadataConfig(bpPath)
+	if err != nil {
+		return nil, err
+	}
+
 	// verfiy that the blueprint path is valid & get repo details
-	repoDetails, err := getRepoDetailsByPath(bpPath, bpMetadataObj.Spec.Info.Source)
+	repoDetails, err := getRepoDetailsByPath(bpPath, bpMetadataObj.Spec.Info.Source, cfg.ModulesPath)
 	if err != nil {
 		return nil, err
 	}
@@ -200,7 +466,7 @@ func CreateBlueprintMetadata(bpPath string, bpMetadataObj *BlueprintMetadata) (*
 	}
 
 	// create blueprint info
-	err = bpMetadataObj.Spec.Info.create(bpPath, readmeContent)
+	err = bpMetadataObj.Spec.Info.create(bpPath, readmeContent, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("error creating blueprint info: %w", err)
 	}
@@ -212,9 +478,9 @@ func CreateBlueprintMetadata(bpPath string, bpMetadataObj *BlueprintMetadata) (*
 	}
 
 	// get blueprint requirements
-	rolesCfgPath := path.Join(repoDetails.Source.RootPath, tfRolesFileName)
-	svcsCfgPath := path.Join(repoDetails.Source.RootPath, tfServicesFileName)
-	requirements, err := getBlueprintRequirements(rolesCfgPath, svcsCfgPath)
+	rolesCfgPath := path.Join(repoDetails.Source.RootPath, cfg.RolesFile)
+	svcsCfgPath := path.Join(repoDetails.Source.RootPath, cfg.ServicesFile)
+	requirements, err := getBlueprintRequirements(rolesCfgPath, svcsCfgPath, bpPath, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("error creating blueprint requirements: %w", err)
 	}
@@ -222,12 +488,12 @@ func CreateBlueprintMetadata(bpPath string, bpMetadataObj *BlueprintMetadata) (*
 	bpMetadataObj.Spec.Requirements = *requirements
 
 	// create blueprint content i.e. documentation, icons, etc.
-	bpMetadataObj.Spec.Content.create(bpPath, repoDetails.Source.RootPath, readmeContent)
+	bpMetadataObj.Spec.Content.create(bpPath, repoDetails.Source.RootPath, getSourceRepoUrl(bpPath, repoDetails.Source), readmeContent, cfg)
 
 	return bpMetadataObj, nil
 }
 
-func CreateBlueprintDisplayMetadata(bpPath string, bpDisp, bpCore *BlueprintMetadata) (*BlueprintMetadata, error) {
+func CreateBlueprintDisplayMetadata(bpPath string, bpDisp, bpCore *BlueprintMetadata, o *metadataOwnership) (*BlueprintMetadata, error) {
 	// start creating blueprint metadata
 	bpDisp.ResourceMeta = yaml.ResourceMeta{
 		TypeMeta: yaml.TypeMeta{
@@ -252,30 +518,82 @@ func CreateBlueprintDisplayMetadata(bpPath string, bpDisp, bpCore *BlueprintMeta
 
 	buildUIInputFromVariables(bpCore.Spec.Interfaces.Variables, &bpDisp.Spec.UI.Input)
 
+	// infer constraints for display variables from validation blocks
+	constraints, err := getVariableConstraints(bpPath)
+	if err != nil {
+		Log.Warn("unable to infer constraints from variable validations", "path", bpPath, "err", err)
+	} else {
+		applyVariableConstraints(bpCore.Spec.Interfaces.Variables, constraints, &bpDisp.Spec.UI.Input, o)
+	}
+
+	// propose extensions for display variables based on their names and
+	// how they are used in resources
+	usages, err := getVariableUsages(bpPath)
+	if err != nil {
+		Log.Warn("unable to infer extensions from variable usages", "path", bpPath, "err", err)
+	} else {
+		applyVariableExtensions(bpCore.Spec.Interfaces.Variables, usages, &bpDisp.Spec.UI.Input, o)
+	}
+
+	// show outputs that are links, IP addresses or SSH commands once the
+	// blueprint is deployed
+	details, err := getInterfaceDetails(bpPath)
+	if err != nil {
+		Log.Warn("unable to infer runtime outputs from output values", "path", bpPath, "err", err)
+		details = &interfaceDetails{}
+	}
+
+	buildUIOutputFromOutputs(bpCore.Spec.Interfaces.Outputs, details, &bpDisp.Spec.UI.Runtime)
+
 	return bpDisp, nil
 }
 
-func (i *BlueprintInfo) create(bpPath string, readmeContent []byte) error {
+func (i *BlueprintInfo) create(bpPath string, readmeContent []byte, cfg *metadataConfig) error {
 	title, err := getMdContent(readmeContent, 1, 1, "", false)
 	if err != nil {
 		return err
 	}
 
 	i.Title = title.literal
-	repoDetails, err := getRepoDetailsByPath(bpPath, i.Source)
+	repoDetails, err := getRepoDetailsByPath(bpPath, i.Source, cfg.ModulesPath)
 	if err != nil {
 		return err
 	}
 
 	i.Source = &BlueprintRepoDetail{
 		Repo:       repoDetails.Source.Path,
-		SourceType: "git",
+		SourceType: repoDetails.Source.SourceType,
+		Ref:        repoDetails.Source.Ref,
+	}
+
+	// submodules are located by their path within the source
+	dir := repoDetails.Source.Dir
+	if submodule := getBpSubmoduleName(bpPath, cfg.ModulesPath); submodule != "" {
+		dir = path.Join(dir, cfg.ModulesPath, submodule)
 	}
 
-	if dir := getBpSubmoduleName(bpPath); dir != "" {
+	if dir != "" && dir != "." {
 		i.Source.Dir = dir
 	}
 
+	// propose org policy checks for the resources the blueprint creates,
+	// the ones that are manually authored are kept through their ownership
+	checks, err := getOrgPolicyChecks(bpPath, withDefaultOrgPolicyRules(cfg.OrgPolicyRules))
+	if err != nil {
+		Log.Warn("unable to infer org policy checks from resources", "path", bpPath, "err", err)
+	} else {
+		i.OrgPolicyChecks = checks
+	}
+
+	// estimate quotas from the compute resources the blueprint creates,
+	// quotas that are manually authored are kept through their ownership
+	quotas, err := getQuotaDetails(bpPath)
+	if err != nil {
+		Log.Warn("unable to estimate quotas from resources", "path", bpPath, "err", err)
+	} else {
+		i.QuotaDetails = quotas
+	}
+
 	versionInfo, err := getBlueprintVersion(path.Join(bpPath, tfVersionsFileName))
 	if err == nil {
 		i.Version = versionInfo.moduleVersion
@@ -285,8 +603,23 @@ func (i *BlueprintInfo) create(bpPath string, readmeContent []byte) error {
 		}
 	}
 
-	// create descriptions
-	i.Description = &BlueprintDescription{}
+	if i.Version == "" {
+		i.Version = getFallbackVersion(repoDetails.Source.RootPath, getTagPrefix(repoDetails.Source.RootPath, cfg))
+	}
+
+	if i.Source.SourceType == sourceTypeRegistry && i.Version != "" {
+		i.Source.Ref = i.Version
+	}
+
+	// create descriptions while keeping the ones that are
+	// manually authored
+	desc := &BlueprintDescription{}
+	if i.Description != nil {
+		desc.HTML = i.Description.HTML
+		desc.EulaURLs = i.Description.EulaURLs
+	}
+
+	i.Description = desc
 	tagline, err := getMdContent(readmeContent, -1, -1, "Tagline", true)
 	if err == nil {
 		i.Description.Tagline = tagline.literal
@@ -313,10 +646,10 @@ func (i *BlueprintInfo) create(bpPath string, readmeContent []byte) error {
 	}
 
 	// create icon
-	iPath := path.Join(repoDetails.Source.RootPath, iconFilePath)
+	iPath := path.Join(repoDetails.Source.RootPath, cfg.IconFile)
 	exists, _ := fileExists(iPath)
 	if exists {
-		i.Icon = iconFilePath
+		i.Icon = cfg.IconFile
 	}
 
 	d, err := getDeploymentDuration(readmeContent, "Deployment Duration")
@@ -329,6 +662,14 @@ func (i *BlueprintInfo) create(bpPath string, readmeContent []byte) error {
 		i.CostEstimate = *c
 	}
 
+	// report the sections expected in a blueprint readme that couldn't be
+	// used, which submodules aren't expected to have
+	if getBpSubmoduleName(bpPath, cfg.ModulesPath) == "" {
+		for _, issue := range checkReadmeSections(readmeContent) {
+			Log.Warn("unable to use readme section for metadata", "path", bpPath, "section", issue.section, "problem", issue.problem)
+		}
+	}
+
 	return nil
 }
 
@@ -344,7 +685,7 @@ func (i *BlueprintInterface) create(bpPath string) error {
 	return nil
 }
 
-func (c *BlueprintContent) create(bpPath string, rootPath string, readmeContent []byte) {
+func (c *BlueprintContent) create(bpPath, rootPath, repoURL string, readmeContent []byte, cfg *metadataConfig) {
 	var docListToSet []BlueprintListContent
 	documentation, err := getMdContent(readmeContent, -1, -1, "Documentation", true)
 	if err == nil {
@@ -366,29 +707,66 @@ func (c *BlueprintContent) create(bpPath string, rootPath string, readmeContent
 		c.Architecture = *a
 	}
 
+	// discover diagrams, the ones that are manually authored are kept
+	// through their ownership
+	c.Diagrams = getDiagrams(bpPath, rootPath, readmeContent, cfg)
+
 	// create sub-blueprints
-	modPath := path.Join(bpPath, modulesPath)
-	modContent, err := getModules(modPath)
+	modPath := path.Join(bpPath, cfg.ModulesPath)
+	modContent, err := getModules(modPath, cfg.ModulesPath)
 	if err == nil {
 		c.SubBlueprints = modContent
 	}
 
 	// create examples
-	exPath := path.Join(rootPath, examplesPath)
-	exContent, err := getExamples(exPath)
+	exPath := path.Join(rootPath, cfg.ExamplesPath)
+	exContent, err := getExamples(exPath, cfg.ExamplesPath)
 	if err == nil {
+		// record the blueprint and sub-blueprints each example uses
+		for i, ex := range exContent {
+			modules, err := getExampleModules(path.Join(rootPath, ex.Location), rootPath, repoURL, cfg.ModulesPath)
+			if err != nil {
+				Log.Warn("unable to find the modules used by example", "path", ex.Location, "err", err)
+				continue
+			}
+
+			exContent[i].Modules = modules
+		}
+
 		c.Examples = exContent
 	}
 }
 
 func WriteMetadata(obj *BlueprintMetadata, bpPath, fileName string) error {
+	_, err := writeMetadata(obj, bpPath, fileName)
+	return err
+}
+
+// writeMetadata writes metadata to the file at bpPath and reports whether
+// the file changed
+func writeMetadata(obj *BlueprintMetadata, bpPath, fileName string) (bool, error) {
 	// marshal and write the file
 	yFile, err := yaml.Marshal(obj)
 	if err != nil {
-		return err
+		return false, err
+	}
+
+	return writeFileIfChanged(path.Join(bpPath, fileName), yFile)
+}
+
+// writeFileIfChanged writes b to the file at p unless the file already
+// has the same contents and reports whether it was written
+func writeFileIfChanged(p string, b []byte) (bool, error) {
+	curr, err := os.ReadFile(p)
+	if err == nil && bytes.Equal(curr, b) {
+		return false, nil
 	}
 
-	return os.WriteFile(path.Join(bpPath, fileName), yFile, 0644)
+	if err := os.WriteFile(p, b, 0644); err != nil {
+		return false, err
+	}
+
+	return true, nil
 }
 
 func UnmarshalMetadata(bpPath, fileName string) (*BlueprintMetadata, error) {
@@ -414,13 +792,33 @@ func UnmarshalMetadata(bpPath, fileName string) (*BlueprintMetadata, error) {
 	currKind := bpObj.ResourceMeta.TypeMeta.Kind
 
 	//validate GVK for current metadata
-	if currVersion != metadataApiVersion {
-		return &bpObj, fmt.Errorf("found incorrect version for the metadata: %s. Supported version is: %s", currVersion, metadataApiVersion)
+	if apiVersionIndex(currVersion) == -1 {
+		return &bpObj, fmt.Errorf("found incorrect version for the metadata: %s. Supported versions are: %s", currVersion, strings.Join(supportedAPIVersions(), ", "))
 	}
 
 	if currKind != metadataKind {
 		return &bpObj, fmt.Errorf("found incorrect kind for the metadata: %s. Supported kind is %s", currKind, metadataKind)
 	}
 
+	// convert metadata of other supported versions to the version
+	// metadata is generated for
+	if currVersion != metadataApiVersion {
+		node, err := yaml.Parse(string(f))
+		if err != nil {
+			return &bpObj, err
+		}
+
+		if err := convertMetadata(node, metadataApiVersion); err != nil {
+			return &bpObj, err
+		}
+
+		bpObj = BlueprintMetadata{}
+		if err := yaml.Unmarshal([]byte(node.MustString()), &bpObj); err != nil {
+			return &bpObj, err
+		}
+
+		Log.Info("converted metadata", "path", metaFilePath, "from", currVersion, "to", metadataApiVersion)
+	}
+
 	return &bpObj, nil
 }
@@ -0,0 +1,179 @@
+package bpmetadata
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// metadataConfig holds the locations metadata is discovered from and the
+// rules it is inferred with. Paths are relative to the root of a blueprint.
+type metadataConfig struct {
+	// RolesFile is the Terraform config listing the roles required
+	// to provision the blueprint.
+	RolesFile string `json:"rolesFile,omitempty" yaml:"rolesFile,omitempty"`
+
+	// ServicesFile is the Terraform config listing the services
+	// required to provision the blueprint.
+	ServicesFile string `json:"servicesFile,omitempty" yaml:"servicesFile,omitempty"`
+
+	// IconFile is the icon for the blueprint.
+	IconFile string `json:"iconFile,omitempty" yaml:"iconFile,omitempty"`
+
+	// ModulesPath is the dir holding the blueprint's submodules.
+	ModulesPath string `json:"modulesPath,omitempty" yaml:"modulesPath,omitempty"`
+
+	// ExamplesPath is the dir holding the blueprint's examples.
+	ExamplesPath string `json:"examplesPath,omitempty" yaml:"examplesPath,omitempty"`
+
+	// TagPrefix is the prefix of the blueprint's release tags e.g. "foo-"
+	// for "foo-v1.2.0". Defaults to no prefix for blueprints at the root of
+	// a repo and to the name of the blueprint's dir followed by "-"
+	// otherwise, which is how release-please tags packages in a monorepo.
+	TagPrefix string `json:"tagPrefix,omitempty" yaml:"tagPrefix,omitempty"`
+
+	// RolesLocals are the keys of locals in RolesFile that hold roles.
+	// All lists of roles are considered if not set.
+	RolesLocals []string `json:"rolesLocals,omitempty" yaml:"rolesLocals,omitempty"`
+
+	// ServicesModules are the names of module blocks in ServicesFile whose
+	// activate_apis hold services. The first module that activates services
+	// is considered if not set.
+	ServicesModules []string `json:"servicesModules,omitempty" yaml:"servicesModules,omitempty"`
+
+	// OrgPolicyRules are rules for proposing org policy checks. They replace
+	// the built-in rules for the same policy.
+	OrgPolicyRules []orgPolicyRule `json:"orgPolicyRules,omitempty" yaml:"orgPolicyRules,omitempty"`
+}
+
+// defaultMetadataConfig returns the locations used by blueprints that follow
+// the standard layout
+func defaultMetadataConfig() *metadataConfig {
+	return &metadataConfig{
+		RolesFile:    tfRolesFileName,
+		ServicesFile: tfServicesFileName,
+		IconFile:     iconFilePath,
+		ModulesPath:  modulesPath,
+		ExamplesPath: examplesPath,
+	}
+}
+
+// loadMetadataConfig loads the config for the blueprint at bpPath from the
+// nearest config file in bpPath or its parents up to the root of the repo.
+// Values that aren't set in the config file are defaulted and values set
+// through flags take precedence.
+func loadMetadataConfig(bpPath string) (*metadataConfig, error) {
+	cfg := defaultMetadataConfig()
+	cfgPath, err := findMetadataConfig(bpPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfgPath != "" {
+		b, err := os.ReadFile(cfgPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read metadata config: %w", err)
+		}
+
+		// unknown keys are rejected so that typos e.g. "rolesfile" aren't
+		// silently ignored
+		fileCfg := metadataConfig{}
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err := dec.Decode(&fileCfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("unable to parse metadata config %s: %w", cfgPath, err)
+		}
+
+		cfg.merge(&fileCfg)
+		Log.Debug("using metadata config", "path", cfgPath)
+	}
+
+	cfg.merge(flagMetadataConfig())
+	return cfg, nil
+}
+
+// findMetadataConfig returns the path to the nearest config file for the
+// blueprint at bpPath or an empty string if there isn't one
+func findMetadataConfig(bpPath string) (string, error) {
+	dir := path.Clean(bpPath)
+	for {
+		cfgPath := path.Join(dir, metadataConfigFileName)
+		_, err := os.Stat(cfgPath)
+		if err == nil {
+			return cfgPath, nil
+		}
+
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("unable to read metadata config: %w", err)
+		}
+
+		// stop at the root of the repo
+		if _, err := os.Stat(path.Join(dir, ".git")); err == nil {
+			return "", nil
+		}
+
+		parent := path.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+
+		dir = parent
+	}
+}
+
+// flagMetadataConfig returns the config values set through flags
+func flagMetadataConfig() *metadataConfig {
+	return &metadataConfig{
+		RolesFile:       mdFlags.rolesFile,
+		ServicesFile:    mdFlags.servicesFile,
+		IconFile:        mdFlags.iconFile,
+		ModulesPath:     mdFlags.modulesPath,
+		ExamplesPath:    mdFlags.examplesPath,
+		RolesLocals:     mdFlags.rolesLocals,
+		ServicesModules: mdFlags.servicesModules,
+	}
+}
+
+// merge overrides the config with all values that are set in o
+func (c *metadataConfig) merge(o *metadataConfig) {
+	if o.RolesFile != "" {
+		c.RolesFile = o.RolesFile
+	}
+
+	if o.ServicesFile != "" {
+		c.ServicesFile = o.ServicesFile
+	}
+
+	if o.IconFile != "" {
+		c.IconFile = o.IconFile
+	}
+
+	if o.ModulesPath != "" {
+		c.ModulesPath = path.Clean(o.ModulesPath)
+	}
+
+	if o.ExamplesPath != "" {
+		c.ExamplesPath = o.ExamplesPath
+	}
+
+	if o.TagPrefix != "" {
+		c.TagPrefix = o.TagPrefix
+	}
+
+	if len(o.RolesLocals) > 0 {
+		c.RolesLocals = o.RolesLocals
+	}
+
+	if len(o.ServicesModules) > 0 {
+		c.ServicesModules = o.ServicesModules
+	}
+
+	if len(o.OrgPolicyRules) > 0 {
+		c.OrgPolicyRules = o.OrgPolicyRules
+	}
+}
@@ -0,0 +1,139 @@
+package bpmetadata
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMetadataConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		atParent bool
+		flags    func()
+		want     *metadataConfig
+		wantErr  string
+	}{
+		{
+			name: "no config",
+			want: defaultMetadataConfig(),
+		},
+		{
+			name: "partial config",
+			config: `rolesFile: infra/iam.tf
+servicesModules:
+- host_project
+`,
+			want: &metadataConfig{
+				RolesFile:       "infra/iam.tf",
+				ServicesFile:    tfServicesFileName,
+				IconFile:        iconFilePath,
+				ModulesPath:     modulesPath,
+				ExamplesPath:    examplesPath,
+				ServicesModules: []string{"host_project"},
+			},
+		},
+		{
+			name: "flags take precedence",
+			config: `rolesFile: infra/iam.tf
+modulesPath: submodules/
+`,
+			flags: func() {
+				mdFlags.rolesFile = "setup/iam.tf"
+				mdFlags.rolesLocals = []string{"required_roles"}
+			},
+			want: &metadataConfig{
+				RolesFile:    "setup/iam.tf",
+				ServicesFile: tfServicesFileName,
+				IconFile:     iconFilePath,
+				ModulesPath:  "submodules",
+				ExamplesPath: examplesPath,
+				RolesLocals:  []string{"required_roles"},
+			},
+		},
+		{
+			name: "org policy rules",
+			config: `orgPolicyRules:
+- policy: storage.uniformBucketLevelAccess
+  resourceTypes: [google_storage_bucket]
+  attributes: [uniform_bucket_level_access]
+`,
+			want: &metadataConfig{
+				RolesFile:    tfRolesFileName,
+				ServicesFile: tfServicesFileName,
+				IconFile:     iconFilePath,
+				ModulesPath:  modulesPath,
+				ExamplesPath: examplesPath,
+				OrgPolicyRules: []orgPolicyRule{{
+					Policy:        "storage.uniformBucketLevelAccess",
+					ResourceTypes: []string{"google_storage_bucket"},
+					Attributes:    []string{"uniform_bucket_level_access"},
+				}},
+			},
+		},
+		{
+			name:   "empty config",
+			config: "# no overrides\n",
+			want:   defaultMetadataConfig(),
+		},
+		{
+			name: "unknown key",
+			config: `rolesfile: infra/iam.tf
+`,
+			wantErr: "field rolesfile not found",
+		},
+		{
+			name: "unknown nested key",
+			config: `orgPolicyRules:
+- policy: storage.uniformBucketLevelAccess
+  resourceType: [google_storage_bucket]
+`,
+			wantErr: "field resourceType not found",
+		},
+		{
+			name:     "config outside of repo is ignored",
+			config:   "rolesFile: infra/iam.tf",
+			atParent: true,
+			want:     defaultMetadataConfig(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := t.TempDir()
+			repo := path.Join(parent, "repo")
+			bpPath := path.Join(repo, "blueprints", "bp")
+			require.NoError(t, os.MkdirAll(path.Join(repo, ".git"), 0755))
+			require.NoError(t, os.MkdirAll(bpPath, 0755))
+
+			if tt.config != "" {
+				cfgDir := repo
+				if tt.atParent {
+					cfgDir = parent
+				}
+
+				require.NoError(t, os.MkdirAll(path.Join(cfgDir, ".cft"), 0755))
+				require.NoError(t, os.WriteFile(path.Join(cfgDir, metadataConfigFileName), []byte(tt.config), 0644))
+			}
+
+			if tt.flags != nil {
+				flags := mdFlags
+				t.Cleanup(func() { mdFlags = flags })
+				tt.flags()
+			}
+
+			got, err := loadMetadataConfig(bpPath)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,472 @@
+package bpmetadata
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// variableConstraint holds the constraints inferred from the validation
+// blocks for a variable. Bounds are nil when they aren't constrained.
+type variableConstraint struct {
+	regexes   []string
+	minLength *int
+	maxLength *int
+	min       *int
+	max       *int
+	enum      []string
+}
+
+// getVariableConstraints parses the validation blocks of all variables
+// defined in the Terraform configs at configPath and returns the
+// constraints that could be inferred, keyed by variable name
+func getVariableConstraints(configPath string) (map[string]*variableConstraint, error) {
+	files, err := filepath.Glob(filepath.Join(configPath, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	constraints := make(map[string]*variableConstraint)
+	p := hclparse.NewParser()
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		file, diags := p.ParseHCL(b, filepath.Base(f))
+		err = hasHclErrors(diags)
+		if err != nil {
+			return nil, err
+		}
+
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		for _, block := range body.Blocks {
+			if block.Type != "variable" || len(block.Labels) != 1 {
+				continue
+			}
+
+			varName := block.Labels[0]
+			for _, vBlock := range block.Body.Blocks {
+				if vBlock.Type != "validation" {
+					continue
+				}
+
+				cond, defined := vBlock.Body.Attributes["condition"]
+				if !defined {
+					continue
+				}
+
+				c, exists := constraints[varName]
+				if !exists {
+					c = &variableConstraint{}
+					constraints[varName] = c
+				}
+
+				c.parseCondition(cond.Expr, varName)
+			}
+		}
+	}
+
+	return constraints, nil
+}
+
+// parseCondition infers constraints from a validation condition. Only
+// conditions that must hold together (i.e. joined by &&) are considered
+// and anything that isn't understood is ignored.
+func (c *variableConstraint) parseCondition(expr hclsyntax.Expression, varName string) {
+	switch e := expr.(type) {
+	case *hclsyntax.ParenthesesExpr:
+		c.parseCondition(e.Expression, varName)
+
+	case *hclsyntax.BinaryOpExpr:
+		if e.Op == hclsyntax.OpLogicalAnd {
+			c.parseCondition(e.LHS, varName)
+			c.parseCondition(e.RHS, varName)
+			return
+		}
+
+		// var.x == null || <condition> only allows the variable to be unset
+		if e.Op == hclsyntax.OpLogicalOr {
+			switch {
+			case isNullCheck(e.LHS, varName):
+				c.parseCondition(e.RHS, varName)
+			case isNullCheck(e.RHS, varName):
+				c.parseCondition(e.LHS, varName)
+			}
+
+			return
+		}
+
+		c.parseComparison(e, varName)
+
+	case *hclsyntax.FunctionCallExpr:
+		switch e.Name {
+		// can(regex("^[a-z]+$", var.x))
+		case "can":
+			if len(e.Args) != 1 {
+				return
+			}
+
+			if r, isRegex := regexPattern(e.Args[0], varName); isRegex {
+				c.addRegex(r)
+			}
+
+		// contains(["a", "b"], var.x)
+		case "contains":
+			if len(e.Args) != 2 || !isVarRef(e.Args[1], varName) {
+				return
+			}
+
+			v, isConst := constValue(e.Args[0])
+			if !isConst || !(v.Type().IsTupleType() || v.Type().IsListType() || v.Type().IsSetType()) {
+				return
+			}
+
+			var enum []string
+			for it := v.ElementIterator(); it.Next(); {
+				_, ev := it.Element()
+				if ev.IsNull() || ev.Type() != cty.String {
+					return
+				}
+
+				enum = append(enum, ev.AsString())
+			}
+
+			c.enum = enum
+		}
+	}
+}
+
+// parseComparison infers bounds from comparisons of a variable or its
+// length with a number e.g. length(var.x) <= 30 or var.x >= 1
+func (c *variableConstraint) parseComparison(e *hclsyntax.BinaryOpExpr, varName string) {
+	subject, bound, op := e.LHS, e.RHS, e.Op
+	if _, isConst := constNumber(subject); isConst {
+		// normalize to "subject op bound"
+		subject, bound, op = e.RHS, e.LHS, mirrorOp(e.Op)
+	}
+
+	n, isConst := constNumber(bound)
+	if !isConst {
+		return
+	}
+
+	// length(regexall("^[a-z]+$", var.x)) > 0
+	if arg, isLength := lengthArg(subject); isLength {
+		if r, isRegex := regexPattern(arg, varName); isRegex {
+			if (op == hclsyntax.OpGreaterThan && n == 0) || (op == hclsyntax.OpGreaterThanOrEqual && n == 1) {
+				c.addRegex(r)
+			}
+
+			return
+		}
+	}
+
+	var lower, upper **int
+	if arg, isLength := lengthArg(subject); isLength && isVarRef(arg, varName) {
+		lower, upper = &c.minLength, &c.maxLength
+	} else if isVarRef(subject, varName) {
+		lower, upper = &c.min, &c.max
+	} else {
+		return
+	}
+
+	// all conditions must hold, so the tightest bounds are kept
+	switch op {
+	case hclsyntax.OpGreaterThanOrEqual:
+		tightenLower(lower, n)
+	case hclsyntax.OpGreaterThan:
+		tightenLower(lower, n+1)
+	case hclsyntax.OpLessThanOrEqual:
+		tightenUpper(upper, n)
+	case hclsyntax.OpLessThan:
+		tightenUpper(upper, n-1)
+	case hclsyntax.OpEqual:
+		tightenLower(lower, n)
+		tightenUpper(upper, n)
+	}
+}
+
+// tightenLower raises a lower bound to n if it isn't already higher
+func tightenLower(bound **int, n int) {
+	if *bound == nil || n > **bound {
+		*bound = intPtr(n)
+	}
+}
+
+// tightenUpper lowers an upper bound to n if it isn't already lower
+func tightenUpper(bound **int, n int) {
+	if *bound == nil || n < **bound {
+		*bound = intPtr(n)
+	}
+}
+
+// addRegex adds a pattern the variable must match. Terraform patterns are
+// RE2 while the UI validates with ECMAScript, so patterns using syntax
+// that only RE2 supports are skipped.
+func (c *variableConstraint) addRegex(r string) {
+	if !isPortableRegex(r) {
+		Log.Warn("skipping regex validation that isn't supported by the UI", "regex", r)
+		return
+	}
+
+	for _, existing := range c.regexes {
+		if existing == r {
+			return
+		}
+	}
+
+	c.regexes = append(c.regexes, r)
+}
+
+// regex returns a single pattern that matches values matching all the
+// patterns of the constraint. Several patterns are combined with lookaheads
+// as supported by the ECMAScript patterns used in the UI.
+func (c *variableConstraint) regex() string {
+	if len(c.regexes) < 2 {
+		return strings.Join(c.regexes, "")
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range c.regexes {
+		fmt.Fprintf(&b, "(?=.*(?:%s))", r)
+	}
+
+	return b.String()
+}
+
+// rePosixClass matches a POSIX character class e.g. [:alpha:] at the start
+// of a pattern
+var rePosixClass = regexp.MustCompile(`^\[:\^?[a-z]+:\]`)
+
+// isPortableRegex checks that an RE2 pattern means the same in ECMAScript
+// i.e. that it doesn't use flags e.g. (?i), "\A", "\z", "\Q...\E",
+// Unicode classes or POSIX classes e.g. [[:alpha:]]
+func isPortableRegex(r string) bool {
+	if _, err := regexp.Compile(r); err != nil {
+		return false
+	}
+
+	for i := 0; i < len(r); i++ {
+		switch {
+		case r[i] == '\\' && i+1 < len(r):
+			if strings.ContainsRune(`AzQEpPC`, rune(r[i+1])) {
+				return false
+			}
+
+			i++
+		case strings.HasPrefix(r[i:], "(?") && !strings.HasPrefix(r[i:], "(?:"):
+			return false
+		case rePosixClass.MatchString(r[i:]):
+			return false
+		}
+	}
+
+	return true
+}
+
+// isNullCheck checks if expr is a comparison of the variable with null
+// i.e. var.x == null
+func isNullCheck(expr hclsyntax.Expression, varName string) bool {
+	e, isBinary := expr.(*hclsyntax.BinaryOpExpr)
+	if !isBinary || e.Op != hclsyntax.OpEqual {
+		return false
+	}
+
+	isNull := func(expr hclsyntax.Expression) bool {
+		v, isConst := constValue(expr)
+		return isConst && v.IsNull()
+	}
+
+	return (isVarRef(e.LHS, varName) && isNull(e.RHS)) || (isNull(e.LHS) && isVarRef(e.RHS, varName))
+}
+
+// regexPattern returns the pattern if expr is a regex() or regexall() call
+// with a constant pattern that is applied to the variable
+func regexPattern(expr hclsyntax.Expression, varName string) (string, bool) {
+	call, isCall := expr.(*hclsyntax.FunctionCallExpr)
+	if !isCall || (call.Name != "regex" && call.Name != "regexall") || len(call.Args) != 2 {
+		return "", false
+	}
+
+	if !isVarRef(call.Args[1], varName) {
+		return "", false
+	}
+
+	v, isConst := constValue(call.Args[0])
+	if !isConst || v.IsNull() || v.Type() != cty.String {
+		return "", false
+	}
+
+	return v.AsString(), true
+}
+
+// lengthArg returns the argument if expr is a length() call
+func lengthArg(expr hclsyntax.Expression) (hclsyntax.Expression, bool) {
+	call, isCall := expr.(*hclsyntax.FunctionCallExpr)
+	if !isCall || call.Name != "length" || len(call.Args) != 1 {
+		return nil, false
+	}
+
+	return call.Args[0], true
+}
+
+// isVarRef checks if expr is a reference to the variable i.e. var.<name>
+func isVarRef(expr hclsyntax.Expression, varName string) bool {
+	return varRefName(expr) == varName
+}
+
+// constValue evaluates expr if it doesn't reference anything
+func constValue(expr hclsyntax.Expression) (cty.Value, bool) {
+	if len(expr.Variables()) > 0 {
+		return cty.NilVal, false
+	}
+
+	v, diags := expr.Value(nil)
+	if diags.HasErrors() || !v.IsWhollyKnown() {
+		return cty.NilVal, false
+	}
+
+	return v, true
+}
+
+// constNumber evaluates expr as a whole number if it is a constant
+func constNumber(expr hclsyntax.Expression) (int, bool) {
+	v, isConst := constValue(expr)
+	if !isConst || v.IsNull() || v.Type() != cty.Number {
+		return 0, false
+	}
+
+	bf := v.AsBigFloat()
+	if !bf.IsInt() {
+		return 0, false
+	}
+
+	i, _ := bf.Int64()
+	return int(i), true
+}
+
+// mirrorOp returns the comparison operator for swapped operands
+func mirrorOp(op *hclsyntax.Operation) *hclsyntax.Operation {
+	switch op {
+	case hclsyntax.OpGreaterThan:
+		return hclsyntax.OpLessThan
+	case hclsyntax.OpGreaterThanOrEqual:
+		return hclsyntax.OpLessThanOrEqual
+	case hclsyntax.OpLessThan:
+		return hclsyntax.OpGreaterThan
+	case hclsyntax.OpLessThanOrEqual:
+		return hclsyntax.OpGreaterThanOrEqual
+	}
+
+	return op
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// constrainedField is a field of a display variable that is generated
+// from the validation blocks of the variable
+type constrainedField struct {
+	name string
+	ptr  func(dv *DisplayVariable) interface{}
+}
+
+var constrainedFields = []constrainedField{
+	{"regexValidation", func(dv *DisplayVariable) interface{} { return &dv.RegExValidation }},
+	{"minItems", func(dv *DisplayVariable) interface{} { return &dv.MinimumItems }},
+	{"maxItems", func(dv *DisplayVariable) interface{} { return &dv.MaximumItems }},
+	{"minLength", func(dv *DisplayVariable) interface{} { return &dv.MinimumLength }},
+	{"maxLength", func(dv *DisplayVariable) interface{} { return &dv.MaximumLength }},
+	{"min", func(dv *DisplayVariable) interface{} { return &dv.Minimum }},
+	{"max", func(dv *DisplayVariable) interface{} { return &dv.Maximum }},
+	{"enumValueLabels", func(dv *DisplayVariable) interface{} { return &dv.EnumValueLabels }},
+}
+
+// applyVariableConstraints sets the constraints inferred from validation
+// blocks on display variables. Generated values are tracked in the
+// ownership so they are refreshed when the validation blocks change,
+// while values that are manually authored are never overridden.
+func applyVariableConstraints(vars []BlueprintVariable, constraints map[string]*variableConstraint, input *BlueprintUIInput, o *metadataOwnership) {
+	if o.Fields == nil {
+		o.Fields = make(map[string]*fieldOwnership)
+	}
+
+	for _, v := range vars {
+		dv, hasDisplayVar := input.Variables[v.Name]
+		if !hasDisplayVar {
+			continue
+		}
+
+		// values generated previously are cleared once a variable has no
+		// constraints
+		c, hasConstraint := constraints[v.Name]
+		if !hasConstraint {
+			c = &variableConstraint{}
+		}
+
+		gen := c.displayVariable(isCollectionType(v.VarType))
+		for _, f := range constrainedFields {
+			p := fmt.Sprintf("spec.ui.input.variables[%s].%s", v.Name, f.name)
+			mergeDisplayValue(p, reflect.ValueOf(f.ptr(dv)).Elem(), reflect.ValueOf(f.ptr(gen)).Elem(), o)
+		}
+	}
+}
+
+// displayVariable returns a display variable with the fields set from the
+// constraint. Length bounds apply to the no. of items for collections.
+func (c *variableConstraint) displayVariable(isCollection bool) *DisplayVariable {
+	dv := &DisplayVariable{
+		RegExValidation: c.regex(),
+		Minimum:         c.min,
+		Maximum:         c.max,
+	}
+
+	minLength, maxLength := &dv.MinimumLength, &dv.MaximumLength
+	if isCollection {
+		minLength, maxLength = &dv.MinimumItems, &dv.MaximumItems
+	}
+
+	if c.minLength != nil {
+		*minLength = *c.minLength
+	}
+
+	if c.maxLength != nil {
+		*maxLength = *c.maxLength
+	}
+
+	for _, e := range c.enum {
+		dv.EnumValueLabels = append(dv.EnumValueLabels, ValueLabel{
+			Label: e,
+			Value: e,
+		})
+	}
+
+	return dv
+}
+
+// isCollectionType checks if a Terraform type constraint is for a
+// list, set, tuple or map
+func isCollectionType(varType string) bool {
+	for _, prefix := range []string{"list", "set", "tuple", "map"} {
+		if strings.HasPrefix(strings.TrimSpace(varType), prefix) {
+			return true
+		}
+	}
+
+	return false
+}
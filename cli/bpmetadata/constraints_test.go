@@ -0,0 +1,223 @@
+package bpmetadata
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariableConstraints(t *testing.T) {
+	tests := []struct {
+		name      string
+		varName   string
+		varType   string
+		existing  *DisplayVariable
+		wantDispV *DisplayVariable
+	}{
+		{
+			name:    "regex and string length",
+			varName: "name",
+			varType: "string",
+			wantDispV: &DisplayVariable{
+				Name:            "name",
+				RegExValidation: "^[a-z][-a-z0-9]*$",
+				MinimumLength:   4,
+				MaximumLength:   30,
+			},
+		},
+		{
+			name:    "regexall",
+			varName: "bucket_prefix",
+			varType: "string",
+			wantDispV: &DisplayVariable{
+				Name:            "bucket_prefix",
+				RegExValidation: "^[a-z0-9-]+$",
+			},
+		},
+		{
+			name:    "numeric range with reversed comparison",
+			varName: "node_count",
+			varType: "number",
+			wantDispV: &DisplayVariable{
+				Name:    "node_count",
+				Minimum: intPtr(1),
+				Maximum: intPtr(10),
+			},
+		},
+		{
+			name:    "enumerated values",
+			varName: "tier",
+			varType: "string",
+			wantDispV: &DisplayVariable{
+				Name: "tier",
+				EnumValueLabels: []ValueLabel{
+					{Label: "BASIC", Value: "BASIC"},
+					{Label: "STANDARD_HA", Value: "STANDARD_HA"},
+				},
+			},
+		},
+		{
+			name:    "list length",
+			varName: "zones",
+			varType: "list(string)",
+			wantDispV: &DisplayVariable{
+				Name:         "zones",
+				MinimumItems: 1,
+				MaximumItems: 3,
+			},
+		},
+		{
+			name:    "null check",
+			varName: "labels",
+			varType: "map(string)",
+			wantDispV: &DisplayVariable{
+				Name:         "labels",
+				MaximumItems: 64,
+			},
+		},
+		{
+			name:    "alternatives are ignored",
+			varName: "mode",
+			varType: "string",
+			wantDispV: &DisplayVariable{
+				Name: "mode",
+			},
+		},
+		{
+			name:    "zero minimum",
+			varName: "disk_size_gb",
+			varType: "number",
+			wantDispV: &DisplayVariable{
+				Name:    "disk_size_gb",
+				Minimum: intPtr(0),
+			},
+		},
+		{
+			name:    "several regexes are combined",
+			varName: "account_id",
+			varType: "string",
+			wantDispV: &DisplayVariable{
+				Name:            "account_id",
+				RegExValidation: "^(?=.*(?:^[a-z]))(?=.*(?:[a-z0-9]$))",
+			},
+		},
+		{
+			name:    "tightest bounds are kept",
+			varName: "instance_name",
+			varType: "string",
+			wantDispV: &DisplayVariable{
+				Name:          "instance_name",
+				MinimumLength: 5,
+				MaximumLength: 63,
+			},
+		},
+		{
+			name:    "regexes only supported by RE2 are skipped",
+			varName: "environment",
+			varType: "string",
+			wantDispV: &DisplayVariable{
+				Name:            "environment",
+				RegExValidation: "^\\w{3,4}$",
+			},
+		},
+		{
+			name:    "existing values are not overridden",
+			varName: "name",
+			varType: "string",
+			existing: &DisplayVariable{
+				Name:            "name",
+				RegExValidation: "^[a-z]+$",
+				MaximumLength:   20,
+			},
+			wantDispV: &DisplayVariable{
+				Name:            "name",
+				RegExValidation: "^[a-z]+$",
+				MinimumLength:   4,
+				MaximumLength:   20,
+			},
+		},
+		{
+			name:    "existing values without constraints are kept",
+			varName: "mode",
+			varType: "string",
+			existing: &DisplayVariable{
+				Name:            "mode",
+				RegExValidation: "^(a|b)$",
+			},
+			wantDispV: &DisplayVariable{
+				Name:            "mode",
+				RegExValidation: "^(a|b)$",
+			},
+		},
+	}
+
+	constraints, err := getVariableConstraints(path.Join(tfTestdataPath, "validations"))
+	require.NoError(t, err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dv := tt.existing
+			if dv == nil {
+				dv = &DisplayVariable{Name: tt.varName}
+			}
+
+			input := &BlueprintUIInput{
+				Variables: map[string]*DisplayVariable{tt.varName: dv},
+			}
+
+			vars := []BlueprintVariable{{Name: tt.varName, VarType: tt.varType}}
+			applyVariableConstraints(vars, constraints, input, &metadataOwnership{})
+			assert.Equal(t, tt.wantDispV, input.Variables[tt.varName])
+		})
+	}
+}
+
+func TestIsPortableRegex(t *testing.T) {
+	tests := []struct {
+		regex string
+		want  bool
+	}{
+		{`^[a-z][-a-z0-9]*$`, true},
+		{`^(?:us|eu)-[a-z]+\d$`, true},
+		{`^\\Qa.b\\E$`, true},
+		{`(?i)^dev$`, false},
+		{`^(?P<region>[a-z]+)$`, false},
+		{`\Adev\z`, false},
+		{`^\Qa.b\E$`, false},
+		{`^\pL+$`, false},
+		{`^[a-z[:digit:]]+$`, false},
+		{`^[a-z`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.regex, func(t *testing.T) {
+			assert.Equal(t, tt.want, isPortableRegex(tt.regex))
+		})
+	}
+}
+
+func TestVariableConstraintsAreRefreshed(t *testing.T) {
+	vars := []BlueprintVariable{{Name: "name", VarType: "string"}}
+	input := &BlueprintUIInput{
+		Variables: map[string]*DisplayVariable{"name": {Name: "name"}},
+	}
+
+	o := &metadataOwnership{}
+	applyVariableConstraints(vars, map[string]*variableConstraint{"name": {maxLength: intPtr(30)}}, input, o)
+	assert.Equal(t, 30, input.Variables["name"].MaximumLength)
+
+	// a changed bound is refreshed and a removed one is cleared
+	applyVariableConstraints(vars, map[string]*variableConstraint{"name": {minLength: intPtr(4), maxLength: intPtr(20)}}, input, o)
+	assert.Equal(t, 4, input.Variables["name"].MinimumLength)
+	assert.Equal(t, 20, input.Variables["name"].MaximumLength)
+
+	applyVariableConstraints(vars, map[string]*variableConstraint{"name": {maxLength: intPtr(20)}}, input, o)
+	assert.Equal(t, 0, input.Variables["name"].MinimumLength)
+
+	// a bound edited by hand is kept
+	input.Variables["name"].MaximumLength = 10
+	applyVariableConstraints(vars, map[string]*variableConstraint{"name": {maxLength: intPtr(25)}}, input, o)
+	assert.Equal(t, 10, input.Variables["name"].MaximumLength)
+	assert.Equal(t, ownerManual, o.Fields["spec.ui.input.variables[name].maxLength"].Owner)
+}
@@ -0,0 +1,102 @@
+package bpmetadata
+
+import (
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const metadataApiGroup = "blueprints.cloud.google.com"
+
+// conversionFunc converts a metadata document between adjacent API
+// versions in place. Documents are converted as YAML nodes so that
+// comments and field ordering are preserved.
+type conversionFunc func(*yaml.RNode) error
+
+// metadataAPIVersion is a version of the metadata API along with the
+// conversions from and to the version before it
+type metadataAPIVersion struct {
+	// Version is the apiVersion of metadata documents
+	// e.g. blueprints.cloud.google.com/v1alpha1.
+	Version string
+
+	// Upgrade converts a document of the previous version to this
+	// version. No conversion is needed if it isn't set.
+	Upgrade conversionFunc
+
+	// Downgrade converts a document of this version to the previous
+	// version. No conversion is needed if it isn't set.
+	Downgrade conversionFunc
+}
+
+// metadataAPIVersions are the supported versions of the metadata API
+// from oldest to newest. Metadata of older versions is converted to the
+// newest version i.e. metadataApiVersion when it is read.
+var metadataAPIVersions = []metadataAPIVersion{
+	{
+		Version: metadataApiVersion,
+	},
+}
+
+// supportedAPIVersions returns the supported versions of the metadata API
+func supportedAPIVersions() []string {
+	var versions []string
+	for _, v := range metadataAPIVersions {
+		versions = append(versions, v.Version)
+	}
+
+	return versions
+}
+
+// apiVersionIndex returns the position of a version in the registry
+// or -1 if it isn't supported
+func apiVersionIndex(version string) int {
+	for i, v := range metadataAPIVersions {
+		if v.Version == version {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// convertMetadata converts a metadata document to the target API version
+// by applying the conversions between each version in order
+func convertMetadata(node *yaml.RNode, to string) error {
+	from := node.GetApiVersion()
+	fromIdx, toIdx := apiVersionIndex(from), apiVersionIndex(to)
+	if fromIdx == -1 {
+		return fmt.Errorf("found incorrect version for the metadata: %s. Supported versions are: %s", from, strings.Join(supportedAPIVersions(), ", "))
+	}
+
+	if toIdx == -1 {
+		return fmt.Errorf("unsupported target version for the metadata: %s. Supported versions are: %s", to, strings.Join(supportedAPIVersions(), ", "))
+	}
+
+	for i := fromIdx + 1; i <= toIdx; i++ {
+		if err := applyConversion(node, metadataAPIVersions[i].Upgrade); err != nil {
+			return fmt.Errorf("error converting metadata to %s: %w", metadataAPIVersions[i].Version, err)
+		}
+	}
+
+	for i := fromIdx; i > toIdx; i-- {
+		if err := applyConversion(node, metadataAPIVersions[i].Downgrade); err != nil {
+			return fmt.Errorf("error converting metadata to %s: %w", metadataAPIVersions[i-1].Version, err)
+		}
+	}
+
+	if from != to {
+		node.SetApiVersion(to)
+	}
+
+	return nil
+}
+
+func applyConversion(node *yaml.RNode, f conversionFunc) error {
+	if f == nil {
+		return nil
+	}
+
+	return f(node)
+}
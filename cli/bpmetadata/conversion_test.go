@@ -0,0 +1,132 @@
+package bpmetadata
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const testPrevApiVersion = metadataApiGroup + "/v1alpha0"
+
+const prevVersionMetadata = `apiVersion: blueprints.cloud.google.com/v1alpha0
+kind: BlueprintMetadata
+metadata:
+  name: terraform-google-bp
+spec:
+  info:
+    # set from the readme
+    displayTitle: Foo Blueprint
+    version: 1.0.0
+`
+
+const currVersionMetadata = `apiVersion: blueprints.cloud.google.com/v1alpha1
+kind: BlueprintMetadata
+metadata:
+  name: terraform-google-bp
+spec:
+  info:
+    # set from the readme
+    title: Foo Blueprint
+    version: 1.0.0
+`
+
+// withPrevAPIVersion registers an API version before the current one for
+// which spec.info.title was named spec.info.displayTitle
+func withPrevAPIVersion(t *testing.T) {
+	versions := metadataAPIVersions
+	t.Cleanup(func() { metadataAPIVersions = versions })
+
+	curr := versions[len(versions)-1]
+	curr.Upgrade = renameField("displayTitle", "title", "spec", "info")
+	curr.Downgrade = renameField("title", "displayTitle", "spec", "info")
+	metadataAPIVersions = []metadataAPIVersion{{Version: testPrevApiVersion}, curr}
+}
+
+func renameField(from, to string, fieldPath ...string) conversionFunc {
+	return func(node *yaml.RNode) error {
+		parent, err := node.Pipe(yaml.Lookup(fieldPath...))
+		if err != nil || parent == nil {
+			return err
+		}
+
+		if f := parent.Field(from); f != nil {
+			f.Key.YNode().Value = to
+		}
+
+		return nil
+	}
+}
+
+func TestConvertMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		to      string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "upgrade",
+			doc:  prevVersionMetadata,
+			to:   metadataApiVersion,
+			want: currVersionMetadata,
+		},
+		{
+			name: "downgrade",
+			doc:  currVersionMetadata,
+			to:   testPrevApiVersion,
+			want: prevVersionMetadata,
+		},
+		{
+			name: "same version",
+			doc:  currVersionMetadata,
+			to:   metadataApiVersion,
+			want: currVersionMetadata,
+		},
+		{
+			name:    "unsupported source version",
+			doc:     "apiVersion: blueprints.cloud.google.com/v2\nkind: BlueprintMetadata\n",
+			to:      metadataApiVersion,
+			wantErr: "found incorrect version for the metadata",
+		},
+		{
+			name:    "unsupported target version",
+			doc:     currVersionMetadata,
+			to:      metadataApiGroup + "/v2",
+			wantErr: "unsupported target version for the metadata",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withPrevAPIVersion(t)
+			node, err := yaml.Parse(tt.doc)
+			require.NoError(t, err)
+
+			err = convertMetadata(node, tt.to)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, node.MustString())
+		})
+	}
+}
+
+func TestUnmarshalMetadataConvertsVersion(t *testing.T) {
+	withPrevAPIVersion(t)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(dir, metadataFileName), []byte(prevVersionMetadata), 0644))
+
+	bpObj, err := UnmarshalMetadata(dir, metadataFileName)
+	require.NoError(t, err)
+	assert.Equal(t, metadataApiVersion, bpObj.APIVersion)
+	assert.Equal(t, "Foo Blueprint", bpObj.Spec.Info.Title)
+}
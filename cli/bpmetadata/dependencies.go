@@ -0,0 +1,185 @@
+package bpmetadata
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// reVersionConstraint matches a single Terraform version constraint
+// e.g. ">= 1.2.0" or "~> 7.0"
+var reVersionConstraint = regexp.MustCompile(`^(=|!=|>=|<=|>|<|~>)?\s*v?([0-9]+(?:\.[0-9]+)*)(?:-[0-9A-Za-z.-]+)?$`)
+
+// gitSourcePrefixes are the prefixes of module sources that are fetched
+// from git repos
+var gitSourcePrefixes = []string{"git::", "git@", "ssh://", "github.com/", "bitbucket.org/"}
+
+// unpinnedGitRefs are well-known git refs that are branches rather than
+// releases
+var unpinnedGitRefs = map[string]bool{
+	"main":   true,
+	"master": true,
+	"HEAD":   true,
+}
+
+// getModuleDependencies returns the external modules used by the module
+// blocks in the Terraform config at configPath. Local modules aren't
+// dependencies and modules used with the same version are only listed once.
+func getModuleDependencies(configPath string) ([]BlueprintDependency, error) {
+	calls, err := getModuleCalls(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[BlueprintDependency]bool)
+	var deps []BlueprintDependency
+	for _, c := range calls {
+		if c.source == "" || isLocalModuleSource(c.source) {
+			continue
+		}
+
+		d := BlueprintDependency{Source: c.source, Version: c.version}
+		if seen[d] {
+			continue
+		}
+
+		seen[d] = true
+		deps = append(deps, d)
+	}
+
+	sort.SliceStable(deps, func(i, j int) bool {
+		if deps[i].Source != deps[j].Source {
+			return deps[i].Source < deps[j].Source
+		}
+
+		return deps[i].Version < deps[j].Version
+	})
+
+	return deps, nil
+}
+
+// checkDependencyPinning returns a problem if the dependency isn't pinned
+// to a range of versions with an upper bound. Registry modules are pinned
+// with a version constraint and git modules with a ref. Refs can't be told
+// apart from branches without fetching the repo, so only the well-known
+// branch names main, master and HEAD are caught. Other sources aren't
+// checked.
+func checkDependencyPinning(d BlueprintDependency) string {
+	if isRegistrySource(d.Source) {
+		if d.Version == "" {
+			return "version is not pinned, set a version constraint e.g. \"~> 1.0\""
+		}
+
+		return checkVersionConstraint(d.Version)
+	}
+
+	if !isGitSource(d.Source) {
+		return ""
+	}
+
+	ref := ""
+	if i := strings.Index(d.Source, "?"); i != -1 {
+		for _, p := range strings.Split(d.Source[i+1:], "&") {
+			if strings.HasPrefix(p, "ref=") {
+				ref = strings.TrimPrefix(p, "ref=")
+			}
+		}
+	}
+
+	switch {
+	case ref == "":
+		return "version is not pinned, set a ref in the source e.g. \"?ref=v1.0.0\""
+	case unpinnedGitRefs[ref]:
+		return fmt.Sprintf("ref %q is a branch, pin the source to a release tag", ref)
+	}
+
+	return ""
+}
+
+// checkVersionConstraint returns a problem if a version constraint is
+// invalid or doesn't have an upper bound. A constraint such as "~> 1"
+// allows any later version and isn't bounded.
+func checkVersionConstraint(constraint string) string {
+	bounded := false
+	major := ""
+	for _, c := range strings.Split(constraint, ",") {
+		m := reVersionConstraint.FindStringSubmatch(strings.TrimSpace(c))
+		if m == nil {
+			return fmt.Sprintf("version constraint %q is invalid", constraint)
+		}
+
+		if major == "" {
+			major = strings.SplitN(m[2], ".", 2)[0]
+		}
+
+		switch m[1] {
+		case "", "=", "<", "<=":
+			bounded = true
+		case "~>":
+			bounded = bounded || strings.Count(m[2], ".") > 0
+		}
+	}
+
+	if !bounded {
+		return fmt.Sprintf("version constraint %q is too loose, set an upper bound e.g. \"~> %s.0\"", constraint, major)
+	}
+
+	return ""
+}
+
+// isGitSource returns true if a module source is a git repo
+func isGitSource(source string) bool {
+	for _, p := range gitSourcePrefixes {
+		if strings.HasPrefix(source, p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isRegistrySource returns true if a module source is a Terraform
+// registry address
+func isRegistrySource(source string) bool {
+	m := reRegistrySource.FindStringSubmatch(source)
+	return m != nil && !strings.Contains(m[1], "github.com") && !strings.Contains(m[1], "bitbucket.org")
+}
+
+// validateMetadataDependencies checks that the external modules in the
+// core metadata of the blueprint at bpPath are pinned to versions with an
+// upper bound. Problems are returned as warnings.
+func validateMetadataDependencies(bpPath string) []validationFinding {
+	bpObj, err := UnmarshalMetadata(bpPath, metadataFileName)
+	if err != nil {
+		return nil
+	}
+
+	m := path.Join(bpPath, metadataFileName)
+	var findings []validationFinding
+	for i, d := range bpObj.Spec.Requirements.Dependencies {
+		p := checkDependencyPinning(d)
+		if p == "" {
+			continue
+		}
+
+		field := []string{"spec", "requirements", "dependencies", strconv.Itoa(i)}
+		line, col := fieldPosition(m, field)
+		f := validationFinding{
+			File:    m,
+			Line:    line,
+			Column:  col,
+			Field:   strings.Join(field, "."),
+			Rule:    "dependency",
+			Level:   levelWarning,
+			Message: fmt.Sprintf("%s: %s", d.Source, p),
+		}
+
+		Log.Warn("dependency validation warning", "path", f.location(), "err", f.Message)
+		findings = append(findings, f)
+	}
+
+	return findings
+}
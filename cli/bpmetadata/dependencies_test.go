@@ -0,0 +1,116 @@
+package bpmetadata
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetModuleDependencies(t *testing.T) {
+	got, err := getModuleDependencies(path.Join(bptestdataPath, exampleModulesPath, "examples", "private"))
+	require.NoError(t, err)
+	assert.Equal(t, []BlueprintDependency{
+		{Source: "terraform-google-modules/kubernetes-engine/google//modules/private-cluster", Version: "~> 27.0"},
+		{Source: "terraform-google-modules/network/google", Version: "~> 7.0"},
+	}, got)
+}
+
+func TestCheckDependencyPinning(t *testing.T) {
+	tests := []struct {
+		name string
+		dep  BlueprintDependency
+		want string
+	}{
+		{
+			name: "pessimistic minor",
+			dep:  BlueprintDependency{Source: "terraform-google-modules/network/google", Version: "~> 7.0"},
+		},
+		{
+			name: "range with upper bound",
+			dep:  BlueprintDependency{Source: "terraform-google-modules/network/google", Version: ">= 6.0, < 8.0"},
+		},
+		{
+			name: "exact version",
+			dep:  BlueprintDependency{Source: "app.terraform.io/org/network/google", Version: "7.1.0"},
+		},
+		{
+			name: "unpinned registry module",
+			dep:  BlueprintDependency{Source: "terraform-google-modules/network/google"},
+			want: `version is not pinned, set a version constraint e.g. "~> 1.0"`,
+		},
+		{
+			name: "lower bound only",
+			dep:  BlueprintDependency{Source: "terraform-google-modules/network/google", Version: ">= 7.0"},
+			want: `version constraint ">= 7.0" is too loose, set an upper bound e.g. "~> 7.0"`,
+		},
+		{
+			name: "pessimistic major",
+			dep:  BlueprintDependency{Source: "terraform-google-modules/network/google", Version: "~> 7"},
+			want: `version constraint "~> 7" is too loose, set an upper bound e.g. "~> 7.0"`,
+		},
+		{
+			name: "invalid constraint",
+			dep:  BlueprintDependency{Source: "terraform-google-modules/network/google", Version: "latest"},
+			want: `version constraint "latest" is invalid`,
+		},
+		{
+			name: "git with tag",
+			dep:  BlueprintDependency{Source: "git::https://github.com/foo/bar.git//modules/baz?ref=v1.2.0"},
+		},
+		{
+			name: "git without ref",
+			dep:  BlueprintDependency{Source: "github.com/foo/bar//modules/baz"},
+			want: `version is not pinned, set a ref in the source e.g. "?ref=v1.0.0"`,
+		},
+		{
+			name: "git with branch",
+			dep:  BlueprintDependency{Source: "git@github.com:foo/bar.git?ref=main"},
+			want: `ref "main" is a branch, pin the source to a release tag`,
+		},
+		{
+			name: "git over ssh with branch",
+			dep:  BlueprintDependency{Source: "git::ssh://git@example.com/foo/bar.git?ref=master"},
+			want: `ref "master" is a branch, pin the source to a release tag`,
+		},
+		{
+			name: "bitbucket without ref",
+			dep:  BlueprintDependency{Source: "bitbucket.org/foo/bar"},
+			want: `version is not pinned, set a ref in the source e.g. "?ref=v1.0.0"`,
+		},
+		{
+			name: "other branches aren't caught",
+			dep:  BlueprintDependency{Source: "github.com/foo/bar?ref=develop"},
+		},
+		{
+			name: "other sources",
+			dep:  BlueprintDependency{Source: "gcs::https://www.googleapis.com/storage/v1/modules/foomodule.zip"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, checkDependencyPinning(tt.dep))
+		})
+	}
+}
+
+func TestValidateMetadataDependencies(t *testing.T) {
+	dir := t.TempDir()
+	bpObj := &BlueprintMetadata{}
+	bpObj.APIVersion = metadataApiVersion
+	bpObj.Kind = metadataKind
+	bpObj.Spec.Requirements.Dependencies = []BlueprintDependency{
+		{Source: "terraform-google-modules/network/google", Version: "~> 7.0"},
+		{Source: "terraform-google-modules/vm/google"},
+	}
+	require.NoError(t, WriteMetadata(bpObj, dir, metadataFileName))
+
+	got := validateMetadataDependencies(dir)
+	require.Len(t, got, 1)
+	assert.Equal(t, "spec.requirements.dependencies.1", got[0].Field)
+	assert.Equal(t, levelWarning, got[0].Level)
+	assert.Equal(t, "dependency", got[0].Rule)
+	assert.NotZero(t, got[0].Line)
+}
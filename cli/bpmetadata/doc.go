@@ -35,7 +35,8 @@
 //
 // This will generate two files i.e. "metadata.yaml" and "metadata.display.yaml" for each root and
 // sub-modules available in the Terraform Blueprint. "metadata.yaml" is mostly auto generated while
-// "metadata.display.yaml" is expected to be hand-authored.
+// "metadata.display.yaml" is expected to be hand-authored. Autogenerated fields that are edited by
+// hand are tracked as manually owned in "metadata.ownership.yaml" and kept on later runs.
 //
 // All fields (auto generated and manually authored) supported by the metadata schema can be found
 // under the top-level struct type [BlueprintMetadata].
@@ -43,7 +44,9 @@
 // Refer to sample versions of [metadata.yaml] and [metadata.display.yaml] for the [canonical]
 // Terraform package.
 //
-// For all available flags for the CLI, use help for cft as:
+// Generating metadata for every blueprint in a repo, checking metadata for drift, writing other
+// formats and configuring where metadata is discovered from are described along with all
+// available flags for the CLI in the help for cft:
 //
 //	cft blueprint metadata -h
 //
@@ -55,7 +58,8 @@
 //
 // This will output a success message i.e. "metadata is valid" if all fields in all metadata files
 // are consistent with the [BlueprintMetadata] schema. Otherwise, error messages for invalid field
-// names, types or values will be shown.
+// names, types or values will be shown. References across metadata files, local assets, module
+// dependencies, provider versions and the blueprint version are validated as well.
 //
 // [BlueprintMetadata]: https://pkg.go.dev/github.com/GoogleCloudPlatform/cloud-foundation-toolkit/cli/bpmetadata#BlueprintMetadata
 // [metadata.yaml]: https://github.com/g-awmalik/terraform-google-canonical-mp/blob/main/metadata.yaml
@@ -0,0 +1,244 @@
+package bpmetadata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/cobra"
+)
+
+// markers for the autogenerated section in the readme, shared with the
+// terraform-docs pre-commit hook so that existing readmes can be updated
+const (
+	docsBeginMarker = "<!-- BEGINNING OF PRE-COMMIT-TERRAFORM DOCS HOOK -->"
+	docsEndMarker   = "<!-- END OF PRE-COMMIT-TERRAFORM DOCS HOOK -->"
+)
+
+var docsFlags struct {
+	path   string
+	nested bool
+	check  bool
+}
+
+func init() {
+	Cmd.AddCommand(docsCmd)
+
+	docsCmd.Flags().StringVarP(&docsFlags.path, "path", "p", ".", "Path to the blueprint for generating docs.")
+	docsCmd.Flags().BoolVar(&docsFlags.nested, "nested", true, "Flag for generating docs for nested blueprint, if any.")
+	docsCmd.Flags().BoolVar(&docsFlags.check, "check", false, "Check that docs in the readme are up to date without writing any files.")
+}
+
+var docsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "Generates blueprint docs from metadata",
+	Long: `Generates the inputs, outputs and requirements sections of README.md from metadata.yaml for specified blueprint
+
+	Docs are written between the BEGINNING OF PRE-COMMIT-TERRAFORM DOCS HOOK and END OF
+	PRE-COMMIT-TERRAFORM DOCS HOOK comments, which are appended to the readme if they don't exist.
+	`,
+	Args: cobra.NoArgs,
+	RunE: generateDocs,
+}
+
+// generateDocs updates the readme for the root module and submodules of
+// a blueprint from their metadata
+func generateDocs(cmd *cobra.Command, args []string) error {
+	wdPath, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("error getting working dir: %w", err)
+	}
+
+	currBpPath := docsFlags.path
+	if !path.IsAbs(docsFlags.path) {
+		currBpPath = path.Join(wdPath, docsFlags.path)
+	}
+
+	cfg, err := loadMetadataConfig(currBpPath)
+	if err != nil {
+		return err
+	}
+
+	allBpPaths, err := getBlueprintPaths(currBpPath, docsFlags.nested, cfg)
+	if len(allBpPaths) == 0 {
+		return err
+	}
+
+	var errs []string
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	for _, bpPath := range allBpPaths {
+		if err := generateDocsForBpPath(bpPath, docsFlags.check); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	return joinErrors(errs)
+}
+
+// generateDocsForBpPath renders docs from the metadata of the blueprint at
+// bpPath and writes them to its readme or, if checking, compares them with
+// the docs in the readme
+func generateDocsForBpPath(bpPath string, check bool) error {
+	if _, err := os.Stat(path.Join(bpPath, metadataFileName)); err != nil {
+		return fmt.Errorf("%s does not exist for blueprint at path: %s. Generate it with \"cft blueprint metadata\"", metadataFileName, bpPath)
+	}
+
+	bpObj, err := UnmarshalMetadata(bpPath, metadataFileName)
+	if err != nil {
+		return fmt.Errorf("error reading %s for blueprint at path: %s. Details: %w", metadataFileName, bpPath, err)
+	}
+
+	readmePath := path.Join(bpPath, readmeFileName)
+	readme, err := os.ReadFile(readmePath)
+	if err != nil {
+		return fmt.Errorf("error reading %s for blueprint at path: %s. Details: %w", readmeFileName, bpPath, err)
+	}
+
+	updated, err := updateDocs(string(readme), renderDocs(bpObj))
+	if err != nil {
+		return fmt.Errorf("error updating %s for blueprint at path: %s. Details: %w", readmeFileName, bpPath, err)
+	}
+
+	if check {
+		if d := cmp.Diff(strings.Split(string(readme), "\n"), strings.Split(updated, "\n")); d != "" {
+			return fmt.Errorf("%s docs are out of date for blueprint at path: %s. Diff (-on disk +generated):\n%s", readmeFileName, bpPath, d)
+		}
+
+		Log.Info("docs are up to date", "path", readmePath)
+		return nil
+	}
+
+	if updated == string(readme) {
+		return nil
+	}
+
+	return os.WriteFile(readmePath, []byte(updated), 0644)
+}
+
+// updateDocs replaces the content between the docs markers in the readme
+// with the rendered docs. The markers are appended to the readme if it
+// doesn't have them.
+func updateDocs(readme, docs string) (string, error) {
+	begin := strings.Index(readme, docsBeginMarker)
+	end := strings.Index(readme, docsEndMarker)
+	switch {
+	case begin == -1 && end == -1:
+		readme = strings.TrimRight(readme, "\n") + "\n\n" + docsBeginMarker + "\n" + docsEndMarker + "\n"
+		begin = strings.Index(readme, docsBeginMarker)
+		end = strings.Index(readme, docsEndMarker)
+	case begin == -1 || end == -1 || end < begin:
+		return "", errors.New("malformed docs markers, expected " + docsBeginMarker + " followed by " + docsEndMarker)
+	}
+
+	return readme[:begin+len(docsBeginMarker)] + "\n" + docs + readme[end:], nil
+}
+
+// renderDocs renders the inputs, outputs and requirements of a blueprint
+// as markdown
+func renderDocs(bpObj *BlueprintMetadata) string {
+	var sb strings.Builder
+	sb.WriteString("## Inputs\n\n")
+	if len(bpObj.Spec.Interfaces.Variables) == 0 {
+		sb.WriteString("No inputs.\n\n")
+	} else {
+		sb.WriteString("| Name | Description | Type | Default | Required |\n")
+		sb.WriteString("|------|-------------|------|---------|:--------:|\n")
+		for _, v := range bpObj.Spec.Interfaces.Variables {
+			defaultValue, required := "n/a", "yes"
+			if !v.Required {
+				defaultValue, required = mdCode(docsValue(v.DefaultValue)), "no"
+			}
+
+			// variables without a type accept any value, as terraform-docs renders them
+			varType := v.VarType
+			if varType == "" {
+				varType = "any"
+			}
+
+			fmt.Fprintf(&sb, "| %s | %s | %s | %s | %s |\n", v.Name, mdTableText(v.Description), mdCode(varType), defaultValue, required)
+		}
+
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("## Outputs\n\n")
+	if len(bpObj.Spec.Interfaces.Outputs) == 0 {
+		sb.WriteString("No outputs.\n\n")
+	} else {
+		sb.WriteString("| Name | Description |\n")
+		sb.WriteString("|------|-------------|\n")
+		for _, o := range bpObj.Spec.Interfaces.Outputs {
+			fmt.Fprintf(&sb, "| %s | %s |\n", o.Name, mdTableText(o.Description))
+		}
+
+		sb.WriteString("\n")
+	}
+
+	reqs := bpObj.Spec.Requirements
+	if len(reqs.Roles) == 0 && len(reqs.Services) == 0 {
+		return sb.String()
+	}
+
+	sb.WriteString("## Requirements\n\n")
+	if len(reqs.Roles) > 0 {
+		sb.WriteString("### Roles\n\n")
+		for _, r := range reqs.Roles {
+			var roles []string
+			for _, role := range r.Roles {
+				roles = append(roles, mdCode(role))
+			}
+
+			fmt.Fprintf(&sb, "- %s: %s\n", r.Level, strings.Join(roles, ", "))
+		}
+
+		sb.WriteString("\n")
+	}
+
+	if len(reqs.Services) > 0 {
+		sb.WriteString("### APIs\n\n")
+		for _, s := range reqs.Services {
+			fmt.Fprintf(&sb, "- %s\n", mdCode(s))
+		}
+
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// docsValue renders a default value as HCL-like JSON
+func docsValue(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+
+	return string(b)
+}
+
+// mdTableText escapes text for a markdown table cell
+func mdTableText(s string) string {
+	s = strings.ReplaceAll(strings.TrimSpace(s), "|", "\\|")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// mdCode formats text as inline code, keeping multiline text such as
+// object types within a table cell
+func mdCode(s string) string {
+	if strings.Contains(s, "\n") {
+		return "<pre>" + mdTableText(s) + "</pre>"
+	}
+
+	return "`" + strings.ReplaceAll(s, "|", "\\|") + "`"
+}
@@ -0,0 +1,159 @@
+package bpmetadata
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const docsBpMetadata = `apiVersion: blueprints.cloud.google.com/v1alpha1
+kind: BlueprintMetadata
+metadata:
+  name: terraform-google-bp
+spec:
+  interfaces:
+    variables:
+    - name: names
+      description: Bucket name suffixes | prefixed.
+      varType: list(string)
+      required: true
+    - name: labels
+      description: |-
+        Labels to apply.
+        Keys must be lowercase.
+      varType: map(string)
+      defaultValue:
+        env: dev
+    - name: location
+      varType: string
+      defaultValue: US
+    - name: retention
+      varType: |-
+        object({
+          days = number
+        })
+    - name: extra_args
+      description: Arguments of any type.
+      required: true
+    outputs:
+    - name: bucket
+      description: Bucket resource.
+  requirements:
+    roles:
+    - level: Project
+      roles:
+      - roles/storage.admin
+      - roles/iam.serviceAccountUser
+    services:
+    - storage-api.googleapis.com
+`
+
+const docsBpReadme = "| names | Bucket name suffixes \\| prefixed. | `list(string)` | n/a | yes |\n" +
+	"| labels | Labels to apply.<br>Keys must be lowercase. | `map(string)` | `{\"env\":\"dev\"}` | no |\n" +
+	"| location |  | `string` | `\"US\"` | no |\n" +
+	"| retention |  | <pre>object({<br>  days = number<br>})</pre> | `null` | no |\n" +
+	"| extra_args | Arguments of any type. | `any` | n/a | yes |\n"
+
+func TestRenderDocs(t *testing.T) {
+	bpObj := &BlueprintMetadata{}
+	require.NoError(t, yaml.Unmarshal([]byte(docsBpMetadata), bpObj))
+
+	want := "## Inputs\n\n" +
+		"| Name | Description | Type | Default | Required |\n" +
+		"|------|-------------|------|---------|:--------:|\n" +
+		docsBpReadme +
+		"\n## Outputs\n\n" +
+		"| Name | Description |\n" +
+		"|------|-------------|\n" +
+		"| bucket | Bucket resource. |\n" +
+		"\n## Requirements\n\n" +
+		"### Roles\n\n" +
+		"- Project: `roles/storage.admin`, `roles/iam.serviceAccountUser`\n" +
+		"\n### APIs\n\n" +
+		"- `storage-api.googleapis.com`\n\n"
+
+	assert.Equal(t, want, renderDocs(bpObj))
+}
+
+func TestRenderDocsEmpty(t *testing.T) {
+	assert.Equal(t, "## Inputs\n\nNo inputs.\n\n## Outputs\n\nNo outputs.\n\n", renderDocs(&BlueprintMetadata{}))
+}
+
+func TestUpdateDocs(t *testing.T) {
+	tests := []struct {
+		name    string
+		readme  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "replace docs",
+			readme: "# Foo\n\n" + docsBeginMarker + "\n## Inputs\nstale\n" + docsEndMarker + "\n\n## License\n",
+			want:   "# Foo\n\n" + docsBeginMarker + "\ndocs\n" + docsEndMarker + "\n\n## License\n",
+		},
+		{
+			name:   "append markers",
+			readme: "# Foo\n\nSome content\n\n",
+			want:   "# Foo\n\nSome content\n\n" + docsBeginMarker + "\ndocs\n" + docsEndMarker + "\n",
+		},
+		{
+			name:    "missing end marker",
+			readme:  "# Foo\n\n" + docsBeginMarker + "\n",
+			wantErr: true,
+		},
+		{
+			name:    "markers out of order",
+			readme:  "# Foo\n\n" + docsEndMarker + "\n" + docsBeginMarker + "\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := updateDocs(tt.readme, "docs\n")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGenerateDocsForBpPath(t *testing.T) {
+	dir := t.TempDir()
+	readmePath := path.Join(dir, readmeFileName)
+	require.NoError(t, os.WriteFile(path.Join(dir, metadataFileName), []byte(docsBpMetadata), 0644))
+	require.NoError(t, os.WriteFile(readmePath, []byte("# Foo\n\n"+docsBeginMarker+"\n"+docsEndMarker+"\n"), 0644))
+
+	// stale docs fail the check without updating the readme
+	err := generateDocsForBpPath(dir, true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "docs are out of date")
+
+	readme, err := os.ReadFile(readmePath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(readme), "## Inputs")
+
+	// generated docs pass the check
+	require.NoError(t, generateDocsForBpPath(dir, false))
+	readme, err = os.ReadFile(readmePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(readme), docsBpReadme)
+	assert.NoError(t, generateDocsForBpPath(dir, true))
+}
+
+func TestGenerateDocsForBpPathNoMetadata(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(dir, readmeFileName), []byte("# Foo\n"), 0644))
+
+	err := generateDocsForBpPath(dir, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Generate it with")
+}
@@ -0,0 +1,275 @@
+package bpmetadata
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+
+	hcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// nameExtensions maps variable name patterns to extension types. Patterns
+// are evaluated in order and the first match wins.
+var nameExtensions = []struct {
+	re  *regexp.Regexp
+	ext ExtensionType
+}{
+	{regexp.MustCompile(`^(.+_)?zone$`), GCEZone},
+	{regexp.MustCompile(`^(.+_)?region$`), GCERegion},
+	{regexp.MustCompile(`^(.+_)?machine_type$`), GCEMachineType},
+	{regexp.MustCompile(`^(.+_)?(subnetwork|subnet)(_name)?$`), GCESubnetwork},
+	{regexp.MustCompile(`^(.+_)?network(_name)?$`), GCENetwork},
+	{regexp.MustCompile(`^(.+_)?service_account(_email)?$`), IAMServiceAccount},
+	{regexp.MustCompile(`^(.+_)?bucket(_name)?$`), GCSBucket},
+	{regexp.MustCompile(`^(.+_)?disk_type$`), GCEDiskType},
+	{regexp.MustCompile(`^(.+_)?disk_size(_gb)?$`), GCEDiskSize},
+	{regexp.MustCompile(`^(.+_)?(source_image|disk_image)$`), GCEDiskImage},
+	{regexp.MustCompile(`^(.+_)?email$`), EmailAddress},
+}
+
+// argExtensions maps resource arguments to the extension type of a
+// variable that is passed to them. Arguments in nested blocks are keyed
+// as "<block>.<argument>" and take precedence over the argument name.
+var argExtensions = map[string]ExtensionType{
+	"zone":                     GCEZone,
+	"region":                   GCERegion,
+	"machine_type":             GCEMachineType,
+	"network":                  GCENetwork,
+	"subnetwork":               GCESubnetwork,
+	"service_account_email":    IAMServiceAccount,
+	"service_account.email":    IAMServiceAccount,
+	"bucket":                   GCSBucket,
+	"source_image":             GCEDiskImage,
+	"initialize_params.image":  GCEDiskImage,
+	"initialize_params.type":   GCEDiskType,
+	"initialize_params.size":   GCEDiskSize,
+	"disk.source_image":        GCEDiskImage,
+	"disk.disk_type":           GCEDiskType,
+	"disk.disk_size_gb":        GCEDiskSize,
+	"google_compute_disk.type": GCEDiskType,
+	"google_compute_disk.size": GCEDiskSize,
+}
+
+// numericExtensions are extension types that only apply to numeric variables.
+// All other types apply to string variables.
+var numericExtensions = map[ExtensionType]bool{
+	GCEDiskSize: true,
+}
+
+// getVariableUsages returns the extension type for variables that are
+// passed directly to a resource argument that implies one e.g. a variable
+// passed to "zone" of a google_compute_instance is a GCE zone. Variables
+// that are passed to arguments of different types are marked as undefined.
+func getVariableUsages(configPath string) (map[string]ExtensionType, error) {
+	files, err := filepath.Glob(filepath.Join(configPath, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	usages := make(map[string]ExtensionType)
+	p := hclparse.NewParser()
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		file, diags := p.ParseHCL(b, filepath.Base(f))
+		err = hasHclErrors(diags)
+		if err != nil {
+			return nil, err
+		}
+
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		for _, block := range body.Blocks {
+			if block.Type != "resource" || len(block.Labels) != 2 {
+				continue
+			}
+
+			collectVariableUsages(block.Body, block.Labels[0], usages)
+		}
+	}
+
+	return usages, nil
+}
+
+// collectVariableUsages walks a resource body and records the extension
+// type for variables passed directly to known arguments
+func collectVariableUsages(body *hclsyntax.Body, parent string, usages map[string]ExtensionType) {
+	for name, attr := range body.Attributes {
+		v := varRefName(attr.Expr)
+		if v == "" {
+			continue
+		}
+
+		ext, known := argExtensions[parent+"."+name]
+		if !known {
+			ext, known = argExtensions[name]
+		}
+
+		if !known {
+			continue
+		}
+
+		// variables used as different types are ambiguous
+		if prev, used := usages[v]; used && prev != ext {
+			ext = ExtTypeUndefined
+		}
+
+		usages[v] = ext
+	}
+
+	for _, b := range body.Blocks {
+		collectVariableUsages(b.Body, b.Type, usages)
+	}
+}
+
+// varRefName returns the variable name if expr is a reference to a
+// variable i.e. var.<name>
+func varRefName(expr hclsyntax.Expression) string {
+	t, isTraversal := expr.(*hclsyntax.ScopeTraversalExpr)
+	if !isTraversal || len(t.Traversal) != 2 || t.Traversal.RootName() != "var" {
+		return ""
+	}
+
+	attr, isAttr := t.Traversal[1].(hcl.TraverseAttr)
+	if !isAttr {
+		return ""
+	}
+
+	return attr.Name
+}
+
+// inferExtension proposes an extension type for a variable based on how
+// it is used in resources and falls back to the variable name
+func inferExtension(v BlueprintVariable, usages map[string]ExtensionType) ExtensionType {
+	ext, used := usages[v.Name]
+	if !used || ext == ExtTypeUndefined {
+		ext = ""
+		for _, n := range nameExtensions {
+			if n.re.MatchString(v.Name) {
+				ext = n.ext
+				break
+			}
+		}
+	}
+
+	if ext == "" {
+		return ""
+	}
+
+	// untyped variables are assumed to be compatible
+	switch v.VarType {
+	case "", "any":
+		return ext
+	case "number":
+		if numericExtensions[ext] {
+			return ext
+		}
+	case "string":
+		if !numericExtensions[ext] {
+			return ext
+		}
+	}
+
+	return ""
+}
+
+// extensionLink is a field of an extension that links a variable of the
+// extension type to a related variable of the target type
+type extensionLink struct {
+	name   string
+	ext    ExtensionType
+	target ExtensionType
+	ptr    func(xgp *GooglePropertyExtension) *string
+}
+
+var extensionLinks = []extensionLink{
+	{"zoneProperty", GCEMachineType, GCEZone, func(xgp *GooglePropertyExtension) *string { return &xgp.ZoneProperty }},
+	{"gceSubnetwork.networkVariable", GCESubnetwork, GCENetwork, func(xgp *GooglePropertyExtension) *string { return &xgp.GCESubnetwork.NetworkVariable }},
+	{"gceDiskSize.diskTypeVariable", GCEDiskSize, GCEDiskType, func(xgp *GooglePropertyExtension) *string { return &xgp.GCEDiskSize.DiskTypeVariable }},
+}
+
+// applyVariableExtensions sets inferred extensions on display variables and
+// links related variables e.g. a machine type to its zone. Inferred values
+// are tracked in the ownership so they are refreshed on every run, while
+// values that are manually authored are never overridden.
+func applyVariableExtensions(vars []BlueprintVariable, usages map[string]ExtensionType, input *BlueprintUIInput, o *metadataOwnership) {
+	if o.Fields == nil {
+		o.Fields = make(map[string]*fieldOwnership)
+	}
+
+	// types are applied first so that links are resolved against the
+	// types that are kept, including manually authored ones
+	for _, v := range vars {
+		dv, hasDisplayVar := input.Variables[v.Name]
+		if !hasDisplayVar || dv == nil {
+			continue
+		}
+
+		ext := inferExtension(v, usages)
+		p := fmt.Sprintf("spec.ui.input.variables[%s].xGoogleProperty.type", v.Name)
+		mergeDisplayValue(p, reflect.ValueOf(&dv.XGoogleProperty.Type).Elem(), reflect.ValueOf(ext), o)
+	}
+
+	targets := make(map[ExtensionType]string)
+	for _, l := range extensionLinks {
+		targets[l.target] = linkedVariable(input, l.target)
+	}
+
+	for _, v := range vars {
+		dv, hasDisplayVar := input.Variables[v.Name]
+		if !hasDisplayVar || dv == nil {
+			continue
+		}
+
+		for _, l := range extensionLinks {
+			linked := ""
+			if dv.XGoogleProperty.Type == l.ext {
+				linked = targets[l.target]
+			}
+
+			p := fmt.Sprintf("spec.ui.input.variables[%s].xGoogleProperty.%s", v.Name, l.name)
+			mergeDisplayValue(p, reflect.ValueOf(l.ptr(&dv.XGoogleProperty)).Elem(), reflect.ValueOf(linked), o)
+		}
+	}
+}
+
+// linkedVariable returns the display variable with the given extension
+// type that related variables should be linked to. A variable named after
+// the extension type is preferred when there are several. An empty string
+// is returned if there isn't exactly one candidate.
+func linkedVariable(input *BlueprintUIInput, ext ExtensionType) string {
+	preferred := map[ExtensionType]string{
+		GCEZone:     "zone",
+		GCENetwork:  "network",
+		GCEDiskType: "disk_type",
+	}
+
+	var candidates []string
+	for n, dv := range input.Variables {
+		if dv == nil || dv.XGoogleProperty.Type != ext {
+			continue
+		}
+
+		if n == preferred[ext] {
+			return n
+		}
+
+		candidates = append(candidates, n)
+	}
+
+	if len(candidates) != 1 {
+		return ""
+	}
+
+	return candidates[0]
+}
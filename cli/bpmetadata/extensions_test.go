@@ -0,0 +1,167 @@
+package bpmetadata
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariableUsages(t *testing.T) {
+	got, err := getVariableUsages(path.Join(tfTestdataPath, "extensions"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]ExtensionType{
+		"location":         GCEZone,
+		"vm_type":          ExtTypeUndefined,
+		"image":            GCEDiskImage,
+		"boot_disk_type":   GCEDiskType,
+		"boot_disk_size":   GCEDiskSize,
+		"subnet_self_link": GCESubnetwork,
+		"sa":               IAMServiceAccount,
+		"vpc":              GCENetwork,
+	}, got)
+}
+
+func TestInferExtension(t *testing.T) {
+	usages := map[string]ExtensionType{
+		"location":     GCEZone,
+		"machine_type": ExtTypeUndefined,
+	}
+
+	tests := []struct {
+		name    string
+		v       BlueprintVariable
+		wantExt ExtensionType
+	}{
+		{
+			name:    "from usage",
+			v:       BlueprintVariable{Name: "location", VarType: "string"},
+			wantExt: GCEZone,
+		},
+		{
+			name:    "from name when usage is ambiguous",
+			v:       BlueprintVariable{Name: "machine_type", VarType: "string"},
+			wantExt: GCEMachineType,
+		},
+		{
+			name:    "from name with prefix",
+			v:       BlueprintVariable{Name: "primary_region"},
+			wantExt: GCERegion,
+		},
+		{
+			name:    "service account email",
+			v:       BlueprintVariable{Name: "service_account_email", VarType: "string"},
+			wantExt: IAMServiceAccount,
+		},
+		{
+			name:    "bucket name",
+			v:       BlueprintVariable{Name: "bucket_name", VarType: "string"},
+			wantExt: GCSBucket,
+		},
+		{
+			name:    "subnetwork is not a network",
+			v:       BlueprintVariable{Name: "subnetwork", VarType: "string"},
+			wantExt: GCESubnetwork,
+		},
+		{
+			name:    "numeric disk size",
+			v:       BlueprintVariable{Name: "disk_size_gb", VarType: "number"},
+			wantExt: GCEDiskSize,
+		},
+		{
+			name: "incompatible type",
+			v:    BlueprintVariable{Name: "zones", VarType: "list(string)"},
+		},
+		{
+			name: "string disk size",
+			v:    BlueprintVariable{Name: "disk_size_gb", VarType: "string"},
+		},
+		{
+			name: "no match",
+			v:    BlueprintVariable{Name: "network_tier", VarType: "string"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantExt, inferExtension(tt.v, usages))
+		})
+	}
+}
+
+func TestApplyVariableExtensions(t *testing.T) {
+	vars := []BlueprintVariable{
+		{Name: "zone", VarType: "string"},
+		{Name: "machine_type", VarType: "string"},
+		{Name: "network", VarType: "string"},
+		{Name: "subnetwork", VarType: "string"},
+		{Name: "disk_type", VarType: "string"},
+		{Name: "disk_size_gb", VarType: "number"},
+		{Name: "region", VarType: "string"},
+	}
+
+	input := &BlueprintUIInput{
+		Variables: map[string]*DisplayVariable{
+			"zone":         {Name: "zone"},
+			"machine_type": {Name: "machine_type"},
+			"network":      {Name: "network"},
+			"subnetwork":   {Name: "subnetwork"},
+			"disk_type":    {Name: "disk_type"},
+			"disk_size_gb": {Name: "disk_size_gb"},
+			"region": {Name: "region", XGoogleProperty: GooglePropertyExtension{
+				Type: GCEGenericResource,
+			}},
+		},
+	}
+
+	applyVariableExtensions(vars, nil, input, &metadataOwnership{})
+	assert.Equal(t, GooglePropertyExtension{Type: GCEZone}, input.Variables["zone"].XGoogleProperty)
+	assert.Equal(t, GooglePropertyExtension{Type: GCEMachineType, ZoneProperty: "zone"}, input.Variables["machine_type"].XGoogleProperty)
+	assert.Equal(t, GooglePropertyExtension{Type: GCENetwork}, input.Variables["network"].XGoogleProperty)
+	assert.Equal(t, GooglePropertyExtension{
+		Type:          GCESubnetwork,
+		GCESubnetwork: GCESubnetworkExtension{NetworkVariable: "network"},
+	}, input.Variables["subnetwork"].XGoogleProperty)
+	assert.Equal(t, GooglePropertyExtension{
+		Type:        GCEDiskSize,
+		GCEDiskSize: GCEDiskSizeExtension{DiskTypeVariable: "disk_type"},
+	}, input.Variables["disk_size_gb"].XGoogleProperty)
+
+	// customized variables are never overridden
+	assert.Equal(t, GooglePropertyExtension{Type: GCEGenericResource}, input.Variables["region"].XGoogleProperty)
+}
+
+func TestVariableExtensionsAreRefreshed(t *testing.T) {
+	vars := []BlueprintVariable{
+		{Name: "machine_type", VarType: "string"},
+		{Name: "size", VarType: "string"},
+	}
+
+	input := &BlueprintUIInput{
+		Variables: map[string]*DisplayVariable{
+			"machine_type": {Name: "machine_type"},
+			"size":         {Name: "size"},
+		},
+	}
+
+	o := &metadataOwnership{}
+	usages := map[string]ExtensionType{"size": GCEMachineType}
+	applyVariableExtensions(vars, usages, input, o)
+	assert.Equal(t, GooglePropertyExtension{Type: GCEMachineType}, input.Variables["machine_type"].XGoogleProperty)
+	assert.Equal(t, GooglePropertyExtension{Type: GCEMachineType}, input.Variables["size"].XGoogleProperty)
+
+	// a zone variable that is added later is linked and an inferred type
+	// that no longer applies is removed
+	vars = append(vars, BlueprintVariable{Name: "zone", VarType: "string"})
+	input.Variables["zone"] = &DisplayVariable{Name: "zone"}
+	applyVariableExtensions(vars, nil, input, o)
+	assert.Equal(t, GooglePropertyExtension{Type: GCEMachineType, ZoneProperty: "zone"}, input.Variables["machine_type"].XGoogleProperty)
+	assert.Equal(t, GooglePropertyExtension{}, input.Variables["size"].XGoogleProperty)
+
+	// a type edited by hand is kept and its link is removed
+	input.Variables["machine_type"].XGoogleProperty.Type = GCEGenericResource
+	applyVariableExtensions(vars, nil, input, o)
+	assert.Equal(t, GooglePropertyExtension{Type: GCEGenericResource}, input.Variables["machine_type"].XGoogleProperty)
+	assert.Equal(t, ownerManual, o.Fields["spec.ui.input.variables[machine_type].xGoogleProperty.type"].Owner)
+}
@@ -1,7 +1,9 @@
 package bpmetadata
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
@@ -21,134 +23,253 @@ type mdListItem struct {
 	url  string
 }
 
-var reTimeEstimate = regexp.MustCompile(`(Configuration|Deployment):\s([0-9]+)\smins`)
+var (
+	errMdHeadingNotFound = errors.New("unable to find md heading")
+	errMdContentNotFound = errors.New("unable to find md content")
+)
+
+var (
+	reTimeEstimate = regexp.MustCompile(`(?im)^\W*(Configuration|Deployment)(?:\s+(?:time|duration))?\s*:\s*(.+)$`)
+	reDurationPart = regexp.MustCompile(`(?i)([0-9]+(?:\.[0-9]+)?)\s*(hours?|hrs?|h|minutes?|mins?|m|seconds?|secs?|s)\b`)
+)
+
+// durationUnitSecs maps the first letter of a duration unit to seconds
+var durationUnitSecs = map[byte]float64{
+	'h': 3600,
+	'm': 60,
+	's': 1,
+}
 
 // getMdContent accepts 3 types of content requests and return and mdContent object
 // with the relevant content info. The 3 scenarios are:
 // 1: get heading literal by (level and/or order) OR by title
-// 2: get paragraph content immediately following a heading by (level and/or order) OR by title
-// 3: get list item content immediately following a heading by (level and/or order) OR by title
-// A -1 value to headLevel/headOrder enforces the content to be matchd by headTitle
+// 2: get paragraph content following a heading by (level and/or order) OR by title
+// 3: get list item content following a heading by (level and/or order) OR by title
+// A -1 value to headLevel/headOrder enforces the content to be matchd by headTitle.
+// Titles are matched ignoring case, inline formatting and a trailing colon. The
+// content of a heading spans all blocks up to the next heading, with paragraphs
+// joined by blank lines and list items taken from the first list.
 func getMdContent(content []byte, headLevel int, headOrder int, headTitle string, getContent bool) (*mdContent, error) {
 	mdDocument := markdown.Parse(content, nil)
 	orderCtr := 0
+	foundAny := false
 	mdSections := mdDocument.GetChildren()
-	var foundHead bool
-	for _, section := range mdSections {
-		// if the first child is nil, it's a comment and we don't
-		// need to evaluate it
-		if ast.GetFirstChild(section) == nil {
+	for i, section := range mdSections {
+		h, isHeading := section.(*ast.Heading)
+		if !isHeading {
 			continue
 		}
 
-		currLeaf := ast.GetFirstChild(section).AsLeaf()
-		switch sectionType := section.(type) {
+		if headLevel == h.Level {
+			orderCtr++
+		}
+
+		isMatch := headOrder == orderCtr && headLevel == h.Level
+		if headTitle != "" {
+			isMatch = sameMdTitle(mdText(h), headTitle)
+		}
+
+		if !isMatch {
+			continue
+		}
+
+		if !getContent {
+			return &mdContent{
+				literal: mdText(h),
+			}, nil
+		}
+
+		// keep looking for a later heading with the same title
+		// that has content
+		foundAny = true
+		if c := getMdSectionContent(mdSections[i+1:]); c != nil {
+			return c, nil
+		}
+	}
+
+	if foundAny {
+		return nil, errMdContentNotFound
+	}
+
+	return nil, errMdHeadingNotFound
+}
+
+// getMdSectionContent returns the content of the blocks up to the next
+// heading or nil if there isn't any
+func getMdSectionContent(blocks []ast.Node) *mdContent {
+	var c mdContent
+	var paragraphs []string
+	for _, b := range blocks {
+		switch block := b.(type) {
 		case *ast.Heading:
-			foundHead = false
-			if headTitle == string(currLeaf.Literal) {
-				foundHead = true
+			return newMdContent(c, paragraphs)
+		case *ast.Paragraph:
+			// a link ending the first paragraph is the link content
+			// e.g. for cost estimates
+			if l, isLink := ast.GetLastChild(block).(*ast.Link); isLink && len(paragraphs) == 0 {
+				c.url = string(l.Destination)
 			}
 
-			if headLevel == sectionType.Level {
-				orderCtr++
+			if t := mdText(block); t != "" {
+				paragraphs = append(paragraphs, t)
 			}
-
-			if !getContent && (headOrder == orderCtr || foundHead) {
-				return &mdContent{
-					literal: string(currLeaf.Literal),
-				}, nil
+		case *ast.List:
+			if c.listItems == nil {
+				c.listItems = getMdListItems(block)
 			}
+		}
+	}
 
-		case *ast.Paragraph:
-			if getContent && (headOrder == orderCtr || foundHead) {
-				// check if the content is a link
-				l := ast.GetLastChild(currLeaf.Parent)
-				lNode, isLink := l.(*ast.Link)
-				if isLink {
-					return &mdContent{
-						literal: string(ast.GetFirstChild(lNode).AsLeaf().Literal),
-						url:     string(lNode.Destination),
-					}, nil
-				}
+	return newMdContent(c, paragraphs)
+}
+
+func newMdContent(c mdContent, paragraphs []string) *mdContent {
+	c.literal = strings.Join(paragraphs, "\n\n")
+	if c.literal == "" && len(c.listItems) == 0 {
+		return nil
+	}
 
-				return &mdContent{
-					literal: string(currLeaf.Literal),
-				}, nil
+	return &c
+}
+
+// getMdListItems returns the text of each item in a list. Items with a
+// link are represented by the link's text and destination.
+func getMdListItems(list *ast.List) []mdListItem {
+	var mdListItems []mdListItem
+	for _, item := range list.Children {
+		var listItem mdListItem
+		ast.WalkFunc(item, func(n ast.Node, entering bool) ast.WalkStatus {
+			l, isLink := n.(*ast.Link)
+			if !entering || !isLink {
+				return ast.GoToNext
 			}
 
-		case *ast.List:
-			if getContent && (headOrder == orderCtr || foundHead) {
-				var mdListItems []mdListItem
-				for _, c := range sectionType.Container.Children {
-					var listItem mdListItem
-					// each item is a list with data and metadata about the list item
-					itemConfigs := ast.GetFirstChild(c).AsContainer().Children
-					// if the length of the child node is 1, it is a plain text list item
-					// if the length is greater the 1, it is a list item with a link
-					if len(itemConfigs) == 1 {
-						listItemText := string(itemConfigs[0].AsLeaf().Literal)
-						listItem = mdListItem{
-							text: listItemText,
-						}
-					} else if len(itemConfigs) > 1 {
-						// the second child node has the link data and metadata
-						listItemLink := itemConfigs[1].(*ast.Link)
-						listItemText := string(ast.GetFirstChild(listItemLink).AsLeaf().Literal)
-
-						listItem = mdListItem{
-							text: listItemText,
-							url:  string(listItemLink.Destination),
-						}
-					}
-
-					mdListItems = append(mdListItems, listItem)
-				}
+			listItem = mdListItem{
+				text: mdText(l),
+				url:  string(l.Destination),
+			}
+
+			return ast.Terminate
+		})
+
+		if listItem.url == "" {
+			listItem.text = mdText(item)
+		}
+
+		mdListItems = append(mdListItems, listItem)
+	}
 
-				return &mdContent{
-					listItems: mdListItems,
-				}, nil
+	return mdListItems
+}
+
+// mdText returns the plain text of a markdown node without inline
+// formatting. Lines are kept with their whitespace collapsed.
+func mdText(n ast.Node) string {
+	var b strings.Builder
+	ast.WalkFunc(n, func(n ast.Node, entering bool) ast.WalkStatus {
+		if !entering {
+			if _, isPara := n.(*ast.Paragraph); isPara {
+				b.WriteString("\n")
 			}
+
+			return ast.GoToNext
+		}
+
+		switch node := n.(type) {
+		case *ast.Text:
+			b.Write(node.Literal)
+		case *ast.Code:
+			b.Write(node.Literal)
+		case *ast.Softbreak, *ast.Hardbreak:
+			b.WriteString("\n")
+		case *ast.Image, *ast.HTMLSpan:
+			return ast.SkipChildren
+		}
+
+		return ast.GoToNext
+	})
+
+	var lines []string
+	for _, l := range strings.Split(b.String(), "\n") {
+		if l = strings.Join(strings.Fields(l), " "); l != "" {
+			lines = append(lines, l)
 		}
 	}
 
-	return nil, fmt.Errorf("unable to find md content")
+	return strings.Join(lines, "\n")
+}
+
+// sameMdTitle compares heading titles ignoring case, whitespace
+// and a trailing colon
+func sameMdTitle(a, b string) bool {
+	normalize := func(s string) string {
+		s = strings.TrimSuffix(strings.TrimSpace(s), ":")
+		return strings.ToLower(strings.Join(strings.Fields(s), " "))
+	}
+
+	return normalize(a) == normalize(b)
 }
 
 // getDeploymentDuration creates the deployment and configuration time
-// estimates for the blueprint from README.md
+// estimates for the blueprint from README.md. Durations can be written
+// in hours, minutes and seconds e.g. "1 hour", "90 seconds" or "1h 30m".
 func getDeploymentDuration(content []byte, headTitle string) (*BlueprintTimeEstimate, error) {
 	durationDetails, err := getMdContent(content, -1, -1, headTitle, true)
 	if err != nil {
 		return nil, err
 	}
 
-	matches := reTimeEstimate.FindAllStringSubmatch(durationDetails.literal, -1)
-	if len(matches) == 0 {
-		return nil, fmt.Errorf("unable to find deployment duration")
+	lines := []string{durationDetails.literal}
+	for _, li := range durationDetails.listItems {
+		lines = append(lines, li.text)
 	}
 
+	matches := reTimeEstimate.FindAllStringSubmatch(strings.Join(lines, "\n"), -1)
 	var timeEstimate BlueprintTimeEstimate
+	found := false
 	for _, m := range matches {
-		// each m[2] will have the time in mins
-		i, err := strconv.Atoi(m[2])
+		secs, err := parseDurationSecs(m[2])
 		if err != nil {
 			continue
 		}
 
-		if m[1] == "Configuration" {
-			timeEstimate.ConfigurationSecs = i * 60
-			continue
+		found = true
+		switch strings.ToLower(m[1]) {
+		case "configuration":
+			timeEstimate.ConfigurationSecs = secs
+		case "deployment":
+			timeEstimate.DeploymentSecs = secs
 		}
+	}
 
-		if m[1] == "Deployment" {
-			timeEstimate.DeploymentSecs = i * 60
-			continue
-		}
+	if !found {
+		return nil, fmt.Errorf("unable to find deployment duration")
 	}
 
 	return &timeEstimate, nil
 }
 
+// parseDurationSecs parses a duration written in words e.g. "1 hour 30 mins"
+// and returns it in seconds
+func parseDurationSecs(s string) (int, error) {
+	parts := reDurationPart.FindAllStringSubmatch(s, -1)
+	if len(parts) == 0 {
+		return 0, fmt.Errorf("unable to parse duration: %s", s)
+	}
+
+	var secs float64
+	for _, p := range parts {
+		n, err := strconv.ParseFloat(p[1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("unable to parse duration: %s", s)
+		}
+
+		secs += n * durationUnitSecs[strings.ToLower(p[2])[0]]
+	}
+
+	return int(math.Round(secs)), nil
+}
+
 // getCostEstimate creates the cost estimates from the cost calculator
 // links provided in README.md
 func getCostEstimate(content []byte, headTitle string) (*BlueprintCostEstimate, error) {
@@ -163,7 +284,10 @@ func getCostEstimate(content []byte, headTitle string) (*BlueprintCostEstimate,
 	}, nil
 }
 
-// getArchitctureInfo parses and builds Architecture details from README.md
+// getArchitctureInfo parses and builds Architecture details from README.md.
+// The diagram is the first image, or else link, in the paragraph following
+// the heading and the description is the text after it or the list following
+// the paragraph.
 func getArchitctureInfo(content []byte, headTitle string) (*BlueprintArchitecture, error) {
 	mdDocument := markdown.Parse(content, nil)
 	if mdDocument == nil {
@@ -171,50 +295,126 @@ func getArchitctureInfo(content []byte, headTitle string) (*BlueprintArchitectur
 	}
 
 	children := mdDocument.GetChildren()
-	for _, node := range children {
+	for i, node := range children {
 		h, isHeading := node.(*ast.Heading)
-		if !isHeading {
-			continue
-		}
-
-		// check if this is the architecture heading
-		hLiteral := string(ast.GetFirstChild(h).AsLeaf().Literal)
-		if hLiteral != headTitle {
+		if !isHeading || !sameMdTitle(mdText(h), headTitle) || i+1 == len(children) {
 			continue
 		}
 
 		//get architecture details
-		infoNode := ast.GetNextNode(h)
-		paraNode, isPara := infoNode.(*ast.Paragraph)
+		paraNode, isPara := children[i+1].(*ast.Paragraph)
 		if !isPara {
 			continue
 		}
 
-		t := ast.GetLastChild(paraNode)
-		_, isText := t.(*ast.Text)
-		if !isText {
+		diagram, desc := getMdDiagram(paraNode)
+		if diagram == "" {
 			continue
 		}
 
-		d := strings.TrimLeft(string(t.AsLeaf().Literal), "\n")
-		dList := strings.Split(d, "\n")
-		i := ast.GetPrevNode(t)
-		iNode, isImage := i.(*ast.Image)
-		if isImage {
-			return &BlueprintArchitecture{
-				Description: dList,
-				DiagramURL:  string(iNode.Destination),
-			}, nil
+		if len(desc) == 0 && i+2 < len(children) {
+			if l, isList := children[i+2].(*ast.List); isList {
+				for _, li := range getMdListItems(l) {
+					desc = append(desc, li.text)
+				}
+			}
 		}
 
-		lNode, isLink := i.(*ast.Link)
-		if isLink {
-			return &BlueprintArchitecture{
-				Description: dList,
-				DiagramURL:  string(lNode.Destination),
-			}, nil
-		}
+		return &BlueprintArchitecture{
+			Description: desc,
+			DiagramURL:  diagram,
+		}, nil
 	}
 
 	return nil, fmt.Errorf("unable to find architecture content")
 }
+
+// getMdDiagram returns the destination of the first image, or else link,
+// in a paragraph and the lines of text following it
+func getMdDiagram(para *ast.Paragraph) (string, []string) {
+	idx, diagram := -1, ""
+	for i, c := range para.Children {
+		if img, isImage := c.(*ast.Image); isImage {
+			idx, diagram = i, string(img.Destination)
+			break
+		}
+
+		if l, isLink := c.(*ast.Link); isLink && idx == -1 {
+			idx, diagram = i, string(l.Destination)
+		}
+	}
+
+	if idx == -1 {
+		return "", nil
+	}
+
+	var desc []string
+	for _, c := range para.Children[idx+1:] {
+		if t := mdText(c); t != "" {
+			desc = append(desc, strings.Split(t, "\n")...)
+		}
+	}
+
+	return diagram, desc
+}
+
+// readmeSectionIssue is an expected README section that was missing
+// or couldn't be parsed
+type readmeSectionIssue struct {
+	section string
+	problem string
+}
+
+// checkReadmeSections returns the expected sections of a blueprint README
+// that are missing or couldn't be parsed into metadata
+func checkReadmeSections(content []byte) []readmeSectionIssue {
+	var issues []readmeSectionIssue
+	add := func(section string, err error) {
+		switch {
+		case err == nil:
+		case errors.Is(err, errMdHeadingNotFound):
+			issues = append(issues, readmeSectionIssue{section, "missing"})
+		default:
+			issues = append(issues, readmeSectionIssue{section, err.Error()})
+		}
+	}
+
+	for _, section := range []string{"Tagline", "Detailed", "PreDeploy"} {
+		c, err := getMdContent(content, -1, -1, section, true)
+		if err == nil && c.literal == "" {
+			err = fmt.Errorf("no paragraph under the heading")
+		}
+
+		add(section, err)
+	}
+
+	arch, err := getMdContent(content, -1, -1, "Architecture", true)
+	if err == nil && len(arch.listItems) == 0 {
+		if _, diagErr := getArchitctureInfo(content, "Architecture"); diagErr != nil {
+			err = fmt.Errorf("no list of steps or diagram under the heading")
+		}
+	}
+
+	add("Architecture", err)
+
+	if _, err := getMdContent(content, -1, -1, "Deployment Duration", true); err != nil {
+		add("Deployment Duration", err)
+	} else if _, err := getDeploymentDuration(content, "Deployment Duration"); err != nil {
+		add("Deployment Duration", fmt.Errorf("no durations like \"Deployment: 10 mins\" under the heading"))
+	}
+
+	cost, err := getMdContent(content, -1, -1, "Cost", true)
+	if err == nil && cost.url == "" {
+		err = fmt.Errorf("no cost calculator link under the heading")
+	}
+
+	add("Cost", err)
+
+	docs, err := getMdContent(content, -1, -1, "Documentation", true)
+	if err == nil && len(docs.listItems) == 0 {
+		err = fmt.Errorf("no list of links under the heading")
+	}
+
+	add("Documentation", err)
+	return issues
+}
@@ -318,3 +318,176 @@ func TestProcessCostEstimateContent(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessRobustMarkdownContent(t *testing.T) {
+	content, err := os.ReadFile(path.Join(mdTestdataPath, "robust-content.md"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		level      int
+		order      int
+		title      string
+		getContent bool
+		want       *mdContent
+	}{
+		{
+			name:  "heading with inline code",
+			level: 1,
+			order: 1,
+			want: &mdContent{
+				literal: "terraform-google-robust module",
+			},
+		},
+		{
+			name:       "title with a trailing colon and a link in the content",
+			level:      -1,
+			order:      -1,
+			title:      "Tagline",
+			getContent: true,
+			want: &mdContent{
+				literal: "A robust blueprint",
+			},
+		},
+		{
+			name:       "title with different case and several paragraphs",
+			level:      -1,
+			order:      -1,
+			title:      "Detailed",
+			getContent: true,
+			want: &mdContent{
+				literal: "Creates a robust deployment\nwith several features.\n\nSpans a second paragraph.",
+			},
+		},
+		{
+			name:       "link at the end of a paragraph",
+			level:      -1,
+			order:      -1,
+			title:      "Cost",
+			getContent: true,
+			want: &mdContent{
+				literal: "Estimated with the cost calculator",
+				url:     "https://cloud.google.com/products/calculator",
+			},
+		},
+		{
+			name:       "list items with inline formatting",
+			level:      -1,
+			order:      -1,
+			title:      "Documentation",
+			getContent: true,
+			want: &mdContent{
+				listItems: []mdListItem{
+					{text: "Guide with code", url: "https://example.com/guide"},
+					{text: "Plain item"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getMdContent(content, tt.level, tt.order, tt.title, tt.getContent)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	arch, err := getArchitctureInfo(content, "Architecture")
+	require.NoError(t, err)
+	assert.Equal(t, &BlueprintArchitecture{
+		Description: []string{"Step one", "Step two"},
+		DiagramURL:  "https://example.com/diagram.png",
+	}, arch)
+
+	d, err := getDeploymentDuration(content, "Deployment Duration")
+	require.NoError(t, err)
+	assert.Equal(t, &BlueprintTimeEstimate{ConfigurationSecs: 5400, DeploymentSecs: 90}, d)
+
+	d, err = getDeploymentDuration(content, "Deployment Duration Short")
+	require.NoError(t, err)
+	assert.Equal(t, &BlueprintTimeEstimate{ConfigurationSecs: 5400, DeploymentSecs: 2700}, d)
+}
+
+func TestParseDurationSecs(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{in: "10 mins", want: 600},
+		{in: "1 hour", want: 3600},
+		{in: "90 seconds", want: 90},
+		{in: "1h 30m", want: 5400},
+		{in: "2 hours and 15 minutes", want: 8100},
+		{in: "1.5 hrs", want: 5400},
+		{in: "a while", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseDurationSecs(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCheckReadmeSections(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		want     []readmeSectionIssue
+	}{
+		{
+			name:     "all sections",
+			fileName: "robust-content.md",
+			want: []readmeSectionIssue{
+				{"PreDeploy", "missing"},
+			},
+		},
+		{
+			name:     "missing sections",
+			fileName: "simple-content.md",
+			want: []readmeSectionIssue{
+				{"Tagline", "missing"},
+				{"Detailed", "missing"},
+				{"PreDeploy", "missing"},
+				{"Architecture", "missing"},
+				{"Documentation", "missing"},
+			},
+		},
+		{
+			name:     "titles matched ignoring case",
+			fileName: "list-content.md",
+			want: []readmeSectionIssue{
+				{"Deployment Duration", "missing"},
+				{"Cost", "missing"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := os.ReadFile(path.Join(mdTestdataPath, tt.fileName))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, checkReadmeSections(content))
+		})
+	}
+
+	content := []byte("## Deployment Duration\nA while\n\n## Cost\nExpensive\n\n## Documentation\nSee the docs\n")
+	assert.Equal(t, []readmeSectionIssue{
+		{"Tagline", "missing"},
+		{"Detailed", "missing"},
+		{"PreDeploy", "missing"},
+		{"Architecture", "missing"},
+		{"Deployment Duration", `no durations like "Deployment: 10 mins" under the heading`},
+		{"Cost", "no cost calculator link under the heading"},
+		{"Documentation", "no list of links under the heading"},
+	}, checkReadmeSections(content))
+}
@@ -0,0 +1,245 @@
+package bpmetadata
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/cli/util"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// rootModuleLocation is the location of the root module of a blueprint
+// relative to itself
+const rootModuleLocation = "."
+
+// moduleMetaArgs are the arguments of a module block that aren't inputs
+var moduleMetaArgs = map[string]bool{
+	"source":     true,
+	"version":    true,
+	"count":      true,
+	"for_each":   true,
+	"providers":  true,
+	"depends_on": true,
+}
+
+// reRegistrySource matches Terraform registry module addresses with an
+// optional dir within the module i.e.
+// [<host>/]<namespace>/<name>/<provider>[//<dir>]
+var reRegistrySource = regexp.MustCompile(`^(?:([\w.-]+\.[\w.-]+)/)?([\w-]+)/([\w-]+)/([\w-]+)(?://(.*))?$`)
+
+// moduleCall is a module block in a Terraform config
+type moduleCall struct {
+	name    string
+	source  string
+	version string
+	inputs  []string
+}
+
+// getModuleCalls returns the module blocks in the Terraform config at
+// configPath sorted by name. Sources and versions are only set if they
+// are string literals.
+func getModuleCalls(configPath string) ([]moduleCall, error) {
+	files, err := filepath.Glob(filepath.Join(configPath, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	var calls []moduleCall
+	p := hclparse.NewParser()
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		file, diags := p.ParseHCL(b, filepath.Base(f))
+		err = hasHclErrors(diags)
+		if err != nil {
+			return nil, err
+		}
+
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		for _, block := range body.Blocks {
+			if block.Type != "module" || len(block.Labels) != 1 {
+				continue
+			}
+
+			call := moduleCall{name: block.Labels[0]}
+			for name, attr := range block.Body.Attributes {
+				switch {
+				case name == "source":
+					call.source = literalString(attr.Expr)
+				case name == "version":
+					call.version = literalString(attr.Expr)
+				case !moduleMetaArgs[name]:
+					call.inputs = append(call.inputs, name)
+				}
+			}
+
+			sort.Strings(call.inputs)
+			calls = append(calls, call)
+		}
+	}
+
+	sort.SliceStable(calls, func(i, j int) bool { return calls[i].name < calls[j].name })
+	return calls, nil
+}
+
+// literalString returns the value of expr if it is a string literal
+func literalString(expr hclsyntax.Expression) string {
+	v, diags := expr.Value(nil)
+	if diags.HasErrors() || v.IsNull() || !v.IsKnown() || v.Type() != cty.String {
+		return ""
+	}
+
+	return v.AsString()
+}
+
+// isLocalModuleSource returns true if a module source is a local path
+func isLocalModuleSource(source string) bool {
+	return strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")
+}
+
+// parseModuleSource splits a remote module source into the repo it is in
+// and the dir of the module within the repo. Repos are identified by
+// host and path e.g. "github.com/foo/bar" for all of:
+// registry sources e.g. "foo/bar/google" (as "github.com/foo/terraform-google-bar"),
+// "github.com/foo/bar//modules/baz", "git::https://github.com/foo/bar.git?ref=v1.0.0"
+// and "git@github.com:foo/bar.git".
+func parseModuleSource(source string) (string, string) {
+	source = strings.TrimPrefix(source, "git::")
+	if i := strings.Index(source, "?"); i != -1 {
+		source = source[:i]
+	}
+
+	// the dir within the repo follows a double slash that isn't
+	// part of the scheme
+	var dir string
+	schemeEnd := 0
+	if i := strings.Index(source, "://"); i != -1 {
+		schemeEnd = i + len("://")
+	}
+
+	if i := strings.Index(source[schemeEnd:], "//"); i != -1 {
+		dir = strings.Trim(source[schemeEnd+i+len("//"):], "/")
+		source = source[:schemeEnd+i]
+	}
+
+	if m := reRegistrySource.FindStringSubmatch(source); m != nil && (m[1] == "" || m[1] == "registry.terraform.io") {
+		return strings.ToLower("github.com/" + m[2] + "/terraform-" + m[4] + "-" + m[3]), dir
+	}
+
+	if !strings.Contains(source, "://") && !strings.Contains(source, "@") {
+		source = "https://" + source
+	}
+
+	return repoKey(source), dir
+}
+
+// repoKey identifies a repo URL by its host and path e.g.
+// "github.com/foo/bar" for "https://github.com/foo/bar.git"
+func repoKey(repoURL string) string {
+	repoURL, err := util.NormalizeRepoUrl(repoURL)
+	if err != nil {
+		return ""
+	}
+
+	u, err := url.Parse(repoURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	return strings.ToLower(u.Hostname() + strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), ".git"))
+}
+
+// resolveModuleLocation resolves the source of a module block in the
+// config at configPath to a module of the blueprint at rootPath. The
+// location is relative to rootPath i.e. "." for the root module or
+// "modules/<name>" for a submodule. Sources that aren't in the blueprint
+// aren't resolved.
+func resolveModuleLocation(source, configPath, rootPath, repoURL, modulesPath string) (string, bool) {
+	var loc string
+	switch {
+	case isLocalModuleSource(source):
+		rel, err := filepath.Rel(rootPath, filepath.Join(configPath, source))
+		if err != nil {
+			return "", false
+		}
+
+		loc = filepath.ToSlash(rel)
+	case source != "":
+		repo, dir := parseModuleSource(source)
+		if repo == "" || repo != repoKey(repoURL) {
+			return "", false
+		}
+
+		loc = rootModuleLocation
+		if dir != "" {
+			loc = dir
+		}
+	default:
+		return "", false
+	}
+
+	if loc == rootModuleLocation {
+		return loc, true
+	}
+
+	// only direct submodules are sub-blueprints
+	if d, name := filepath.Split(loc); strings.TrimSuffix(d, "/") == filepath.ToSlash(filepath.Clean(modulesPath)) && name != "" {
+		return loc, true
+	}
+
+	return "", false
+}
+
+// getExampleModules returns the blueprint and sub-blueprints used by the
+// example at exPath along with the inputs the example sets for each of
+// them. Inputs set by several module blocks for the same blueprint are
+// merged.
+func getExampleModules(exPath, rootPath, repoURL, modulesPath string) ([]BlueprintExampleModule, error) {
+	calls, err := getModuleCalls(exPath)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := make(map[string]map[string]bool)
+	for _, c := range calls {
+		loc, ok := resolveModuleLocation(c.source, exPath, rootPath, repoURL, modulesPath)
+		if !ok {
+			continue
+		}
+
+		if inputs[loc] == nil {
+			inputs[loc] = make(map[string]bool)
+		}
+
+		for _, in := range c.inputs {
+			inputs[loc][in] = true
+		}
+	}
+
+	var modules []BlueprintExampleModule
+	for loc, set := range inputs {
+		m := BlueprintExampleModule{Location: loc}
+		for in := range set {
+			m.Inputs = append(m.Inputs, in)
+		}
+
+		sort.Strings(m.Inputs)
+		modules = append(modules, m)
+	}
+
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Location < modules[j].Location })
+	return modules, nil
+}
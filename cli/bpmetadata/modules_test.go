@@ -0,0 +1,118 @@
+package bpmetadata
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	exampleModulesPath = "content/examples-modules"
+	exampleModulesRepo = "https://github.com/terraform-google-modules/terraform-google-kubernetes-engine.git"
+)
+
+func TestParseModuleSource(t *testing.T) {
+	tests := []struct {
+		source   string
+		wantRepo string
+		wantDir  string
+	}{
+		{
+			source:   "terraform-google-modules/network/google",
+			wantRepo: "github.com/terraform-google-modules/terraform-google-network",
+		},
+		{
+			source:   "registry.terraform.io/terraform-google-modules/network/google//modules/subnets",
+			wantRepo: "github.com/terraform-google-modules/terraform-google-network",
+			wantDir:  "modules/subnets",
+		},
+		{
+			source:   "github.com/GoogleCloudPlatform/terraform-google-lb//modules/backend",
+			wantRepo: "github.com/googlecloudplatform/terraform-google-lb",
+			wantDir:  "modules/backend",
+		},
+		{
+			source:   "git::https://github.com/foo/bar.git//modules/baz/?ref=v1.0.0",
+			wantRepo: "github.com/foo/bar",
+			wantDir:  "modules/baz",
+		},
+		{
+			source:   "git::ssh://git@github.com/foo/bar.git?ref=main",
+			wantRepo: "github.com/foo/bar",
+		},
+		{
+			source:   "git@github.com:foo/bar.git",
+			wantRepo: "github.com/foo/bar",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			repo, dir := parseModuleSource(tt.source)
+			assert.Equal(t, tt.wantRepo, repo)
+			assert.Equal(t, tt.wantDir, dir)
+		})
+	}
+}
+
+func TestGetModuleCalls(t *testing.T) {
+	got, err := getModuleCalls(path.Join(bptestdataPath, exampleModulesPath, "examples", "private"))
+	require.NoError(t, err)
+	assert.Equal(t, []moduleCall{
+		{
+			name:    "gke",
+			source:  "terraform-google-modules/kubernetes-engine/google//modules/private-cluster",
+			version: "~> 27.0",
+			inputs:  []string{"network", "project_id"},
+		},
+		{
+			name:   "nested",
+			source: "../../modules/private-cluster/modules/nested",
+			inputs: []string{"name"},
+		},
+		{
+			name:    "network",
+			source:  "terraform-google-modules/network/google",
+			version: "~> 7.0",
+			inputs:  []string{"network_name", "project_id"},
+		},
+	}, got)
+}
+
+func TestGetExampleModules(t *testing.T) {
+	rootPath := path.Join(bptestdataPath, exampleModulesPath)
+	tests := []struct {
+		name    string
+		example string
+		want    []BlueprintExampleModule
+	}{
+		{
+			name:    "root module used twice",
+			example: "simple",
+			want: []BlueprintExampleModule{
+				{Location: ".", Inputs: []string{"name", "project", "region"}},
+			},
+		},
+		{
+			name:    "registry submodule and other modules",
+			example: "private",
+			want: []BlueprintExampleModule{
+				{Location: "modules/private-cluster", Inputs: []string{"network", "project_id"}},
+			},
+		},
+		{
+			name:    "no blueprint modules",
+			example: "other",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getExampleModules(path.Join(rootPath, "examples", tt.example), rootPath, exampleModulesRepo, modulesPath)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
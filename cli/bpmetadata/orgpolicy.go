@@ -0,0 +1,285 @@
+package bpmetadata
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// orgPolicyRule proposes an org policy check for a blueprint that creates
+// resources matching the rule. Rules in the metadata config are added to
+// the built-in ones and replace the built-in rules for the same policy.
+type orgPolicyRule struct {
+	// Policy is the org policy constraint e.g. "compute.vmExternalIpAccess".
+	Policy string `json:"policy" yaml:"policy"`
+
+	// ResourceTypes are the resource types the rule applies to. A trailing
+	// "*" matches any type with the prefix e.g. "google_compute_*".
+	ResourceTypes []string `json:"resourceTypes" yaml:"resourceTypes"`
+
+	// Blocks are nested blocks, one of which must be in a resource for the
+	// rule to match e.g. "network_interface.access_config".
+	Blocks []string `json:"blocks,omitempty" yaml:"blocks,omitempty"`
+
+	// Attributes are arguments, one of which must be set to a value other
+	// than false or null for the rule to match e.g. "can_ip_forward".
+	// Arguments in nested blocks are written as "<block>.<argument>".
+	Attributes []string `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+
+	// RequiredValues are the policy values the blueprint requires, if any.
+	RequiredValues []string `json:"requiredValues,omitempty" yaml:"requiredValues,omitempty"`
+}
+
+// defaultOrgPolicyRules are the built-in rules for proposing org policy
+// checks. Rules without blocks or attributes match any resource of the
+// listed types.
+var defaultOrgPolicyRules = []orgPolicyRule{
+	{
+		Policy:        "compute.vmExternalIpAccess",
+		ResourceTypes: []string{"google_compute_instance", "google_compute_instance_template", "google_compute_instance_from_template"},
+		Blocks:        []string{"network_interface.access_config", "network_interface.ipv6_access_config"},
+	},
+	{
+		Policy:        "compute.vmCanIpForward",
+		ResourceTypes: []string{"google_compute_instance", "google_compute_instance_template", "google_compute_instance_from_template"},
+		Attributes:    []string{"can_ip_forward"},
+	},
+	{
+		Policy:        "compute.restrictVpcPeering",
+		ResourceTypes: []string{"google_compute_network_peering", "google_service_networking_connection"},
+	},
+	{
+		Policy:        "compute.restrictLoadBalancerCreationForTypes",
+		ResourceTypes: []string{"google_compute_forwarding_rule", "google_compute_global_forwarding_rule"},
+	},
+	{
+		Policy:        "iam.disableServiceAccountCreation",
+		ResourceTypes: []string{"google_service_account"},
+	},
+	{
+		Policy:        "iam.disableServiceAccountKeyCreation",
+		ResourceTypes: []string{"google_service_account_key"},
+	},
+	{
+		Policy:        "sql.restrictPublicIp",
+		ResourceTypes: []string{"google_sql_database_instance"},
+		Attributes:    []string{"settings.ip_configuration.ipv4_enabled"},
+	},
+	{
+		// only the common resources that the constraint restricts
+		Policy: "gcp.resourceLocations",
+		ResourceTypes: []string{
+			"google_bigquery_dataset",
+			"google_cloud_run_service",
+			"google_cloud_run_v2_service",
+			"google_cloudfunctions_function",
+			"google_cloudfunctions2_function",
+			"google_compute_disk",
+			"google_compute_instance",
+			"google_compute_region_disk",
+			"google_container_cluster",
+			"google_kms_key_ring",
+			"google_pubsub_topic",
+			"google_spanner_instance",
+			"google_sql_database_instance",
+			"google_storage_bucket",
+		},
+	},
+}
+
+// withDefaultOrgPolicyRules returns the custom rules followed by the
+// built-in rules for policies that have no custom rule
+func withDefaultOrgPolicyRules(custom []orgPolicyRule) []orgPolicyRule {
+	overridden := make(map[string]bool)
+	for _, r := range custom {
+		overridden[r.Policy] = true
+	}
+
+	rules := append([]orgPolicyRule{}, custom...)
+	for _, r := range defaultOrgPolicyRules {
+		if !overridden[r.Policy] {
+			rules = append(rules, r)
+		}
+	}
+
+	return rules
+}
+
+// getOrgPolicyChecks proposes org policy checks for the resources created
+// by the module at configPath and the local modules it calls. The first
+// matching rule for a policy sets its required values and checks are
+// sorted by policy.
+func getOrgPolicyChecks(configPath string, rules []orgPolicyRule) ([]BlueprintOrgPolicyCheck, error) {
+	matched := make(map[string][]string)
+	err := collectOrgPolicies(configPath, rules, matched, make(map[string]bool))
+	if err != nil {
+		return nil, err
+	}
+
+	var checks []BlueprintOrgPolicyCheck
+	for policy, values := range matched {
+		checks = append(checks, BlueprintOrgPolicyCheck{
+			PolicyId:       policy,
+			RequiredValues: values,
+		})
+	}
+
+	sort.Slice(checks, func(i, j int) bool { return checks[i].PolicyId < checks[j].PolicyId })
+	return checks, nil
+}
+
+func collectOrgPolicies(configPath string, rules []orgPolicyRule, matched map[string][]string, visited map[string]bool) error {
+	configPath = filepath.Clean(configPath)
+	if visited[configPath] {
+		return nil
+	}
+
+	visited[configPath] = true
+	files, err := filepath.Glob(filepath.Join(configPath, "*.tf"))
+	if err != nil {
+		return err
+	}
+
+	p := hclparse.NewParser()
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+
+		file, diags := p.ParseHCL(b, filepath.Base(f))
+		err = hasHclErrors(diags)
+		if err != nil {
+			return err
+		}
+
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		for _, block := range body.Blocks {
+			switch {
+			case block.Type == "resource" && len(block.Labels) == 2:
+				for _, r := range rules {
+					if _, found := matched[r.Policy]; found || !r.matches(block.Labels[0], block.Body) {
+						continue
+					}
+
+					matched[r.Policy] = r.RequiredValues
+				}
+			case block.Type == "module" && len(block.Labels) == 1:
+				attr, hasSrc := block.Body.Attributes["source"]
+				if !hasSrc {
+					continue
+				}
+
+				src := literalString(attr.Expr)
+				if !isLocalModuleSource(src) {
+					continue
+				}
+
+				err := collectOrgPolicies(filepath.Join(configPath, src), rules, matched, visited)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// matches returns true if a resource of type resType with the given body
+// matches the rule
+func (r orgPolicyRule) matches(resType string, body *hclsyntax.Body) bool {
+	typeMatch := false
+	for _, t := range r.ResourceTypes {
+		if t == resType || (strings.HasSuffix(t, "*") && strings.HasPrefix(resType, strings.TrimSuffix(t, "*"))) {
+			typeMatch = true
+			break
+		}
+	}
+
+	if !typeMatch {
+		return false
+	}
+
+	if len(r.Blocks) == 0 && len(r.Attributes) == 0 {
+		return true
+	}
+
+	for _, b := range r.Blocks {
+		if hasNestedBlock(body, strings.Split(b, ".")) {
+			return true
+		}
+	}
+
+	for _, a := range r.Attributes {
+		if hasSetAttribute(body, strings.Split(a, ".")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasNestedBlock returns true if the body has a block at the path of
+// nested block types, including dynamic blocks
+func hasNestedBlock(body *hclsyntax.Body, path []string) bool {
+	for _, b := range body.Blocks {
+		content := b.Body
+		if b.Type == "dynamic" && len(b.Labels) == 1 && b.Labels[0] == path[0] {
+			// the content of a dynamic block is in its content block
+			content = nil
+			for _, c := range b.Body.Blocks {
+				if c.Type == "content" {
+					content = c.Body
+				}
+			}
+		} else if b.Type != path[0] {
+			continue
+		}
+
+		if content == nil {
+			continue
+		}
+
+		if len(path) == 1 || hasNestedBlock(content, path[1:]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasSetAttribute returns true if the argument at the path is set to
+// a value other than the literals false or null
+func hasSetAttribute(body *hclsyntax.Body, path []string) bool {
+	if len(path) > 1 {
+		for _, b := range body.Blocks {
+			if b.Type == path[0] && hasSetAttribute(b.Body, path[1:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	attr, found := body.Attributes[path[0]]
+	if !found {
+		return false
+	}
+
+	lit, isLiteral := attr.Expr.(*hclsyntax.LiteralValueExpr)
+	if !isLiteral {
+		return true
+	}
+
+	return !lit.Val.IsNull() && !lit.Val.RawEquals(cty.False)
+}
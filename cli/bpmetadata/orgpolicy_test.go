@@ -0,0 +1,118 @@
+package bpmetadata
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOrgPolicyChecks(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []orgPolicyRule
+		want  []BlueprintOrgPolicyCheck
+	}{
+		{
+			name:  "default rules",
+			rules: defaultOrgPolicyRules,
+			want: []BlueprintOrgPolicyCheck{
+				{PolicyId: "compute.vmExternalIpAccess"},
+				{PolicyId: "gcp.resourceLocations"},
+				{PolicyId: "iam.disableServiceAccountCreation"},
+				{PolicyId: "iam.disableServiceAccountKeyCreation"},
+			},
+		},
+		{
+			name: "custom rule",
+			rules: []orgPolicyRule{{
+				Policy:         "storage.uniformBucketLevelAccess",
+				ResourceTypes:  []string{"google_storage_bucket"},
+				Attributes:     []string{"uniform_bucket_level_access"},
+				RequiredValues: []string{"true"},
+			}},
+			want: []BlueprintOrgPolicyCheck{
+				{PolicyId: "storage.uniformBucketLevelAccess", RequiredValues: []string{"true"}},
+			},
+		},
+		{
+			name: "custom rule replaces built-in rule",
+			rules: withDefaultOrgPolicyRules([]orgPolicyRule{{
+				Policy:         "gcp.resourceLocations",
+				ResourceTypes:  []string{"google_storage_bucket"},
+				RequiredValues: []string{"in:us-locations"},
+			}}),
+			want: []BlueprintOrgPolicyCheck{
+				{PolicyId: "compute.vmExternalIpAccess"},
+				{PolicyId: "gcp.resourceLocations", RequiredValues: []string{"in:us-locations"}},
+				{PolicyId: "iam.disableServiceAccountCreation"},
+				{PolicyId: "iam.disableServiceAccountKeyCreation"},
+			},
+		},
+		{
+			name: "attribute in nested block",
+			rules: []orgPolicyRule{{
+				Policy:        "sql.restrictAuthorizedNetworks",
+				ResourceTypes: []string{"google_sql_database_instance"},
+				Attributes:    []string{"settings.ip_configuration.private_network"},
+			}},
+			want: []BlueprintOrgPolicyCheck{
+				{PolicyId: "sql.restrictAuthorizedNetworks"},
+			},
+		},
+		{
+			name: "attribute set to false",
+			rules: []orgPolicyRule{{
+				Policy:        "compute.vmCanIpForward",
+				ResourceTypes: []string{"google_compute_instance"},
+				Attributes:    []string{"can_ip_forward"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getOrgPolicyChecks(path.Join(tfTestdataPath, "org-policies"), tt.rules)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestOrgPolicyChecksAreOwnedPerItem(t *testing.T) {
+	stale := BlueprintOrgPolicyCheck{PolicyId: "iam.disableServiceAccountKeyCreation"}
+	staleSum, err := checksum(stale)
+	require.NoError(t, err)
+
+	onDisk := &BlueprintMetadata{}
+	onDisk.Spec.Info.OrgPolicyChecks = []BlueprintOrgPolicyCheck{
+		{PolicyId: "gcp.resourceLocations", RequiredValues: []string{"in:us-locations"}},
+		{PolicyId: "storage.uniformBucketLevelAccess"},
+		stale,
+	}
+
+	generated := &BlueprintMetadata{}
+	generated.Spec.Info.OrgPolicyChecks = []BlueprintOrgPolicyCheck{
+		{PolicyId: "compute.vmExternalIpAccess"},
+		{PolicyId: "gcp.resourceLocations"},
+	}
+
+	o := &metadataOwnership{Fields: map[string]*fieldOwnership{
+		"spec.info.orgPolicyChecks[gcp.resourceLocations]":                {Owner: ownerManual},
+		"spec.info.orgPolicyChecks[storage.uniformBucketLevelAccess]":     {Owner: ownerManual},
+		"spec.info.orgPolicyChecks[iam.disableServiceAccountKeyCreation]": {Owner: ownerAuto, Checksum: staleSum},
+	}}
+	_, err = mergeOwnedFields(onDisk, generated, o)
+	require.NoError(t, err)
+
+	// manually owned checks are kept and proposed checks that are no
+	// longer proposed are dropped
+	assert.Equal(t, []BlueprintOrgPolicyCheck{
+		{PolicyId: "compute.vmExternalIpAccess"},
+		{PolicyId: "gcp.resourceLocations", RequiredValues: []string{"in:us-locations"}},
+		{PolicyId: "storage.uniformBucketLevelAccess"},
+	}, generated.Spec.Info.OrgPolicyChecks)
+	assert.Equal(t, ownerAuto, o.Fields["spec.info.orgPolicyChecks[compute.vmExternalIpAccess]"].Owner)
+	assert.NotContains(t, o.Fields, "spec.info.orgPolicyChecks[iam.disableServiceAccountKeyCreation]")
+}
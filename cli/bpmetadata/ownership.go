@@ -0,0 +1,362 @@
+package bpmetadata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+type fieldOwner string
+
+const (
+	// ownerAuto marks a field that is refreshed from the README or
+	// Terraform configs on every run.
+	ownerAuto fieldOwner = "auto"
+
+	// ownerManual marks a field that has been hand-authored and is
+	// preserved across runs.
+	ownerManual fieldOwner = "manual"
+)
+
+// fieldOwnership records who owns a metadata field along with the
+// checksum of the last value autogenerated for it.
+type fieldOwnership struct {
+	Owner    fieldOwner `json:"owner" yaml:"owner"`
+	Checksum string     `json:"checksum,omitempty" yaml:"checksum,omitempty"`
+}
+
+// metadataOwnership is the sidecar that is written next to metadata.yaml
+// and tracks ownership for all autogenerated fields, keyed by field path.
+type metadataOwnership struct {
+	Fields map[string]*fieldOwnership `json:"fields" yaml:"fields"`
+}
+
+// ownedField maps a field path in the metadata to an accessor that
+// returns a pointer to the field's value.
+type ownedField struct {
+	path string
+	ptr  func(m *BlueprintMetadata) interface{}
+}
+
+// ownedFields are the fields of core metadata that are autogenerated
+// and can be taken over by authors.
+var ownedFields = []ownedField{
+	{"spec.info.title", func(m *BlueprintMetadata) interface{} { return &m.Spec.Info.Title }},
+	{"spec.info.source", func(m *BlueprintMetadata) interface{} { return &m.Spec.Info.Source }},
+	{"spec.info.version", func(m *BlueprintMetadata) interface{} { return &m.Spec.Info.Version }},
+	{"spec.info.actuationTool", func(m *BlueprintMetadata) interface{} { return &m.Spec.Info.ActuationTool }},
+	{"spec.info.description.tagline", func(m *BlueprintMetadata) interface{} { return &description(m).Tagline }},
+	{"spec.info.description.detailed", func(m *BlueprintMetadata) interface{} { return &description(m).Detailed }},
+	{"spec.info.description.preDeploy", func(m *BlueprintMetadata) interface{} { return &description(m).PreDeploy }},
+	{"spec.info.description.architecture", func(m *BlueprintMetadata) interface{} { return &description(m).Architecture }},
+	{"spec.info.icon", func(m *BlueprintMetadata) interface{} { return &m.Spec.Info.Icon }},
+	{"spec.info.deploymentDuration", func(m *BlueprintMetadata) interface{} { return &m.Spec.Info.DeploymentDuration }},
+	{"spec.info.quotaDetails", func(m *BlueprintMetadata) interface{} { return &m.Spec.Info.QuotaDetails }},
+	{"spec.info.costEstimate", func(m *BlueprintMetadata) interface{} { return &m.Spec.Info.CostEstimate }},
+	{"spec.content.architecture", func(m *BlueprintMetadata) interface{} { return &m.Spec.Content.Architecture }},
+	{"spec.content.documentation", func(m *BlueprintMetadata) interface{} { return &m.Spec.Content.Documentation }},
+	{"spec.content.subBlueprints", func(m *BlueprintMetadata) interface{} { return &m.Spec.Content.SubBlueprints }},
+	{"spec.content.examples", func(m *BlueprintMetadata) interface{} { return &m.Spec.Content.Examples }},
+	{"spec.requirements.services", func(m *BlueprintMetadata) interface{} { return &m.Spec.Requirements.Services }},
+	{"spec.requirements.dependencies", func(m *BlueprintMetadata) interface{} { return &m.Spec.Requirements.Dependencies }},
+	{"spec.requirements.providers", func(m *BlueprintMetadata) interface{} { return &m.Spec.Requirements.Providers }},
+}
+
+// ownedList is a list in the metadata whose items are owned separately,
+// keyed by the value returned by key for an item.
+type ownedList struct {
+	path string
+	ptr  func(m *BlueprintMetadata) interface{}
+	key  func(item interface{}) string
+}
+
+// ownedLists are the lists of core metadata that are autogenerated and
+// whose items can be taken over by authors one at a time.
+var ownedLists = []ownedList{
+	{
+		"spec.info.orgPolicyChecks",
+		func(m *BlueprintMetadata) interface{} { return &m.Spec.Info.OrgPolicyChecks },
+		func(item interface{}) string { return item.(BlueprintOrgPolicyCheck).PolicyId },
+	},
+	{
+		"spec.interfaces.variables",
+		func(m *BlueprintMetadata) interface{} { return &m.Spec.Interfaces.Variables },
+		func(item interface{}) string { return item.(BlueprintVariable).Name },
+	},
+	{
+		"spec.interfaces.outputs",
+		func(m *BlueprintMetadata) interface{} { return &m.Spec.Interfaces.Outputs },
+		func(item interface{}) string { return item.(BlueprintOutput).Name },
+	},
+	{
+		"spec.requirements.roles",
+		func(m *BlueprintMetadata) interface{} { return &m.Spec.Requirements.Roles },
+		func(item interface{}) string { return item.(BlueprintRoles).Level },
+	},
+	{
+		"spec.content.diagrams",
+		func(m *BlueprintMetadata) interface{} { return &m.Spec.Content.Diagrams },
+		func(item interface{}) string { return item.(BlueprintDiagram).Name },
+	},
+}
+
+// description returns the description for the metadata, initializing
+// it if it hasn't been set yet.
+func description(m *BlueprintMetadata) *BlueprintDescription {
+	if m.Spec.Info.Description == nil {
+		m.Spec.Info.Description = &BlueprintDescription{}
+	}
+
+	return m.Spec.Info.Description
+}
+
+// mergeOwnedFields merges the metadata on disk into freshly generated
+// metadata based on field ownership. Manually owned fields keep the value
+// on disk while autogenerated fields take the generated value. A field that
+// was autogenerated but has since been edited on disk, or that differs from
+// the generated value without ownership, becomes manually owned. Items of
+// owned lists are owned separately. Conflicts between manually owned values
+// and newly generated values are returned so they can be reported.
+func mergeOwnedFields(onDisk, generated *BlueprintMetadata, o *metadataOwnership) ([]string, error) {
+	if o.Fields == nil {
+		o.Fields = make(map[string]*fieldOwnership)
+	}
+
+	var conflicts []string
+	for _, f := range ownedFields {
+		diskVal := reflect.ValueOf(f.ptr(onDisk)).Elem()
+		genVal := reflect.ValueOf(f.ptr(generated)).Elem()
+		keepDisk, conflict, err := mergeOwnedValue(f.path, diskVal, genVal, o)
+		if err != nil {
+			return nil, err
+		}
+
+		if conflict != "" {
+			conflicts = append(conflicts, conflict)
+		}
+
+		if keepDisk {
+			genVal.Set(diskVal)
+		}
+	}
+
+	for _, l := range ownedLists {
+		c, err := mergeOwnedList(l, onDisk, generated, o)
+		if err != nil {
+			return nil, err
+		}
+
+		conflicts = append(conflicts, c...)
+	}
+
+	return conflicts, nil
+}
+
+// mergeOwnedList merges the items of a list on disk into the generated
+// list by key. Items only on disk are kept if they are manually owned or
+// have no ownership and dropped otherwise.
+func mergeOwnedList(l ownedList, onDisk, generated *BlueprintMetadata, o *metadataOwnership) ([]string, error) {
+	diskList := reflect.ValueOf(l.ptr(onDisk)).Elem()
+	genList := reflect.ValueOf(l.ptr(generated)).Elem()
+
+	diskItems := make(map[string]reflect.Value)
+	for i := 0; i < diskList.Len(); i++ {
+		diskItems[l.key(diskList.Index(i).Interface())] = diskList.Index(i)
+	}
+
+	var conflicts []string
+	merged := reflect.MakeSlice(genList.Type(), 0, genList.Len())
+	seen := make(map[string]bool)
+	for i := 0; i < genList.Len(); i++ {
+		genItem := genList.Index(i)
+		k := l.key(genItem.Interface())
+		seen[k] = true
+
+		diskItem, found := diskItems[k]
+		if !found {
+			diskItem = reflect.Zero(genItem.Type())
+		}
+
+		keepDisk, conflict, err := mergeOwnedValue(itemPath(l.path, k), diskItem, genItem, o)
+		if err != nil {
+			return nil, err
+		}
+
+		if conflict != "" {
+			conflicts = append(conflicts, conflict)
+		}
+
+		// a manually owned item that was removed from disk stays removed
+		switch {
+		case !keepDisk:
+			merged = reflect.Append(merged, genItem)
+		case found:
+			merged = reflect.Append(merged, diskItem)
+		}
+	}
+
+	for i := 0; i < diskList.Len(); i++ {
+		diskItem := diskList.Index(i)
+		k := l.key(diskItem.Interface())
+		if seen[k] {
+			continue
+		}
+
+		// items without ownership have been added by hand
+		p := itemPath(l.path, k)
+		rec, tracked := o.Fields[p]
+		if !tracked {
+			rec = &fieldOwnership{Owner: ownerManual}
+			o.Fields[p] = rec
+		}
+
+		if rec.Owner != ownerManual {
+			delete(o.Fields, p)
+			continue
+		}
+
+		merged = reflect.Append(merged, diskItem)
+	}
+
+	if merged.Len() == 0 {
+		merged = reflect.Zero(genList.Type())
+	}
+
+	genList.Set(merged)
+	return conflicts, nil
+}
+
+// itemPath returns the ownership path for the item of a list with key k
+func itemPath(listPath, k string) string {
+	return fmt.Sprintf("%s[%s]", listPath, k)
+}
+
+// mergeOwnedValue updates the ownership of the value at path and returns
+// whether the value on disk must be kept over the generated one along with
+// a conflict, if any.
+func mergeOwnedValue(path string, diskVal, genVal reflect.Value, o *metadataOwnership) (bool, string, error) {
+	diskSum, err := checksum(diskVal.Interface())
+	if err != nil {
+		return false, "", fmt.Errorf("error computing checksum for %s: %w", path, err)
+	}
+
+	genSum, err := checksum(genVal.Interface())
+	if err != nil {
+		return false, "", fmt.Errorf("error computing checksum for %s: %w", path, err)
+	}
+
+	// a field without ownership, e.g. on the first run, that differs from
+	// the generated value has been authored by hand and is manually owned.
+	// The generated checksum is recorded so that no conflict is reported
+	// until the source changes.
+	rec, tracked := o.Fields[path]
+	if !tracked {
+		rec = &fieldOwnership{Owner: ownerAuto}
+		if !diskVal.IsZero() && diskSum != genSum {
+			Log.Info("field differs from the generated value and will no longer be autogenerated", "field", path)
+			rec = &fieldOwnership{Owner: ownerManual, Checksum: genSum}
+		}
+
+		o.Fields[path] = rec
+	}
+
+	// an autogenerated field that no longer matches the last generated
+	// value has been edited by hand and is now manually owned
+	if rec.Owner != ownerManual && rec.Checksum != "" && !diskVal.IsZero() && diskSum != rec.Checksum {
+		Log.Info("field was edited manually and will no longer be autogenerated", "field", path)
+		rec.Owner = ownerManual
+	}
+
+	if rec.Owner != ownerManual {
+		rec.Owner = ownerAuto
+		rec.Checksum = genSum
+		return false, "", nil
+	}
+
+	// report a conflict only when the source has changed since the last
+	// run and disagrees with the manually authored value
+	conflict := ""
+	if genSum != rec.Checksum && genSum != diskSum {
+		conflict = fmt.Sprintf("%s is manually owned but the README/Terraform configs now generate a different value", path)
+	}
+
+	rec.Checksum = genSum
+	return true, conflict, nil
+}
+
+// mergeDisplayValue merges a generated value into a field of display
+// metadata in place. Values that are empty and untracked are skipped so
+// that the sidecar only tracks display fields that are inferred.
+func mergeDisplayValue(path string, diskVal, genVal reflect.Value, o *metadataOwnership) {
+	if _, tracked := o.Fields[path]; !tracked && diskVal.IsZero() && genVal.IsZero() {
+		return
+	}
+
+	keepDisk, conflict, err := mergeOwnedValue(path, diskVal, genVal, o)
+	if err != nil {
+		Log.Warn("unable to merge display metadata", "field", path, "err", err)
+		return
+	}
+
+	if conflict != "" {
+		Log.Warn("metadata conflict", "details", conflict)
+	}
+
+	if !keepDisk {
+		diskVal.Set(genVal)
+	}
+}
+
+// checksum returns a stable fingerprint for a field value
+func checksum(v interface{}) (string, error) {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:8]), nil
+}
+
+// readOwnership reads the ownership sidecar for a blueprint. An empty
+// ownership record is returned if the sidecar doesn't exist.
+func readOwnership(bpPath string) (*metadataOwnership, error) {
+	o := metadataOwnership{}
+	b, err := os.ReadFile(path.Join(bpPath, metadataOwnershipFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return &o, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read metadata ownership: %w", err)
+	}
+
+	if err := yaml.Unmarshal(b, &o); err != nil {
+		return nil, fmt.Errorf("unable to parse metadata ownership: %w", err)
+	}
+
+	for p, f := range o.Fields {
+		if f == nil || (f.Owner != ownerAuto && f.Owner != ownerManual) {
+			return nil, fmt.Errorf("invalid owner for field %s in %s, must be one of: %s, %s", p, metadataOwnershipFileName, ownerAuto, ownerManual)
+		}
+	}
+
+	return &o, nil
+}
+
+// writeOwnership writes the ownership sidecar for a blueprint and reports
+// whether it changed
+func writeOwnership(o *metadataOwnership, bpPath string) (bool, error) {
+	b, err := yaml.Marshal(o)
+	if err != nil {
+		return false, err
+	}
+
+	return writeFileIfChanged(path.Join(bpPath, metadataOwnershipFileName), b)
+}
@@ -0,0 +1,295 @@
+package bpmetadata
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeOwnedFields(t *testing.T) {
+	titleSum := func(title string) string {
+		s, err := checksum(title)
+		require.NoError(t, err)
+		return s
+	}
+
+	tests := []struct {
+		name          string
+		diskTitle     string
+		genTitle      string
+		ownership     *metadataOwnership
+		wantTitle     string
+		wantOwner     fieldOwner
+		wantConflicts int
+	}{
+		{
+			name:      "untracked field without a value is autogenerated",
+			genTitle:  "New Title",
+			ownership: &metadataOwnership{},
+			wantTitle: "New Title",
+			wantOwner: ownerAuto,
+		},
+		{
+			name:      "untracked field matching the generated value is autogenerated",
+			diskTitle: "New Title",
+			genTitle:  "New Title",
+			ownership: &metadataOwnership{},
+			wantTitle: "New Title",
+			wantOwner: ownerAuto,
+		},
+		{
+			name:      "untracked field that differs from the generated value becomes manual",
+			diskTitle: "Edited Title",
+			genTitle:  "New Title",
+			ownership: &metadataOwnership{},
+			wantTitle: "Edited Title",
+			wantOwner: ownerManual,
+		},
+		{
+			name:      "unedited auto field is regenerated",
+			diskTitle: "Old Title",
+			genTitle:  "New Title",
+			ownership: &metadataOwnership{
+				Fields: map[string]*fieldOwnership{
+					"spec.info.title": {Owner: ownerAuto, Checksum: titleSum("Old Title")},
+				},
+			},
+			wantTitle: "New Title",
+			wantOwner: ownerAuto,
+		},
+		{
+			name:      "edited auto field becomes manual",
+			diskTitle: "Edited Title",
+			genTitle:  "Old Title",
+			ownership: &metadataOwnership{
+				Fields: map[string]*fieldOwnership{
+					"spec.info.title": {Owner: ownerAuto, Checksum: titleSum("Old Title")},
+				},
+			},
+			wantTitle: "Edited Title",
+			wantOwner: ownerManual,
+		},
+		{
+			name:      "edited auto field with changed source is a conflict",
+			diskTitle: "Edited Title",
+			genTitle:  "New Title",
+			ownership: &metadataOwnership{
+				Fields: map[string]*fieldOwnership{
+					"spec.info.title": {Owner: ownerAuto, Checksum: titleSum("Old Title")},
+				},
+			},
+			wantTitle:     "Edited Title",
+			wantOwner:     ownerManual,
+			wantConflicts: 1,
+		},
+		{
+			name:      "manual field with unchanged source",
+			diskTitle: "Edited Title",
+			genTitle:  "Old Title",
+			ownership: &metadataOwnership{
+				Fields: map[string]*fieldOwnership{
+					"spec.info.title": {Owner: ownerManual, Checksum: titleSum("Old Title")},
+				},
+			},
+			wantTitle: "Edited Title",
+			wantOwner: ownerManual,
+		},
+		{
+			name:      "manual field that agrees with changed source",
+			diskTitle: "New Title",
+			genTitle:  "New Title",
+			ownership: &metadataOwnership{
+				Fields: map[string]*fieldOwnership{
+					"spec.info.title": {Owner: ownerManual, Checksum: titleSum("Old Title")},
+				},
+			},
+			wantTitle: "New Title",
+			wantOwner: ownerManual,
+		},
+		{
+			name:      "manual field without a checksum",
+			diskTitle: "Edited Title",
+			genTitle:  "New Title",
+			ownership: &metadataOwnership{
+				Fields: map[string]*fieldOwnership{
+					"spec.info.title": {Owner: ownerManual},
+				},
+			},
+			wantTitle:     "Edited Title",
+			wantOwner:     ownerManual,
+			wantConflicts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			onDisk := &BlueprintMetadata{Spec: BlueprintMetadataSpec{Info: BlueprintInfo{Title: tt.diskTitle}}}
+			generated := &BlueprintMetadata{Spec: BlueprintMetadataSpec{Info: BlueprintInfo{Title: tt.genTitle}}}
+			conflicts, err := mergeOwnedFields(onDisk, generated, tt.ownership)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTitle, generated.Spec.Info.Title)
+			assert.Equal(t, tt.wantOwner, tt.ownership.Fields["spec.info.title"].Owner)
+			assert.Equal(t, titleSum(tt.genTitle), tt.ownership.Fields["spec.info.title"].Checksum)
+			assert.Len(t, conflicts, tt.wantConflicts)
+		})
+	}
+}
+
+func TestMergeOwnedLists(t *testing.T) {
+	itemSum := func(v interface{}) string {
+		s, err := checksum(v)
+		require.NoError(t, err)
+		return s
+	}
+
+	zone := BlueprintVariable{Name: "zone", Description: "The zone"}
+	region := BlueprintVariable{Name: "region", Description: "The region"}
+	editedRegion := BlueprintVariable{Name: "region", Description: "The region to deploy to"}
+	name := BlueprintVariable{Name: "name", Description: "The name"}
+	extra := BlueprintVariable{Name: "extra", Description: "Authored by hand"}
+
+	onDisk := &BlueprintMetadata{}
+	onDisk.Spec.Interfaces.Variables = []BlueprintVariable{editedRegion, zone, extra, {Name: "removed"}}
+
+	generated := &BlueprintMetadata{}
+	generated.Spec.Interfaces.Variables = []BlueprintVariable{region, zone, name}
+
+	o := &metadataOwnership{
+		Fields: map[string]*fieldOwnership{
+			"spec.interfaces.variables[region]":  {Owner: ownerAuto, Checksum: itemSum(region)},
+			"spec.interfaces.variables[zone]":    {Owner: ownerAuto, Checksum: itemSum(zone)},
+			"spec.interfaces.variables[extra]":   {Owner: ownerManual},
+			"spec.interfaces.variables[removed]": {Owner: ownerAuto, Checksum: itemSum(BlueprintVariable{Name: "removed"})},
+		},
+	}
+
+	conflicts, err := mergeOwnedFields(onDisk, generated, o)
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+
+	// the edited item is kept, new items are generated, manually owned
+	// items are kept and items that are no longer generated are dropped
+	assert.Equal(t, []BlueprintVariable{editedRegion, zone, name, extra}, generated.Spec.Interfaces.Variables)
+	assert.Equal(t, ownerManual, o.Fields["spec.interfaces.variables[region]"].Owner)
+	assert.Equal(t, ownerAuto, o.Fields["spec.interfaces.variables[zone]"].Owner)
+	assert.Equal(t, ownerAuto, o.Fields["spec.interfaces.variables[name]"].Owner)
+	assert.Equal(t, ownerManual, o.Fields["spec.interfaces.variables[extra]"].Owner)
+	assert.NotContains(t, o.Fields, "spec.interfaces.variables[removed]")
+}
+
+func TestMergeOwnedListsWithoutOwnership(t *testing.T) {
+	onDisk := &BlueprintMetadata{}
+	onDisk.Spec.Interfaces.Variables = []BlueprintVariable{
+		{Name: "zone", Description: "Edited description"},
+		{Name: "extra", Description: "Authored by hand"},
+		{Name: "region", Description: "The region"},
+	}
+
+	generated := &BlueprintMetadata{}
+	generated.Spec.Interfaces.Variables = []BlueprintVariable{
+		{Name: "region", Description: "The region"},
+		{Name: "zone", Description: "The zone"},
+	}
+
+	// without a sidecar, edited and hand-added items are manually owned
+	o := &metadataOwnership{}
+	conflicts, err := mergeOwnedFields(onDisk, generated, o)
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+	assert.Equal(t, []BlueprintVariable{
+		{Name: "region", Description: "The region"},
+		{Name: "zone", Description: "Edited description"},
+		{Name: "extra", Description: "Authored by hand"},
+	}, generated.Spec.Interfaces.Variables)
+	assert.Equal(t, ownerAuto, o.Fields["spec.interfaces.variables[region]"].Owner)
+	assert.Equal(t, ownerManual, o.Fields["spec.interfaces.variables[zone]"].Owner)
+	assert.Equal(t, ownerManual, o.Fields["spec.interfaces.variables[extra]"].Owner)
+}
+
+func TestGenerateFirstRunKeepsEditedMetadata(t *testing.T) {
+	bpPath := tempBlueprint(t)
+	_, err := generateMetadataForBpPath(bpPath)
+	require.NoError(t, err)
+
+	// start over from hand-edited metadata without a sidecar
+	bpObj, err := UnmarshalMetadata(bpPath, metadataFileName)
+	require.NoError(t, err)
+	bpObj.Spec.Info.Title = "Simple Bucket"
+	bpObj.Spec.Requirements.Roles = []BlueprintRoles{{Level: "Project", Roles: []string{"roles/storage.admin"}}}
+	for i, v := range bpObj.Spec.Interfaces.Variables {
+		if v.Name == "name" {
+			bpObj.Spec.Interfaces.Variables[i].Description = "Name of the bucket, must be globally unique."
+		}
+	}
+
+	bpObj.Spec.Interfaces.Outputs = append(bpObj.Spec.Interfaces.Outputs, BlueprintOutput{Name: "bucket_name", Description: "Added by hand."})
+	require.NoError(t, WriteMetadata(bpObj, bpPath, metadataFileName))
+	require.NoError(t, os.Remove(path.Join(bpPath, metadataOwnershipFileName)))
+
+	changed, err := generateMetadataForBpPath(bpPath)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	got, err := UnmarshalMetadata(bpPath, metadataFileName)
+	require.NoError(t, err)
+	assert.Equal(t, bpObj.Spec.Info.Title, got.Spec.Info.Title)
+	assert.Equal(t, bpObj.Spec.Requirements.Roles, got.Spec.Requirements.Roles)
+	assert.Equal(t, bpObj.Spec.Interfaces.Variables, got.Spec.Interfaces.Variables)
+	assert.Equal(t, bpObj.Spec.Interfaces.Outputs, got.Spec.Interfaces.Outputs)
+
+	o, err := readOwnership(bpPath)
+	require.NoError(t, err)
+	for _, p := range []string{
+		"spec.info.title",
+		"spec.requirements.roles[Project]",
+		"spec.interfaces.variables[name]",
+		"spec.interfaces.outputs[bucket_name]",
+	} {
+		assert.Equal(t, ownerManual, o.Fields[p].Owner, p)
+	}
+
+	assert.Equal(t, ownerAuto, o.Fields["spec.interfaces.variables[location]"].Owner)
+}
+
+// tempBlueprint copies the blueprint fixture to a git repo with an origin
+// remote and returns its path
+func tempBlueprint(t *testing.T) string {
+	t.Helper()
+	dir := tempGitRepo(t, "")
+	r, err := git.PlainOpen(dir)
+	require.NoError(t, err)
+
+	_, err = r.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{"https://github.com/foo/terraform-google-simple-bucket"}})
+	require.NoError(t, err)
+
+	src := path.Join("..", "testdata", "bpmetadata", "blueprint")
+	err = filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return os.MkdirAll(path.Join(dir, rel), 0755)
+		}
+
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		return os.WriteFile(path.Join(dir, rel), b, 0644)
+	})
+	require.NoError(t, err)
+
+	return dir
+}
@@ -4,22 +4,13 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
 )
 
-const (
-	regexExamples = ".*/(examples/.*)"
-	regexModules  = ".*/(modules/.*)"
-)
-
-var (
-	reExamples = regexp.MustCompile(regexExamples)
-	reModules  = regexp.MustCompile(regexModules)
-)
-
 func fileExists(path string) (bool, error) {
 	info, err := os.Stat(path)
 	if err != nil {
@@ -33,12 +24,22 @@ func fileExists(path string) (bool, error) {
 	return true, nil
 }
 
-func getExamples(configPath string) ([]BlueprintMiscContent, error) {
-	return getDirPaths(configPath, reExamples)
+// getExamples returns the examples under configPath, which is the
+// examplesDir of the blueprint
+func getExamples(configPath, examplesDir string) ([]BlueprintMiscContent, error) {
+	return getDirPaths(configPath, dirPathRegexp(examplesDir))
+}
+
+// getModules returns the submodules under configPath, which is the
+// modulesDir of the blueprint
+func getModules(configPath, modulesDir string) ([]BlueprintMiscContent, error) {
+	return getDirPaths(configPath, dirPathRegexp(modulesDir))
 }
 
-func getModules(configPath string) ([]BlueprintMiscContent, error) {
-	return getDirPaths(configPath, reModules)
+// dirPathRegexp matches paths within dir e.g. "examples" with the path
+// starting from dir as the first group
+func dirPathRegexp(dir string) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(".*/(%s/.*)", regexp.QuoteMeta(path.Clean(dir))))
 }
 
 // getDirPaths traverses a given path and looks for directories
@@ -63,7 +63,7 @@ func TestDirContent(t *testing.T) {
 		{
 			name:  "valid examples",
 			path:  "content/examples",
-			regex: regexExamples,
+			regex: dirPathRegexp("examples").String(),
 			want: []BlueprintMiscContent{
 				BlueprintMiscContent{
 					Name:     "terraform",
@@ -87,7 +87,7 @@ func TestDirContent(t *testing.T) {
 		{
 			name:  "valid modules",
 			path:  "content/modules",
-			regex: regexModules,
+			regex: dirPathRegexp("modules").String(),
 			want: []BlueprintMiscContent{
 				BlueprintMiscContent{
 					Name:     "beta-public-cluster",
@@ -107,13 +107,13 @@ func TestDirContent(t *testing.T) {
 		{
 			name:    "invalid dir",
 			path:    "content/modules2",
-			regex:   regexModules,
+			regex:   dirPathRegexp("modules").String(),
 			wantErr: true,
 		},
 		{
 			name:  "some example folders without any tf",
 			path:  "content/examples-some-without-tf/examples",
-			regex: regexExamples,
+			regex: dirPathRegexp("examples").String(),
 			want: []BlueprintMiscContent{
 				BlueprintMiscContent{
 					Name:     "terraform",
@@ -129,7 +129,26 @@ func TestDirContent(t *testing.T) {
 		{
 			name:    "all module folders without any tf",
 			path:    "content/modules-no-tf/modules",
-			regex:   regexModules,
+			regex:   dirPathRegexp("modules").String(),
+			want:    []BlueprintMiscContent{},
+			wantErr: false,
+		},
+		{
+			name:  "non-default examples dir",
+			path:  "content/samples",
+			regex: dirPathRegexp("samples").String(),
+			want: []BlueprintMiscContent{
+				BlueprintMiscContent{
+					Name:     "simple_regional",
+					Location: "samples/simple_regional",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "default regex in non-default dir",
+			path:    "content/samples",
+			regex:   dirPathRegexp("examples").String(),
 			want:    []BlueprintMiscContent{},
 			wantErr: false,
 		},
@@ -0,0 +1,279 @@
+package bpmetadata
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// protoDescriptorSet holds the descriptors of the metadata proto
+// definitions along with their imports, generated with "make protoc".
+// Messages are built from them at runtime since the Go types of the
+// metadata are maintained by hand.
+//
+//go:embed proto/out/bpmetadata.binpb
+var protoDescriptorSet []byte
+
+const protoMetadataMessage = "google.cloud.config.bpmetadata.BlueprintMetadata"
+
+// output formats metadata can be written in alongside YAML
+const (
+	outputFormatJSON      = "json"
+	outputFormatTextproto = "textproto"
+	outputFormatProto     = "proto"
+)
+
+var outputFormatExtensions = map[string]string{
+	outputFormatJSON:      ".json",
+	outputFormatTextproto: ".textproto",
+	outputFormatProto:     ".pb",
+}
+
+var (
+	protoFilesOnce sync.Once
+	protoFiles     *protoregistry.Files
+	protoFilesErr  error
+)
+
+// getProtoFiles returns the registry of the metadata proto definitions
+func getProtoFiles() (*protoregistry.Files, error) {
+	protoFilesOnce.Do(func() {
+		protoFiles, protoFilesErr = loadProtoFiles()
+	})
+
+	return protoFiles, protoFilesErr
+}
+
+// getMetadataDescriptor returns the descriptor of the BlueprintMetadata
+// proto message
+func getMetadataDescriptor() (protoreflect.MessageDescriptor, error) {
+	files, err := getProtoFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := files.FindDescriptorByName(protoMetadataMessage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find %s: %w", protoMetadataMessage, err)
+	}
+
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a message", protoMetadataMessage)
+	}
+
+	return md, nil
+}
+
+// metadataToProto converts metadata to the BlueprintMetadata proto message.
+// Fields are mapped through their JSON names which are shared by the Go
+// types and the proto definitions.
+func metadataToProto(obj *BlueprintMetadata) (*dynamicpb.Message, error) {
+	md, err := getMetadataDescriptor()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	// unset enums are empty strings in Go and the zero value in proto
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+
+	dropEmptyEnums(v, md)
+	if b, err = json.Marshal(v); err != nil {
+		return nil, err
+	}
+
+	m := dynamicpb.NewMessage(md)
+	if err := protojson.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("error converting metadata to proto: %w", err)
+	}
+
+	return m, nil
+}
+
+// metadataFromProto converts a BlueprintMetadata proto message to metadata
+func metadataFromProto(m proto.Message) (*BlueprintMetadata, error) {
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("error converting proto to metadata: %w", err)
+	}
+
+	obj := &BlueprintMetadata{}
+	if err := json.Unmarshal(b, obj); err != nil {
+		return nil, fmt.Errorf("error converting proto to metadata: %w", err)
+	}
+
+	return obj, nil
+}
+
+// dropEmptyEnums removes empty strings set for enum fields of a message
+// from its JSON representation
+func dropEmptyEnums(v interface{}, md protoreflect.MessageDescriptor) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	for name, fv := range obj {
+		fd := md.Fields().ByJSONName(name)
+		if fd == nil {
+			continue
+		}
+
+		if fd.IsMap() {
+			fd = fd.MapValue()
+			if m, ok := fv.(map[string]interface{}); ok {
+				for _, mv := range m {
+					if fd.Kind() == protoreflect.MessageKind {
+						dropEmptyEnums(mv, fd.Message())
+					}
+				}
+			}
+
+			continue
+		}
+
+		switch fd.Kind() {
+		case protoreflect.EnumKind:
+			if fv == "" {
+				delete(obj, name)
+			}
+		case protoreflect.MessageKind:
+			if l, ok := fv.([]interface{}); ok {
+				for _, item := range l {
+					dropEmptyEnums(item, fd.Message())
+				}
+
+				continue
+			}
+
+			dropEmptyEnums(fv, fd.Message())
+		}
+	}
+}
+
+// marshalMetadata serializes metadata in one of the proto output formats
+func marshalMetadata(obj *BlueprintMetadata, format string) ([]byte, error) {
+	m, err := metadataToProto(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case outputFormatProto:
+		return proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	case outputFormatTextproto:
+		return prototext.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(m)
+	case outputFormatJSON:
+		b, err := protojson.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+
+		// protojson output isn't stable so it is reformatted
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, b, "", "  "); err != nil {
+			return nil, err
+		}
+
+		buf.WriteString("\n")
+		return buf.Bytes(), nil
+	}
+
+	return nil, fmt.Errorf("unsupported output format: %s", format)
+}
+
+// unmarshalMetadata deserializes metadata from one of the proto
+// output formats
+func unmarshalMetadata(b []byte, format string) (*BlueprintMetadata, error) {
+	md, err := getMetadataDescriptor()
+	if err != nil {
+		return nil, err
+	}
+
+	m := dynamicpb.NewMessage(md)
+	switch format {
+	case outputFormatProto:
+		err = proto.Unmarshal(b, m)
+	case outputFormatTextproto:
+		err = prototext.Unmarshal(b, m)
+	case outputFormatJSON:
+		err = protojson.Unmarshal(b, m)
+	default:
+		err = fmt.Errorf("unsupported output format: %s", format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return metadataFromProto(m)
+}
+
+// validateOutputFormats checks that all formats are supported
+func validateOutputFormats(formats []string) error {
+	for _, f := range formats {
+		if _, ok := outputFormatExtensions[f]; !ok {
+			return fmt.Errorf("unsupported output format: %s. Supported formats are: %s, %s, %s", f, outputFormatJSON, outputFormatTextproto, outputFormatProto)
+		}
+	}
+
+	return nil
+}
+
+// writeMetadataFormats writes metadata in each of the output formats next
+// to the YAML file e.g. metadata.json for metadata.yaml and reports whether
+// any of the files changed
+func writeMetadataFormats(obj *BlueprintMetadata, bpPath, fileName string, formats []string) (bool, error) {
+	changed := false
+	for _, f := range formats {
+		b, err := marshalMetadata(obj, f)
+		if err != nil {
+			return changed, err
+		}
+
+		outFileName := strings.TrimSuffix(fileName, path.Ext(fileName)) + outputFormatExtensions[f]
+		c, err := writeFileIfChanged(path.Join(bpPath, outFileName), b)
+		changed = changed || c
+		if err != nil {
+			return changed, err
+		}
+	}
+
+	return changed, nil
+}
+
+// loadProtoFiles builds a registry from the embedded descriptor set
+func loadProtoFiles() (*protoregistry.Files, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(protoDescriptorSet, set); err != nil {
+		return nil, fmt.Errorf("unable to read proto descriptors: %w", err)
+	}
+
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build proto descriptors: %w", err)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,424 @@
+package bpmetadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const protoValueMessage = "google.protobuf.Value"
+
+var (
+	// reProtoDecl matches top level message and enum declarations in proto files
+	reProtoDecl = regexp.MustCompile(`^(?:message|enum)\s+(\w+)`)
+
+	// reProtoField matches fields of messages and values of enums
+	reProtoField = regexp.MustCompile(`^\s*(?:(?:optional|repeated)\s+)?(?:(?:map<[^>]+>|[\w.]+)\s+)?(\w+)\s*=\s*(-?\d+)\s*[;\[]`)
+)
+
+func TestProtoConformance(t *testing.T) {
+	md, err := getMetadataDescriptor()
+	require.NoError(t, err)
+
+	errs := compareProtoFields(reflect.TypeOf(BlueprintMetadata{}), md, "", make(map[string]bool))
+	assert.Empty(t, errs, "Go types and proto definitions are out of sync:\n%s", strings.Join(errs, "\n"))
+}
+
+func TestProtoDescriptorsAreCurrent(t *testing.T) {
+	files, err := getProtoFiles()
+	require.NoError(t, err)
+
+	protos, err := filepath.Glob(path.Join("proto", "*.proto"))
+	require.NoError(t, err)
+	require.NotEmpty(t, protos)
+	for _, p := range protos {
+		b, err := os.ReadFile(p)
+		require.NoError(t, err)
+
+		fd, err := files.FindFileByPath(path.Join("bpmetadata", p))
+		require.NoError(t, err, "descriptors are out of date, run make protoc")
+
+		// compare the messages and enums declared at the top level along
+		// with their fields and values
+		var got []string
+		for i := 0; i < fd.Messages().Len(); i++ {
+			m := fd.Messages().Get(i)
+			got = append(got, string(m.Name()))
+			for j := 0; j < m.Fields().Len(); j++ {
+				f := m.Fields().Get(j)
+				got = append(got, fmt.Sprintf("%s.%s=%d", m.Name(), f.Name(), f.Number()))
+			}
+		}
+
+		for i := 0; i < fd.Enums().Len(); i++ {
+			e := fd.Enums().Get(i)
+			got = append(got, string(e.Name()))
+			for j := 0; j < e.Values().Len(); j++ {
+				v := e.Values().Get(j)
+				got = append(got, fmt.Sprintf("%s.%s=%d", e.Name(), v.Name(), v.Number()))
+			}
+		}
+
+		assert.ElementsMatch(t, protoDeclarations(string(b)), got, "descriptors for %s are out of date, run make protoc", p)
+	}
+}
+
+// protoDeclarations returns the names of the messages and enums declared at
+// the top level of a proto file along with their fields and values as
+// "<decl>.<name>=<number>"
+func protoDeclarations(proto string) []string {
+	var decls []string
+	decl := ""
+	depth := 0
+	for _, line := range strings.Split(proto, "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+
+		if m := reProtoDecl.FindStringSubmatch(line); m != nil && depth == 0 {
+			decl = m[1]
+			decls = append(decls, decl)
+		} else if m := reProtoField.FindStringSubmatch(line); m != nil && depth == 1 && decl != "" {
+			decls = append(decls, fmt.Sprintf("%s.%s=%s", decl, m[1], m[2]))
+		}
+
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		if depth == 0 {
+			decl = ""
+		}
+	}
+
+	return decls
+}
+
+func TestProtoConformanceDetectsDrift(t *testing.T) {
+	files, err := getProtoFiles()
+	require.NoError(t, err)
+
+	d, err := files.FindDescriptorByName("google.cloud.config.bpmetadata.BlueprintMiscContent")
+	require.NoError(t, err)
+
+	type driftedMiscContent struct {
+		Name    string                   `json:"name"`
+		Extra   string                   `json:"extra"`
+		Modules []BlueprintExampleModule `json:"modules"`
+	}
+
+	errs := compareProtoFields(reflect.TypeOf(driftedMiscContent{}), d.(protoreflect.MessageDescriptor), "", make(map[string]bool))
+	assert.ElementsMatch(t, []string{
+		"extra: Go field has no proto counterpart in google.cloud.config.bpmetadata.BlueprintMiscContent",
+		"location: proto field has no Go counterpart in driftedMiscContent",
+	}, errs)
+}
+
+func TestSchemaConformance(t *testing.T) {
+	b, err := os.ReadFile(path.Join("schema", "bpmetadataschema.json"))
+	require.NoError(t, err)
+
+	var s struct {
+		Defs map[string]struct {
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"$defs"`
+	}
+	require.NoError(t, json.Unmarshal(b, &s))
+
+	defs := make(map[string][]string)
+	for name, def := range s.Defs {
+		for p := range def.Properties {
+			defs[name] = append(defs[name], p)
+		}
+	}
+
+	errs := compareSchemaFields(reflect.TypeOf(BlueprintMetadata{}), defs, make(map[string]bool))
+	assert.Empty(t, errs, "Go types and the JSON schema are out of sync, regenerate the schema:\n%s", strings.Join(errs, "\n"))
+}
+
+func TestProtoRoundTrip(t *testing.T) {
+	files := []struct {
+		dir  string
+		name string
+	}{
+		{dir: path.Join("int-test", "goldens"), name: "golden-metadata.yaml"},
+		{dir: path.Join("int-test", "goldens"), name: "golden-metadata.display.yaml"},
+		{dir: yamlTestDirPath, name: "valid-metadata.yaml"},
+		{dir: yamlTestDirPath, name: "valid-metadata-w-enum.yaml"},
+	}
+
+	for _, f := range files {
+		for _, format := range []string{outputFormatProto, outputFormatTextproto, outputFormatJSON} {
+			t.Run(f.name+" "+format, func(t *testing.T) {
+				want, err := UnmarshalMetadata(f.dir, f.name)
+				require.NoError(t, err)
+
+				b, err := marshalMetadata(want, format)
+				require.NoError(t, err)
+
+				got, err := unmarshalMetadata(b, format)
+				require.NoError(t, err)
+
+				d, err := diffMetadata(want, got)
+				require.NoError(t, err)
+				assert.Empty(t, d)
+			})
+		}
+	}
+}
+
+func TestWriteMetadataFormats(t *testing.T) {
+	dir := t.TempDir()
+	bpObj, err := UnmarshalMetadata(path.Join("int-test", "goldens"), "golden-metadata.yaml")
+	require.NoError(t, err)
+
+	formats := []string{outputFormatJSON, outputFormatTextproto, outputFormatProto}
+	changed, err := writeMetadataFormats(bpObj, dir, metadataFileName, formats)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	// rewriting the same metadata leaves the files as is
+	changed, err = writeMetadataFormats(bpObj, dir, metadataFileName, formats)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	for _, name := range []string{"metadata.json", "metadata.textproto", "metadata.pb"} {
+		assert.FileExists(t, path.Join(dir, name))
+	}
+
+	b, err := os.ReadFile(path.Join(dir, "metadata.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"apiVersion": "blueprints.cloud.google.com/v1alpha1"`)
+
+	assert.Error(t, validateOutputFormats([]string{"xml"}))
+}
+
+// compareProtoFields compares the JSON fields of a Go struct type with the
+// fields of a proto message and returns a description of each mismatch
+func compareProtoFields(t reflect.Type, md protoreflect.MessageDescriptor, fieldPath string, visited map[string]bool) []string {
+	key := t.String() + "/" + string(md.FullName())
+	if visited[key] {
+		return nil
+	}
+
+	visited[key] = true
+	var errs []string
+	goFields := goJSONFields(t)
+	for _, name := range sortedKeys(goFields) {
+		f := goFields[name]
+		fd := md.Fields().ByJSONName(name)
+		if fd == nil {
+			errs = append(errs, fmt.Sprintf("%s: Go field has no proto counterpart in %s", joinFieldPath(fieldPath, name), md.FullName()))
+			continue
+		}
+
+		errs = append(errs, compareProtoField(f, fd, joinFieldPath(fieldPath, name), visited)...)
+	}
+
+	for i := 0; i < md.Fields().Len(); i++ {
+		fd := md.Fields().Get(i)
+		if _, ok := goFields[fd.JSONName()]; !ok {
+			errs = append(errs, fmt.Sprintf("%s: proto field has no Go counterpart in %s", joinFieldPath(fieldPath, fd.JSONName()), t.Name()))
+		}
+	}
+
+	return errs
+}
+
+func compareProtoField(f reflect.StructField, fd protoreflect.FieldDescriptor, fieldPath string, visited map[string]bool) []string {
+	gt := f.Type
+	switch {
+	case fd.IsMap():
+		if gt.Kind() != reflect.Map {
+			return []string{fmt.Sprintf("%s: proto map has Go type %s", fieldPath, gt)}
+		}
+
+		return compareProtoValue(gt.Elem(), f.Tag, fd.MapValue(), fieldPath, visited)
+	case fd.IsList():
+		if gt.Kind() != reflect.Slice {
+			return []string{fmt.Sprintf("%s: proto repeated field has Go type %s", fieldPath, gt)}
+		}
+
+		return compareProtoValue(gt.Elem(), f.Tag, fd, fieldPath, visited)
+	case gt.Kind() == reflect.Slice || gt.Kind() == reflect.Map:
+		return []string{fmt.Sprintf("%s: Go type %s has singular proto field", fieldPath, gt)}
+	case gt.Kind() == reflect.Ptr && gt.Elem().Kind() != reflect.Struct && !fd.HasPresence():
+		return []string{fmt.Sprintf("%s: Go pointer %s has proto field without presence, declare it optional", fieldPath, gt)}
+	}
+
+	return compareProtoValue(gt, f.Tag, fd, fieldPath, visited)
+}
+
+func compareProtoValue(gt reflect.Type, tag reflect.StructTag, fd protoreflect.FieldDescriptor, fieldPath string, visited map[string]bool) []string {
+	if gt.Kind() == reflect.Ptr {
+		gt = gt.Elem()
+	}
+
+	mismatch := []string{fmt.Sprintf("%s: Go type %s doesn't match proto %s", fieldPath, gt, fd.Kind())}
+	switch gt.Kind() {
+	case reflect.String:
+		switch fd.Kind() {
+		case protoreflect.StringKind:
+			return nil
+		case protoreflect.EnumKind:
+			return compareProtoEnum(tag, fd.Enum(), fieldPath)
+		}
+	case reflect.Bool:
+		if fd.Kind() == protoreflect.BoolKind {
+			return nil
+		}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		switch fd.Kind() {
+		case protoreflect.Int32Kind, protoreflect.Int64Kind, protoreflect.Sint32Kind, protoreflect.Sint64Kind:
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		switch fd.Kind() {
+		case protoreflect.FloatKind, protoreflect.DoubleKind:
+			return nil
+		}
+	case reflect.Interface:
+		if fd.Kind() == protoreflect.MessageKind && fd.Message().FullName() == protoValueMessage {
+			return nil
+		}
+	case reflect.Struct:
+		if fd.Kind() == protoreflect.MessageKind {
+			return compareProtoFields(gt, fd.Message(), fieldPath, visited)
+		}
+	}
+
+	return mismatch
+}
+
+// compareProtoEnum compares the values allowed for a Go field through its
+// jsonschema tag with the values of a proto enum. The zero value of proto
+// enums is a placeholder and may not be allowed in Go, and aliases of values
+// declared earlier are kept for proto consumers only.
+func compareProtoEnum(tag reflect.StructTag, ed protoreflect.EnumDescriptor, fieldPath string) []string {
+	goValues := make(map[string]bool)
+	for _, opt := range strings.Split(tag.Get("jsonschema"), ",") {
+		if v := strings.TrimPrefix(opt, "enum="); v != opt {
+			goValues[v] = true
+		}
+	}
+
+	var errs []string
+	for _, v := range strings.Split(tag.Get("jsonschema"), ",") {
+		v = strings.TrimPrefix(v, "enum=")
+		if !goValues[v] {
+			continue
+		}
+
+		if ed.Values().ByName(protoreflect.Name(v)) == nil {
+			errs = append(errs, fmt.Sprintf("%s: Go enum value %s has no proto counterpart in %s", fieldPath, v, ed.FullName()))
+		}
+	}
+
+	for i := 1; i < ed.Values().Len(); i++ {
+		ev := ed.Values().Get(i)
+		if ed.Values().ByNumber(ev.Number()) != ev {
+			continue
+		}
+
+		if v := string(ev.Name()); !goValues[v] {
+			errs = append(errs, fmt.Sprintf("%s: proto enum value %s has no Go counterpart", fieldPath, v))
+		}
+	}
+
+	return errs
+}
+
+// compareSchemaFields compares the JSON fields of a Go struct type and the
+// struct types it refers to with the properties of their JSON schema defs
+func compareSchemaFields(t reflect.Type, defs map[string][]string, visited map[string]bool) []string {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		return compareSchemaFields(t.Elem(), defs, visited)
+	case reflect.Struct:
+	default:
+		return nil
+	}
+
+	if visited[t.String()] {
+		return nil
+	}
+
+	visited[t.String()] = true
+	props, ok := defs[t.Name()]
+	if !ok {
+		return []string{fmt.Sprintf("%s: Go type has no JSON schema def", t.Name())}
+	}
+
+	var errs []string
+	goFields := goJSONFields(t)
+	schemaProps := make(map[string]bool)
+	for _, p := range props {
+		schemaProps[p] = true
+		if _, ok := goFields[p]; !ok {
+			errs = append(errs, fmt.Sprintf("%s.%s: JSON schema property has no Go counterpart", t.Name(), p))
+		}
+	}
+
+	for _, name := range sortedKeys(goFields) {
+		if !schemaProps[name] {
+			errs = append(errs, fmt.Sprintf("%s.%s: Go field has no JSON schema property", t.Name(), name))
+		}
+
+		errs = append(errs, compareSchemaFields(goFields[name].Type, defs, visited)...)
+	}
+
+	return errs
+}
+
+// goJSONFields returns the fields of a Go struct type by their JSON names,
+// flattening embedded structs the same way as encoding/json
+func goJSONFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		switch {
+		case name == "-":
+			continue
+		case f.Anonymous && name == "":
+			for n, ef := range goJSONFields(f.Type) {
+				fields[n] = ef
+			}
+
+			continue
+		case f.PkgPath != "":
+			continue
+		case name == "":
+			name = f.Name
+		}
+
+		fields[name] = f
+	}
+
+	return fields
+}
+
+func joinFieldPath(fieldPath, name string) string {
+	if fieldPath == "" {
+		return name
+	}
+
+	return fieldPath + "." + name
+}
+
+func sortedKeys(m map[string]reflect.StructField) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
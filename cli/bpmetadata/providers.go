@@ -0,0 +1,227 @@
+package bpmetadata
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultProviderRegistry is the registry host providers are
+// installed from if their source doesn't set one
+const defaultProviderRegistry = "registry.terraform.io/"
+
+// versionBound is the lower or upper bound of a range of versions
+type versionBound struct {
+	set       bool
+	version   [3]int
+	inclusive bool
+}
+
+// versionRange is the range of versions allowed by a constraint
+type versionRange struct {
+	lower versionBound
+	upper versionBound
+}
+
+// providerConstraint is the version constraint for a provider set by
+// a module of a blueprint
+type providerConstraint struct {
+	module     string
+	constraint string
+	file       string
+	field      []string
+}
+
+// providerSource returns the normalized source address of a provider.
+// Providers without a source are from the hashicorp namespace.
+func providerSource(p BlueprintProvider) string {
+	src := p.Source
+	if src == "" {
+		src = "hashicorp/" + p.Name
+	}
+
+	return strings.ToLower(strings.TrimPrefix(src, defaultProviderRegistry))
+}
+
+// parseVersion parses the segments of a version e.g. "4.4" as 4.4.0
+func parseVersion(v string) ([3]int, error) {
+	var segs [3]int
+	for i, s := range strings.SplitN(v, ".", 3) {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return segs, err
+		}
+
+		segs[i] = n
+	}
+
+	return segs, nil
+}
+
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// constraintRange returns the range of versions allowed by a version
+// constraint e.g. ">= 4.4.0, < 5.0". Exclusions with "!=" are ignored.
+func constraintRange(constraint string) (versionRange, error) {
+	var r versionRange
+	for _, c := range strings.Split(constraint, ",") {
+		m := reVersionConstraint.FindStringSubmatch(strings.TrimSpace(c))
+		if m == nil {
+			return r, fmt.Errorf("invalid version constraint %q", constraint)
+		}
+
+		v, err := parseVersion(m[2])
+		if err != nil {
+			return r, fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+		}
+
+		switch m[1] {
+		case "", "=":
+			r = r.intersect(versionRange{versionBound{true, v, true}, versionBound{true, v, true}})
+		case ">=", ">":
+			r = r.intersect(versionRange{lower: versionBound{true, v, m[1] == ">="}})
+		case "<=", "<":
+			r = r.intersect(versionRange{upper: versionBound{true, v, m[1] == "<="}})
+		case "~>":
+			// the rightmost segment can increase e.g. "~> 4.4" allows
+			// 4.x from 4.4 and "~> 4.4.1" allows 4.4.x from 4.4.1
+			pr := versionRange{lower: versionBound{true, v, true}}
+			if n := strings.Count(m[2], "."); n > 0 {
+				var upper [3]int
+				copy(upper[:], v[:n-1])
+				upper[n-1] = v[n-1] + 1
+				pr.upper = versionBound{true, upper, false}
+			}
+
+			r = r.intersect(pr)
+		}
+	}
+
+	return r, nil
+}
+
+// intersect returns the range of versions allowed by both ranges
+func (r versionRange) intersect(o versionRange) versionRange {
+	res := r
+	if o.lower.set {
+		c := compareVersions(o.lower.version, r.lower.version)
+		if !r.lower.set || c > 0 || (c == 0 && !o.lower.inclusive) {
+			res.lower = o.lower
+		}
+	}
+
+	if o.upper.set {
+		c := compareVersions(o.upper.version, r.upper.version)
+		if !r.upper.set || c < 0 || (c == 0 && !o.upper.inclusive) {
+			res.upper = o.upper
+		}
+	}
+
+	return res
+}
+
+// empty returns true if no version is in the range
+func (r versionRange) empty() bool {
+	if !r.lower.set || !r.upper.set {
+		return false
+	}
+
+	c := compareVersions(r.lower.version, r.upper.version)
+	return c > 0 || (c == 0 && !(r.lower.inclusive && r.upper.inclusive))
+}
+
+// validateProviderConstraints checks that the provider version constraints
+// in the core metadata of the root module and submodules in moduleDirs can
+// be satisfied together, since a root config using several of them must
+// install a single version of each provider. A finding is returned for
+// each provider that can't be satisfied. Invalid constraints aren't checked.
+func validateProviderConstraints(rootPath string, moduleDirs []string, modulesPath string) ([]validationFinding, error) {
+	constraints := make(map[string][]providerConstraint)
+	for _, d := range moduleDirs {
+		bpObj, err := UnmarshalMetadata(d, metadataFileName)
+		if err != nil {
+			continue
+		}
+
+		name := getBpSubmoduleName(d, modulesPath)
+		if name == "" {
+			name = "root module"
+		}
+
+		for i, p := range bpObj.Spec.Requirements.Providers {
+			if p.Version == "" {
+				continue
+			}
+
+			src := providerSource(p)
+			constraints[src] = append(constraints[src], providerConstraint{
+				module:     name,
+				constraint: p.Version,
+				file:       path.Join(d, metadataFileName),
+				field:      []string{"spec", "requirements", "providers", strconv.Itoa(i), "version"},
+			})
+		}
+	}
+
+	var srcs []string
+	for src := range constraints {
+		srcs = append(srcs, src)
+	}
+	sort.Strings(srcs)
+
+	var findings []validationFinding
+	for _, src := range srcs {
+		var r versionRange
+		var found []string
+		for _, c := range constraints[src] {
+			cr, err := constraintRange(c.constraint)
+			if err != nil {
+				Log.Warn("unable to check provider version constraint", "provider", src, "module", c.module, "err", err)
+				continue
+			}
+
+			r = r.intersect(cr)
+			found = append(found, fmt.Sprintf("%q (%s)", c.constraint, c.module))
+		}
+
+		if !r.empty() {
+			continue
+		}
+
+		// the finding is reported at the first constraint, which is
+		// for the root module if it requires the provider
+		first := constraints[src][0]
+		line, col := fieldPosition(first.file, first.field)
+		f := validationFinding{
+			File:    first.file,
+			Line:    line,
+			Column:  col,
+			Field:   strings.Join(first.field, "."),
+			Rule:    "provider",
+			Message: fmt.Sprintf("version constraints for provider %s can't be satisfied together: %s", src, strings.Join(found, ", ")),
+		}
+
+		Log.Error("provider validation error", "path", f.location(), "err", f.Message)
+		findings = append(findings, f)
+	}
+
+	if len(findings) > 0 {
+		return findings, fmt.Errorf("provider version constraints can't be satisfied for: %s", rootPath)
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,112 @@
+package bpmetadata
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRequiredProviders(t *testing.T) {
+	got, err := getBlueprintVersion(path.Join(tfTestdataPath, "versions-providers.tf"))
+	require.NoError(t, err)
+	assert.Equal(t, []BlueprintProvider{
+		{Name: "google", Source: "hashicorp/google", Version: ">= 4.64, < 6", ConfigurationAliases: []string{"google.us", "google.eu"}},
+		{Name: "kubernetes", Source: "hashicorp/kubernetes"},
+		{Name: "random", Version: "~> 3.0"},
+	}, got.requiredProviders)
+}
+
+func TestConstraintsSatisfiable(t *testing.T) {
+	tests := []struct {
+		name        string
+		constraints []string
+		want        bool
+	}{
+		{
+			name:        "overlapping ranges",
+			constraints: []string{">= 4.4.0, < 5.0", ">= 4.64", "~> 4.70"},
+			want:        true,
+		},
+		{
+			name:        "disjoint ranges",
+			constraints: []string{">= 4.4.0, < 5.0", ">= 5.0"},
+		},
+		{
+			name:        "pessimistic patch",
+			constraints: []string{"~> 4.4.1", ">= 4.5"},
+		},
+		{
+			name:        "pessimistic major is unbounded",
+			constraints: []string{"~> 4", ">= 5.10"},
+			want:        true,
+		},
+		{
+			name:        "exact versions",
+			constraints: []string{"4.80.0", "<= 4.80.0"},
+			want:        true,
+		},
+		{
+			name:        "exclusive bounds",
+			constraints: []string{"> 4.80.0", "< 4.80.1", "<= 4.80.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r versionRange
+			for _, c := range tt.constraints {
+				cr, err := constraintRange(c)
+				require.NoError(t, err)
+				r = r.intersect(cr)
+			}
+
+			assert.Equal(t, tt.want, !r.empty())
+		})
+	}
+
+	_, err := constraintRange(">= latest")
+	assert.Error(t, err)
+}
+
+func TestValidateProviderConstraints(t *testing.T) {
+	root := t.TempDir()
+	sub1 := path.Join(root, "modules", "sub1")
+	sub2 := path.Join(root, "modules", "sub2")
+	providers := map[string][]BlueprintProvider{
+		root: {
+			{Name: "google", Source: "hashicorp/google", Version: ">= 4.4.0, < 5.0"},
+			{Name: "google-beta", Source: "hashicorp/google-beta", Version: ">= 4.4.0, < 5.0"},
+		},
+		sub1: {
+			{Name: "google", Version: "~> 4.60"},
+			{Name: "google-beta", Source: "registry.terraform.io/hashicorp/google-beta", Version: ">= 5.0"},
+		},
+		sub2: {
+			{Name: "google", Source: "hashicorp/google", Version: ">= 4.50"},
+		},
+	}
+
+	for d, p := range providers {
+		require.NoError(t, os.MkdirAll(d, 0755))
+		bpObj := &BlueprintMetadata{}
+		bpObj.APIVersion = metadataApiVersion
+		bpObj.Kind = metadataKind
+		bpObj.Spec.Requirements.Providers = p
+		require.NoError(t, WriteMetadata(bpObj, d, metadataFileName))
+	}
+
+	got, err := validateProviderConstraints(root, []string{root, sub1, sub2}, modulesPath)
+	assert.Error(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, path.Join(root, metadataFileName), got[0].File)
+	assert.Equal(t, "spec.requirements.providers.1.version", got[0].Field)
+	assert.NotZero(t, got[0].Line)
+	assert.Equal(t, `version constraints for provider hashicorp/google-beta can't be satisfied together: ">= 4.4.0, < 5.0" (root module), ">= 5.0" (sub1)`, got[0].Message)
+
+	got, err = validateProviderConstraints(root, []string{root, sub2}, modulesPath)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+}
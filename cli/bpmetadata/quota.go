@@ -0,0 +1,314 @@
+package bpmetadata
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/gocty"
+)
+
+// defaultDiskType is the type of disks that don't set one
+const defaultDiskType = "pd-standard"
+
+// reCustomMachineType matches custom machine types e.g. "n2-custom-4-16384"
+// with the number of CPUs as the first group
+var reCustomMachineType = regexp.MustCompile(`^(?:[a-z][a-z0-9]*-)?custom-(\d+)-\d+(?:-ext)?$`)
+
+// rePredefinedMachineType matches predefined machine types e.g.
+// "n2-standard-8" with the number of CPUs as the first group
+var rePredefinedMachineType = regexp.MustCompile(`^[a-z][a-z0-9]*-(?:standard|highmem|highcpu|ultramem|megamem|hypermem)-(\d+)(?:-lssd)?$`)
+
+// sharedCoreMachineTypes are the CPUs counted towards quota for machine
+// types that share a physical core
+var sharedCoreMachineTypes = map[string]int{
+	"f1-micro":  1,
+	"g1-small":  1,
+	"e2-micro":  2,
+	"e2-small":  2,
+	"e2-medium": 2,
+}
+
+// quotaValue is a value that counts towards quota along with the input
+// variable it comes from, if any
+type quotaValue struct {
+	val      cty.Value
+	variable string
+}
+
+// known returns true if the value could be determined statically
+func (v quotaValue) known() bool {
+	return v.val != cty.NilVal && v.val.IsWhollyKnown() && !v.val.IsNull()
+}
+
+// getQuotaDetails estimates the GCE instance and disk quotas required by
+// the resources in the Terraform config at configPath. Values that come
+// from input variables are estimated from their defaults and the quota is
+// linked to the variable. Resources using count or for_each are counted
+// as many times as can be determined statically, otherwise once.
+func getQuotaDetails(configPath string) ([]BlueprintQuotaDetail, error) {
+	files, err := filepath.Glob(filepath.Join(configPath, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	p := hclparse.NewParser()
+	var bodies []*hclsyntax.Body
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		file, diags := p.ParseHCL(b, filepath.Base(f))
+		err = hasHclErrors(diags)
+		if err != nil {
+			return nil, err
+		}
+
+		if body, ok := file.Body.(*hclsyntax.Body); ok {
+			bodies = append(bodies, body)
+		}
+	}
+
+	// variable defaults are needed to resolve values in any file
+	defaults := make(map[string]cty.Value)
+	for _, body := range bodies {
+		for _, block := range body.Blocks {
+			if block.Type != "variable" || len(block.Labels) != 1 {
+				continue
+			}
+
+			defaults[block.Labels[0]] = cty.NilVal
+			if attr, found := block.Body.Attributes["default"]; found {
+				if v, diags := attr.Expr.Value(nil); !diags.HasErrors() {
+					defaults[block.Labels[0]] = v
+				}
+			}
+		}
+	}
+
+	var quotas []BlueprintQuotaDetail
+	for _, body := range bodies {
+		for _, block := range body.Blocks {
+			if block.Type != "resource" || len(block.Labels) != 2 {
+				continue
+			}
+
+			count := resourceCount(block.Body, defaults)
+			switch block.Labels[0] {
+			case "google_compute_instance":
+				quotas = appendQuota(quotas, instanceQuota(block.Body, count, defaults))
+				for _, d := range nestedBlocks(block.Body, "boot_disk", "initialize_params") {
+					quotas = appendQuota(quotas, diskQuota(d, "size", "type", count, defaults))
+				}
+			case "google_compute_instance_template", "google_compute_region_instance_template":
+				quotas = appendQuota(quotas, instanceQuota(block.Body, count, defaults))
+				for _, d := range nestedBlocks(block.Body, "disk") {
+					quotas = appendQuota(quotas, diskQuota(d, "disk_size_gb", "disk_type", count, defaults))
+				}
+			case "google_compute_disk":
+				quotas = appendQuota(quotas, diskQuota(block.Body, "size", "type", count, defaults))
+			}
+		}
+	}
+
+	return quotas, nil
+}
+
+// appendQuota appends a quota detail if anything is known about it
+func appendQuota(quotas []BlueprintQuotaDetail, q BlueprintQuotaDetail) []BlueprintQuotaDetail {
+	if len(q.QuotaType) == 0 && q.DynamicVariable == "" {
+		return quotas
+	}
+
+	return append(quotas, q)
+}
+
+// instanceQuota estimates the machine type and CPUs required by count
+// instances with the given body
+func instanceQuota(body *hclsyntax.Body, count quotaValue, defaults map[string]cty.Value) BlueprintQuotaDetail {
+	q := BlueprintQuotaDetail{
+		ResourceType: QuotaResTypeGCEInstance,
+		QuotaType:    make(map[QuotaType]string),
+	}
+
+	mt := attributeValue(body, "machine_type", defaults)
+	n, countKnown := quotaInt(count)
+	if mt.known() && mt.val.Type() == cty.String {
+		q.QuotaType[MachineType] = mt.val.AsString()
+		if cpus, ok := machineTypeCPUs(mt.val.AsString()); ok && countKnown {
+			q.QuotaType[CPUs] = strconv.Itoa(cpus * n)
+		}
+	}
+
+	q.DynamicVariable = firstVariable(mt, count)
+	return q
+}
+
+// diskQuota estimates the disk type and size required by count disks with
+// the given body, where the size and type are set by the sizeAttr and
+// typeAttr arguments
+func diskQuota(body *hclsyntax.Body, sizeAttr, typeAttr string, count quotaValue, defaults map[string]cty.Value) BlueprintQuotaDetail {
+	q := BlueprintQuotaDetail{
+		ResourceType: QuotaResTypeGCEDisk,
+		QuotaType:    make(map[QuotaType]string),
+	}
+
+	size := attributeValue(body, sizeAttr, defaults)
+	n, countKnown := quotaInt(count)
+	if s, ok := quotaInt(size); ok && countKnown {
+		q.QuotaType[DiskSizeGB] = strconv.Itoa(s * n)
+	}
+
+	diskType := attributeValue(body, typeAttr, defaults)
+	_, typeSet := body.Attributes[typeAttr]
+	switch {
+	case diskType.known() && diskType.val.Type() == cty.String:
+		q.QuotaType[DiskType] = diskType.val.AsString()
+	case !typeSet && len(q.QuotaType) > 0:
+		q.QuotaType[DiskType] = defaultDiskType
+	}
+
+	q.DynamicVariable = firstVariable(size, diskType, count)
+	return q
+}
+
+// resourceCount returns the number of instances of a resource from its
+// count or for_each argument. Resources without either are created once.
+func resourceCount(body *hclsyntax.Body, defaults map[string]cty.Value) quotaValue {
+	if attr, found := body.Attributes["count"]; found {
+		return resolveQuotaValue(attr.Expr, defaults)
+	}
+
+	attr, found := body.Attributes["for_each"]
+	if !found {
+		return quotaValue{val: cty.NumberIntVal(1)}
+	}
+
+	v := resolveQuotaValue(attr.Expr, defaults)
+	if v.known() && v.val.CanIterateElements() {
+		v.val = cty.NumberIntVal(int64(v.val.LengthInt()))
+	} else {
+		v.val = cty.NilVal
+	}
+
+	return v
+}
+
+// attributeValue resolves the value of an argument in the body
+func attributeValue(body *hclsyntax.Body, name string, defaults map[string]cty.Value) quotaValue {
+	attr, found := body.Attributes[name]
+	if !found {
+		return quotaValue{}
+	}
+
+	return resolveQuotaValue(attr.Expr, defaults)
+}
+
+// resolveQuotaValue statically resolves an expression that is a literal,
+// an input variable or a toset, tolist or tomap call with one of those
+func resolveQuotaValue(expr hclsyntax.Expression, defaults map[string]cty.Value) quotaValue {
+	switch e := expr.(type) {
+	case *hclsyntax.ScopeTraversalExpr:
+		if e.Traversal.RootName() != "var" || len(e.Traversal) != 2 {
+			return quotaValue{}
+		}
+
+		attr, ok := e.Traversal[1].(hcl.TraverseAttr)
+		if !ok {
+			return quotaValue{}
+		}
+
+		return quotaValue{val: defaults[attr.Name], variable: attr.Name}
+	case *hclsyntax.FunctionCallExpr:
+		if len(e.Args) != 1 || (e.Name != "toset" && e.Name != "tolist" && e.Name != "tomap") {
+			return quotaValue{}
+		}
+
+		return resolveQuotaValue(e.Args[0], defaults)
+	}
+
+	v, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		return quotaValue{}
+	}
+
+	return quotaValue{val: v}
+}
+
+// quotaInt converts a value to a whole number
+func quotaInt(v quotaValue) (int, bool) {
+	if !v.known() {
+		return 0, false
+	}
+
+	n, err := convert.Convert(v.val, cty.Number)
+	if err != nil {
+		return 0, false
+	}
+
+	var i int
+	if err := gocty.FromCtyValue(n, &i); err != nil {
+		return 0, false
+	}
+
+	return i, true
+}
+
+// firstVariable returns the first input variable the values come from
+func firstVariable(values ...quotaValue) string {
+	for _, v := range values {
+		if v.variable != "" {
+			return v.variable
+		}
+	}
+
+	return ""
+}
+
+// nestedBlocks returns the bodies of blocks at the path of nested block
+// types
+func nestedBlocks(body *hclsyntax.Body, path ...string) []*hclsyntax.Body {
+	var bodies []*hclsyntax.Body
+	for _, b := range body.Blocks {
+		if b.Type != path[0] {
+			continue
+		}
+
+		if len(path) == 1 {
+			bodies = append(bodies, b.Body)
+			continue
+		}
+
+		bodies = append(bodies, nestedBlocks(b.Body, path[1:]...)...)
+	}
+
+	return bodies
+}
+
+// machineTypeCPUs returns the number of CPUs counted towards quota for
+// a machine type
+func machineTypeCPUs(machineType string) (int, bool) {
+	if cpus, found := sharedCoreMachineTypes[machineType]; found {
+		return cpus, true
+	}
+
+	m := reCustomMachineType.FindStringSubmatch(machineType)
+	if m == nil {
+		m = rePredefinedMachineType.FindStringSubmatch(machineType)
+	}
+
+	if m == nil {
+		return 0, false
+	}
+
+	cpus, err := strconv.Atoi(m[1])
+	return cpus, err == nil
+}
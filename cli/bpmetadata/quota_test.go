@@ -0,0 +1,96 @@
+package bpmetadata
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetQuotaDetails(t *testing.T) {
+	got, err := getQuotaDetails(path.Join(tfTestdataPath, "quotas"))
+	require.NoError(t, err)
+	assert.Equal(t, []BlueprintQuotaDetail{
+		{
+			DynamicVariable: "web_count",
+			ResourceType:    QuotaResTypeGCEInstance,
+			QuotaType:       map[QuotaType]string{MachineType: "n2-standard-4", CPUs: "8"},
+		},
+		{
+			DynamicVariable: "web_count",
+			ResourceType:    QuotaResTypeGCEDisk,
+			QuotaType:       map[QuotaType]string{DiskType: "pd-balanced", DiskSizeGB: "40"},
+		},
+		{
+			DynamicVariable: "worker_machine_type",
+			ResourceType:    QuotaResTypeGCEInstance,
+			QuotaType:       map[QuotaType]string{MachineType: "e2-medium", CPUs: "2"},
+		},
+		{
+			ResourceType: QuotaResTypeGCEDisk,
+			QuotaType:    map[QuotaType]string{DiskType: "pd-standard", DiskSizeGB: "50"},
+		},
+		{
+			DynamicVariable: "data_disk_size",
+			ResourceType:    QuotaResTypeGCEDisk,
+			QuotaType:       map[QuotaType]string{DiskType: "pd-ssd"},
+		},
+		{
+			ResourceType: QuotaResTypeGCEDisk,
+			QuotaType:    map[QuotaType]string{DiskType: "pd-standard", DiskSizeGB: "100"},
+		},
+	}, got)
+}
+
+func TestQuotaDetailsAreRefreshed(t *testing.T) {
+	estimated := []BlueprintQuotaDetail{{
+		ResourceType: QuotaResTypeGCEInstance,
+		QuotaType:    map[QuotaType]string{MachineType: "e2-medium", CPUs: "2"},
+	}}
+	estimatedSum, err := checksum(estimated)
+	require.NoError(t, err)
+
+	onDisk := &BlueprintMetadata{}
+	onDisk.Spec.Info.QuotaDetails = estimated
+
+	// quotas estimated previously are removed along with the resources
+	generated := &BlueprintMetadata{}
+	o := &metadataOwnership{Fields: map[string]*fieldOwnership{
+		"spec.info.quotaDetails": {Owner: ownerAuto, Checksum: estimatedSum},
+	}}
+	_, err = mergeOwnedFields(onDisk, generated, o)
+	require.NoError(t, err)
+	assert.Empty(t, generated.Spec.Info.QuotaDetails)
+
+	// quotas that are manually owned are kept
+	generated = &BlueprintMetadata{}
+	o.Fields["spec.info.quotaDetails"] = &fieldOwnership{Owner: ownerManual}
+	_, err = mergeOwnedFields(onDisk, generated, o)
+	require.NoError(t, err)
+	assert.Equal(t, estimated, generated.Spec.Info.QuotaDetails)
+}
+
+func TestMachineTypeCPUs(t *testing.T) {
+	tests := []struct {
+		machineType string
+		want        int
+		wantOk      bool
+	}{
+		{machineType: "n2-standard-8", want: 8, wantOk: true},
+		{machineType: "c3-highcpu-22-lssd", want: 22, wantOk: true},
+		{machineType: "e2-small", want: 2, wantOk: true},
+		{machineType: "f1-micro", want: 1, wantOk: true},
+		{machineType: "custom-6-23040", want: 6, wantOk: true},
+		{machineType: "n2-custom-4-16384-ext", want: 4, wantOk: true},
+		{machineType: "a2-highgpu-1g"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.machineType, func(t *testing.T) {
+			got, ok := machineTypeCPUs(tt.machineType)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package bpmetadata
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// referenceError is a dangling or mistyped reference along with the
+// path to the field holding the reference.
+type referenceError struct {
+	// display is set if the reference is in display metadata
+	display bool
+	field   []string
+	msg     string
+}
+
+func (e referenceError) Error() string {
+	return e.msg
+}
+
+// extensionRef is a reference from a display variable's extension to
+// another display variable that must have a specific extension type.
+type extensionRef struct {
+	field    string
+	variable string
+	wantType ExtensionType
+}
+
+// validateMetadataReferences loads core and display metadata for the
+// blueprint at bpPath and validates the references between them
+func validateMetadataReferences(bpPath string) ([]validationFinding, error) {
+	core, err := UnmarshalMetadata(bpPath, metadataFileName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load core metadata for %s: %w", bpPath, err)
+	}
+
+	disp, err := UnmarshalMetadata(bpPath, metadataDisplayFileName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load display metadata for %s: %w", bpPath, err)
+	}
+
+	refErrs := validateReferences(core, disp)
+	if len(refErrs) == 0 {
+		return nil, nil
+	}
+
+	var findings []validationFinding
+	for _, e := range refErrs {
+		m := path.Join(bpPath, metadataFileName)
+		if e.display {
+			m = path.Join(bpPath, metadataDisplayFileName)
+		}
+
+		line, col := fieldPosition(m, e.field)
+		f := validationFinding{
+			File:    m,
+			Line:    line,
+			Column:  col,
+			Field:   strings.Join(e.field, "."),
+			Rule:    "reference",
+			Message: e.msg,
+		}
+
+		Log.Error("reference validation error", "path", f.location(), "err", e)
+		findings = append(findings, f)
+	}
+
+	return findings, fmt.Errorf("metadata reference validation failed for: %s", bpPath)
+}
+
+// validateReferences returns every dangling or mistyped reference found
+// across core and display metadata for a blueprint
+func validateReferences(core, disp *BlueprintMetadata) []referenceError {
+	var errs []referenceError
+	coreVars := make(map[string]bool)
+	for _, v := range core.Spec.Interfaces.Variables {
+		coreVars[v.Name] = true
+	}
+
+	// variable groups and quotas in core metadata
+	for i, g := range core.Spec.Interfaces.VariableGroups {
+		for j, v := range g.Variables {
+			if !coreVars[v] {
+				errs = append(errs, referenceError{
+					field: []string{"spec", "interfaces", "variableGroups", strconv.Itoa(i), "variables", strconv.Itoa(j)},
+					msg:   fmt.Sprintf("variable group %q references unknown variable %q", g.Name, v),
+				})
+			}
+		}
+	}
+
+	for i, q := range core.Spec.Info.QuotaDetails {
+		if q.DynamicVariable != "" && !coreVars[q.DynamicVariable] {
+			errs = append(errs, referenceError{
+				field: []string{"spec", "info", "quotaDetails", strconv.Itoa(i), "dynamicVariable"},
+				msg:   fmt.Sprintf("quota detail for %s references unknown dynamic variable %q", q.ResourceType, q.DynamicVariable),
+			})
+		}
+	}
+
+	// sections in display metadata
+	sections := make(map[string]bool)
+	for _, s := range disp.Spec.UI.Input.Sections {
+		sections[s.Name] = true
+	}
+
+	for i, s := range disp.Spec.UI.Input.Sections {
+		if s.Parent != "" && !sections[s.Parent] {
+			errs = append(errs, referenceError{
+				display: true,
+				field:   []string{"spec", "ui", "input", "sections", strconv.Itoa(i), "parent"},
+				msg:     fmt.Sprintf("section %q references unknown parent section %q", s.Name, s.Parent),
+			})
+		}
+	}
+
+	// display variables, sorted for a stable error order
+	dispVars := disp.Spec.UI.Input.Variables
+	var names []string
+	for n := range dispVars {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		dv := dispVars[n]
+		if dv == nil {
+			continue
+		}
+
+		varField := []string{"spec", "ui", "input", "variables", n}
+		if !coreVars[n] {
+			errs = append(errs, referenceError{
+				display: true,
+				field:   varField,
+				msg:     fmt.Sprintf("display variable %q does not exist in core metadata", n),
+			})
+		}
+
+		if dv.Name != "" && dv.Name != n {
+			errs = append(errs, referenceError{
+				display: true,
+				field:   append(varField, "name"),
+				msg:     fmt.Sprintf("display variable %q has a mismatched name %q", n, dv.Name),
+			})
+		}
+
+		if dv.Section != "" && !sections[dv.Section] {
+			errs = append(errs, referenceError{
+				display: true,
+				field:   append(varField, "section"),
+				msg:     fmt.Sprintf("display variable %q references unknown section %q", n, dv.Section),
+			})
+		}
+
+		for _, r := range extensionRefs(dv.XGoogleProperty) {
+			refField := append(varField, strings.Split(r.field, ".")...)
+			ref, exists := dispVars[r.variable]
+			if !exists || ref == nil {
+				errs = append(errs, referenceError{
+					display: true,
+					field:   refField,
+					msg:     fmt.Sprintf("display variable %q references unknown variable %q in %s", n, r.variable, r.field),
+				})
+				continue
+			}
+
+			if ref.XGoogleProperty.Type != r.wantType {
+				errs = append(errs, referenceError{
+					display: true,
+					field:   refField,
+					msg:     fmt.Sprintf("display variable %q references variable %q in %s which must be of type %s, found %q", n, r.variable, r.field, r.wantType, ref.XGoogleProperty.Type),
+				})
+			}
+		}
+	}
+
+	// runtime outputs in display metadata
+	coreOutputs := make(map[string]bool)
+	for _, o := range core.Spec.Interfaces.Outputs {
+		coreOutputs[o.Name] = true
+	}
+
+	dispOutputs := disp.Spec.UI.Runtime.Outputs
+	names = nil
+	for n := range dispOutputs {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		do := dispOutputs[n]
+		if do == nil {
+			continue
+		}
+
+		outField := []string{"spec", "ui", "runtime", "outputs", n}
+		if !coreOutputs[n] {
+			errs = append(errs, referenceError{
+				display: true,
+				field:   outField,
+				msg:     fmt.Sprintf("display output %q does not exist in core metadata", n),
+			})
+		}
+
+		if do.Name != "" && do.Name != n {
+			errs = append(errs, referenceError{
+				display: true,
+				field:   append(outField, "name"),
+				msg:     fmt.Sprintf("display output %q has a mismatched name %q", n, do.Name),
+			})
+		}
+	}
+
+	return errs
+}
+
+// extensionRefs returns all references to other variables that are
+// set on an extension
+func extensionRefs(ext GooglePropertyExtension) []extensionRef {
+	candidates := []extensionRef{
+		{"xGoogleProperty.zoneProperty", ext.ZoneProperty, GCEZone},
+		{"xGoogleProperty.gceDiskSize.diskTypeVariable", ext.GCEDiskSize.DiskTypeVariable, GCEDiskType},
+		{"xGoogleProperty.gceSubnetwork.networkVariable", ext.GCESubnetwork.NetworkVariable, GCENetwork},
+		{"xGoogleProperty.gceResource.resourceVariable", ext.GCEResource.ResourceVariable, GCEGenericResource},
+		{"xGoogleProperty.gceGpuCount.machineTypeVariable", ext.GCEGPUCount.MachineTypeVariable, GCEMachineType},
+		{"xGoogleProperty.gceNetwork.machineTypeVariable", ext.GCENetwork.MachineTypeVariable, GCEMachineType},
+		{"xGoogleProperty.gceExternalIp.networkVariable", ext.GCEExternalIP.NetworkVariable, GCENetwork},
+		{"xGoogleProperty.gceIpForwarding.networkVariable", ext.GCEIPForwarding.NetworkVariable, GCENetwork},
+		{"xGoogleProperty.gceFirewall.networkVariable", ext.GCEFirewall.NetworkVariable, GCENetwork},
+		{"xGoogleProperty.gceFirewallRange.firewallVariable", ext.GCEFirewallRange.FirewallVariable, GCEFirewall},
+	}
+
+	var refs []extensionRef
+	for _, c := range candidates {
+		if c.variable != "" {
+			refs = append(refs, c)
+		}
+	}
+
+	return refs
+}
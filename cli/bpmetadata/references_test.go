@@ -0,0 +1,152 @@
+package bpmetadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateReferences(t *testing.T) {
+	core := &BlueprintMetadata{
+		Spec: BlueprintMetadataSpec{
+			Interfaces: BlueprintInterface{
+				Variables: []BlueprintVariable{
+					{Name: "zone"},
+					{Name: "region"},
+					{Name: "machine_type"},
+					{Name: "network"},
+					{Name: "subnetwork"},
+				},
+				Outputs: []BlueprintOutput{
+					{Name: "console_link"},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		dispVars   map[string]*DisplayVariable
+		sections   []DisplaySection
+		groups     []BlueprintVariableGroup
+		quotas     []BlueprintQuotaDetail
+		outputs    map[string]*DisplayOutput
+		wantErrors []string
+	}{
+		{
+			name: "valid references",
+			dispVars: map[string]*DisplayVariable{
+				"zone": {Name: "zone", XGoogleProperty: GooglePropertyExtension{Type: GCEZone}},
+				"machine_type": {Name: "machine_type", Section: "compute", XGoogleProperty: GooglePropertyExtension{
+					Type:         GCEMachineType,
+					ZoneProperty: "zone",
+				}},
+				"network": {Name: "network", XGoogleProperty: GooglePropertyExtension{Type: GCENetwork}},
+				"subnetwork": {Name: "subnetwork", XGoogleProperty: GooglePropertyExtension{
+					Type:          GCESubnetwork,
+					GCESubnetwork: GCESubnetworkExtension{NetworkVariable: "network"},
+				}},
+			},
+			sections: []DisplaySection{{Name: "compute"}},
+			groups:   []BlueprintVariableGroup{{Name: "location", Variables: []string{"zone", "region"}}},
+			quotas:   []BlueprintQuotaDetail{{DynamicVariable: "machine_type", ResourceType: QuotaResTypeGCEInstance}},
+			outputs:  map[string]*DisplayOutput{"console_link": {Name: "console_link", Type: DisplayOutputLink}},
+		},
+		{
+			name: "unknown display variable",
+			dispVars: map[string]*DisplayVariable{
+				"zone":     {Name: "zone"},
+				"old_zone": {Name: "old_zone"},
+			},
+			wantErrors: []string{`display variable "old_zone" does not exist in core metadata`},
+		},
+		{
+			name: "mismatched display variable name",
+			dispVars: map[string]*DisplayVariable{
+				"zone": {Name: "region"},
+			},
+			wantErrors: []string{`display variable "zone" has a mismatched name "region"`},
+		},
+		{
+			name: "unknown variable in group and quota",
+			groups: []BlueprintVariableGroup{
+				{Name: "location", Variables: []string{"zone", "location"}},
+			},
+			quotas: []BlueprintQuotaDetail{
+				{DynamicVariable: "instance_count", ResourceType: QuotaResTypeGCEInstance},
+			},
+			wantErrors: []string{
+				`variable group "location" references unknown variable "location"`,
+				`quota detail for QRT_GCE_INSTANCE references unknown dynamic variable "instance_count"`,
+			},
+		},
+		{
+			name: "unknown sections",
+			dispVars: map[string]*DisplayVariable{
+				"zone": {Name: "zone", Section: "location"},
+			},
+			sections: []DisplaySection{{Name: "compute", Parent: "advanced"}},
+			wantErrors: []string{
+				`section "compute" references unknown parent section "advanced"`,
+				`display variable "zone" references unknown section "location"`,
+			},
+		},
+		{
+			name: "dangling and mistyped extension references",
+			dispVars: map[string]*DisplayVariable{
+				"region": {Name: "region", XGoogleProperty: GooglePropertyExtension{Type: GCERegion}},
+				"machine_type": {Name: "machine_type", XGoogleProperty: GooglePropertyExtension{
+					Type:         GCEMachineType,
+					ZoneProperty: "region",
+				}},
+				"subnetwork": {Name: "subnetwork", XGoogleProperty: GooglePropertyExtension{
+					Type:          GCESubnetwork,
+					GCESubnetwork: GCESubnetworkExtension{NetworkVariable: "network"},
+				}},
+			},
+			wantErrors: []string{
+				`display variable "machine_type" references variable "region" in xGoogleProperty.zoneProperty which must be of type ET_GCE_ZONE, found "ET_GCE_REGION"`,
+				`display variable "subnetwork" references unknown variable "network" in xGoogleProperty.gceSubnetwork.networkVariable`,
+			},
+		},
+		{
+			name: "unknown and mismatched display outputs",
+			outputs: map[string]*DisplayOutput{
+				"console_link": {Name: "console_url"},
+				"vm_ip":        {Name: "vm_ip"},
+			},
+			wantErrors: []string{
+				`display output "console_link" has a mismatched name "console_url"`,
+				`display output "vm_ip" does not exist in core metadata`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := *core
+			c.Spec.Interfaces.VariableGroups = tt.groups
+			c.Spec.Info.QuotaDetails = tt.quotas
+			disp := &BlueprintMetadata{
+				Spec: BlueprintMetadataSpec{
+					UI: BlueprintUI{
+						Input: BlueprintUIInput{
+							Variables: tt.dispVars,
+							Sections:  tt.sections,
+						},
+						Runtime: BlueprintUIOutput{
+							Outputs: tt.outputs,
+						},
+					},
+				},
+			}
+
+			var got []string
+			for _, e := range validateReferences(&c, disp) {
+				got = append(got, e.Error())
+			}
+
+			assert.Equal(t, tt.wantErrors, got)
+		})
+	}
+}
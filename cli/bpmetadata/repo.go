@@ -1,10 +1,15 @@
 package bpmetadata
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path"
+	"regexp"
 	"strings"
 
 	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/cli/util"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
 type repoDetail struct {
@@ -16,46 +21,223 @@ type repoSource struct {
 	Path       string
 	RootPath   string
 	SourceType string
+	Ref        string
+
+	// Dir is the directory of the blueprint in the source,
+	// if it isn't at the root.
+	Dir string
 }
 
 const (
-	nestedBpPath = "/modules"
+	sourceTypeGit      = "git"
+	sourceTypeRegistry = "registry"
+	sourceTypeOCI      = "oci"
+
+	kptFileName = "Kptfile"
 )
 
+// versionTagRegEx matches refs that are version tags e.g. v1.2.0
+var versionTagRegEx = regexp.MustCompile(`^v?[0-9]+(\.[0-9]+)*`)
+
+// kptFile holds the upstream lock of a package fetched with kpt
+type kptFile struct {
+	UpstreamLock *struct {
+		Type string `yaml:"type"`
+		Git  *struct {
+			Repo      string `yaml:"repo"`
+			Directory string `yaml:"directory"`
+			Ref       string `yaml:"ref"`
+			Commit    string `yaml:"commit"`
+		} `yaml:"git"`
+		OCI *struct {
+			Image string `yaml:"image"`
+		} `yaml:"oci"`
+	} `yaml:"upstreamLock"`
+}
+
 // getRepoDetailsByPath takes a local path for a blueprint and tries
-// to get repo details that include its name, path and type
-func getRepoDetailsByPath(bpPath string, sourceUrl *BlueprintRepoDetail) (*repoDetail, error) {
-	rootRepoPath := getBpRootPath(bpPath)
-	if sourceUrl == nil {
+// to get repo details that include its name, path, type and ref. Packages
+// fetched with kpt are described by the upstream in their Kptfile, else
+// by the git repo they are in.
+func getRepoDetailsByPath(bpPath string, sourceUrl *BlueprintRepoDetail, modulesPath string) (*repoDetail, error) {
+	rootRepoPath := getBpRootPath(bpPath, modulesPath)
+	kptSrc, err := getKptSource(rootRepoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var src repoSource
+	switch {
+	case sourceUrl != nil:
+		src = repoSource{
+			Path:       sourceUrl.Repo,
+			SourceType: sourceUrl.SourceType,
+			Ref:        sourceUrl.Ref,
+		}
+	case kptSrc != nil:
+		src = *kptSrc
+	default:
 		bpPath = strings.TrimSuffix(bpPath, "/")
 		repoUrl, err := util.GetRepoUrl(bpPath)
 		if err != nil {
 			return nil, fmt.Errorf("error getting the repo URL from the provided local repo path: %w", err)
 		}
 
-		sourceUrl = &BlueprintRepoDetail{
-			Repo: repoUrl,
+		src = repoSource{
+			Path: repoUrl,
+		}
+	}
+
+	if src.SourceType == "" {
+		src.SourceType = sourceTypeGit
+	}
+
+	// refs are refreshed on every run so that metadata pins the source
+	// it was generated from
+	switch {
+	case kptSrc != nil && kptSrc.SourceType == src.SourceType:
+		src.Ref, src.Dir = kptSrc.Ref, kptSrc.Dir
+	case src.SourceType == sourceTypeGit:
+		if ref, err := gitTags.RepoRef(bpPath); err == nil {
+			src.Ref = ref
 		}
+	case src.SourceType == sourceTypeOCI && src.Ref == "":
+		_, src.Ref = splitOCIRef(src.Path)
 	}
 
-	repoName, err := util.GetRepoName(sourceUrl.Repo)
+	repoName, err := getSourceName(src.Path, src.SourceType)
 	if err != nil {
 		return nil, fmt.Errorf("error getting the repo name from the provided local repo path: %w", err)
 	}
 
+	src.RootPath = rootRepoPath
 	return &repoDetail{
-		Name: repoName,
-		Source: &repoSource{
-			Path:       sourceUrl.Repo,
-			SourceType: "git",
-			RootPath:   rootRepoPath,
-		},
+		Name:   repoName,
+		Source: &src,
 	}, nil
 }
 
+// getSourceRepoUrl returns the URL of the git repo the blueprint is
+// developed in, which is the source itself for git sources. Registry and
+// OCI sources are resolved to the repo at bpPath and registry sources
+// otherwise to the repo they are published from.
+func getSourceRepoUrl(bpPath string, src *repoSource) string {
+	if src.SourceType == sourceTypeGit {
+		return src.Path
+	}
+
+	if repoUrl, err := util.GetRepoUrl(strings.TrimSuffix(bpPath, "/")); err == nil {
+		return repoUrl
+	}
+
+	if src.SourceType == sourceTypeRegistry {
+		if repo, _ := parseModuleSource(src.Path); repo != "" {
+			return "https://" + repo
+		}
+	}
+
+	return ""
+}
+
+// getKptSource returns the source from the upstream lock of the Kptfile
+// at rootPath, if there is one
+func getKptSource(rootPath string) (*repoSource, error) {
+	b, err := os.ReadFile(path.Join(rootPath, kptFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", kptFileName, err)
+	}
+
+	k := kptFile{}
+	if err := yaml.Unmarshal(b, &k); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", kptFileName, err)
+	}
+
+	lock := k.UpstreamLock
+	switch {
+	case lock == nil:
+		return nil, nil
+	case lock.Git != nil:
+		repoUrl, err := util.NormalizeRepoUrl(lock.Git.Repo)
+		if err != nil {
+			return nil, err
+		}
+
+		// prefer version tags and otherwise pin the commit since the
+		// ref may be a branch
+		ref := lock.Git.Ref
+		if lock.Git.Commit != "" && !versionTagRegEx.MatchString(ref) {
+			ref = lock.Git.Commit
+		}
+
+		return &repoSource{
+			Path:       repoUrl,
+			SourceType: sourceTypeGit,
+			Ref:        ref,
+			Dir:        strings.Trim(lock.Git.Directory, "/"),
+		}, nil
+	case lock.OCI != nil:
+		image, ref := splitOCIRef(lock.OCI.Image)
+		return &repoSource{
+			Path:       image,
+			SourceType: sourceTypeOCI,
+			Ref:        ref,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// getSourceName returns the name for a blueprint from its source
+func getSourceName(source, sourceType string) (string, error) {
+	switch sourceType {
+	case sourceTypeGit:
+		return util.GetRepoName(source)
+	case sourceTypeRegistry:
+		// <namespace>/<name>/<provider> with an optional hostname
+		parts := strings.Split(strings.Split(source, "//")[0], "/")
+		if len(parts) == 4 {
+			parts = parts[1:]
+		}
+
+		if len(parts) != 3 {
+			return "", fmt.Errorf("malformed registry address: %s", source)
+		}
+
+		// registry modules are published from terraform-<provider>-<name> repos
+		return fmt.Sprintf("terraform-%s-%s", parts[2], parts[1]), nil
+	case sourceTypeOCI:
+		image, _ := splitOCIRef(source)
+		return image[strings.LastIndex(image, "/")+1:], nil
+	}
+
+	return "", fmt.Errorf("unsupported source type: %s. Supported types are: %s, %s, %s", sourceType, sourceTypeGit, sourceTypeRegistry, sourceTypeOCI)
+}
+
+// splitOCIRef splits an OCI reference into the image and its
+// tag or digest
+func splitOCIRef(image string) (string, string) {
+	image = strings.TrimPrefix(image, "oci://")
+	if i := strings.LastIndex(image, "@"); i != -1 {
+		return image[:i], image[i+1:]
+	}
+
+	// tags are separated by a colon in the last path segment
+	// since the registry host may have a port
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+
+	return image, ""
+}
+
 // getBpRootPath determines if the provided bpPath is for a submodule
 // and resolves it to the root module path if necessary
-func getBpRootPath(bpPath string) string {
+func getBpRootPath(bpPath, modulesPath string) string {
+	nestedBpPath := "/" + modulesPath
 	if strings.Contains(bpPath, nestedBpPath) {
 		i := strings.Index(bpPath, nestedBpPath)
 		bpPath = bpPath[0:i]
@@ -65,11 +247,12 @@ func getBpRootPath(bpPath string) string {
 }
 
 // getBpSubmoduleName gets the submodule name from the blueprint path
-// if it lives under the /modules directory
-func getBpSubmoduleName(bpPath string) string {
+// if it lives under the modules directory
+func getBpSubmoduleName(bpPath, modulesPath string) string {
+	nestedBpPath := "/" + modulesPath + "/"
 	if strings.Contains(bpPath, nestedBpPath) {
 		i := strings.Index(bpPath, nestedBpPath)
-		return bpPath[i+9:]
+		return bpPath[i+len(nestedBpPath):]
 	}
 
 	return ""
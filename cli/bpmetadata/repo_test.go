@@ -1,7 +1,14 @@
 package bpmetadata
 
 import (
+	"os"
+	"path"
 	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetBpRootPath(t *testing.T) {
@@ -44,7 +51,7 @@ func TestGetBpRootPath(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := getBpRootPath(tt.path)
+			got := getBpRootPath(tt.path, modulesPath)
 			if got != tt.want {
 				t.Errorf("getBpRootPath() = %v, want %v", got, tt.want)
 			}
@@ -77,10 +84,279 @@ func TestGetBpSubmoduleName(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := getBpSubmoduleName(tt.path)
+			got := getBpSubmoduleName(tt.path, modulesPath)
 			if got != tt.want {
 				t.Errorf("getBpSubmoduleName() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestGetSourceName(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     string
+		sourceType string
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:       "git",
+			source:     "git@gitlab.com:foo/bar/terraform-google-bp.git",
+			sourceType: sourceTypeGit,
+			want:       "terraform-google-bp",
+		},
+		{
+			name:       "registry",
+			source:     "terraform-google-modules/cloud-storage/google",
+			sourceType: sourceTypeRegistry,
+			want:       "terraform-google-cloud-storage",
+		},
+		{
+			name:       "registry with host and submodule",
+			source:     "registry.terraform.io/terraform-google-modules/cloud-storage/google//modules/simple_bucket",
+			sourceType: sourceTypeRegistry,
+			want:       "terraform-google-cloud-storage",
+		},
+		{
+			name:       "malformed registry",
+			source:     "cloud-storage/google",
+			sourceType: sourceTypeRegistry,
+			wantErr:    true,
+		},
+		{
+			name:       "oci with tag and port",
+			source:     "oci://localhost:5000/foo/bp:v1.0.0",
+			sourceType: sourceTypeOCI,
+			want:       "bp",
+		},
+		{
+			name:       "unknown type",
+			source:     "foo",
+			sourceType: "svn",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getSourceName(tt.source, tt.sourceType)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSplitOCIRef(t *testing.T) {
+	tests := []struct {
+		image     string
+		wantImage string
+		wantRef   string
+	}{
+		{"us-docker.pkg.dev/foo/bps/bp:v1.0.0", "us-docker.pkg.dev/foo/bps/bp", "v1.0.0"},
+		{"oci://localhost:5000/bp@sha256:abc", "localhost:5000/bp", "sha256:abc"},
+		{"localhost:5000/bp", "localhost:5000/bp", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			image, ref := splitOCIRef(tt.image)
+			assert.Equal(t, tt.wantImage, image)
+			assert.Equal(t, tt.wantRef, ref)
+		})
+	}
+}
+
+func TestGetKptSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		kptfile string
+		want    *repoSource
+	}{
+		{
+			name: "no upstream",
+			kptfile: `apiVersion: kpt.dev/v1
+kind: Kptfile
+metadata:
+  name: bp
+`,
+		},
+		{
+			name: "git tag",
+			kptfile: `apiVersion: kpt.dev/v1
+kind: Kptfile
+upstreamLock:
+  type: git
+  git:
+    repo: git@github.com:foo/terraform-google-bp
+    directory: /
+    ref: v1.2.0
+    commit: 0123abcd
+`,
+			want: &repoSource{
+				Path:       "https://github.com/foo/terraform-google-bp",
+				SourceType: sourceTypeGit,
+				Ref:        "v1.2.0",
+			},
+		},
+		{
+			name: "git branch in dir",
+			kptfile: `apiVersion: kpt.dev/v1
+kind: Kptfile
+upstreamLock:
+  type: git
+  git:
+    repo: https://github.com/foo/bps
+    directory: /blueprints/bp
+    ref: main
+    commit: 0123abcd
+`,
+			want: &repoSource{
+				Path:       "https://github.com/foo/bps",
+				SourceType: sourceTypeGit,
+				Ref:        "0123abcd",
+				Dir:        "blueprints/bp",
+			},
+		},
+		{
+			name: "oci",
+			kptfile: `apiVersion: kpt.dev/v1
+kind: Kptfile
+upstreamLock:
+  type: oci
+  oci:
+    image: us-docker.pkg.dev/foo/bps/bp@sha256:abc
+`,
+			want: &repoSource{
+				Path:       "us-docker.pkg.dev/foo/bps/bp",
+				SourceType: sourceTypeOCI,
+				Ref:        "sha256:abc",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(path.Join(dir, kptFileName), []byte(tt.kptfile), 0644))
+			got, err := getKptSource(dir)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetRepoDetailsByPath(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    *BlueprintRepoDetail
+		kptfile   string
+		wantName  string
+		wantSrc   repoSource
+		wantError bool
+	}{
+		{
+			name:     "manual registry source",
+			source:   &BlueprintRepoDetail{Repo: "terraform-google-modules/cloud-storage/google", SourceType: sourceTypeRegistry, Ref: "4.0.0"},
+			wantName: "terraform-google-cloud-storage",
+			wantSrc: repoSource{
+				Path:       "terraform-google-modules/cloud-storage/google",
+				SourceType: sourceTypeRegistry,
+				Ref:        "4.0.0",
+			},
+		},
+		{
+			name: "kpt package",
+			kptfile: `upstreamLock:
+  type: git
+  git:
+    repo: https://github.com/foo/terraform-google-bp
+    directory: /
+    ref: v1.0.0
+    commit: 0123abcd
+`,
+			wantName: "terraform-google-bp",
+			wantSrc: repoSource{
+				Path:       "https://github.com/foo/terraform-google-bp",
+				SourceType: sourceTypeGit,
+				Ref:        "v1.0.0",
+			},
+		},
+		{
+			name:      "no git repo",
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.kptfile != "" {
+				require.NoError(t, os.WriteFile(path.Join(dir, kptFileName), []byte(tt.kptfile), 0644))
+			}
+
+			got, err := getRepoDetailsByPath(dir, tt.source, modulesPath)
+			if tt.wantError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			tt.wantSrc.RootPath = dir
+			assert.Equal(t, tt.wantName, got.Name)
+			assert.Equal(t, &tt.wantSrc, got.Source)
+		})
+	}
+}
+
+func TestGetSourceRepoUrl(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     repoSource
+		gitRepo string
+		want    string
+	}{
+		{
+			name: "git",
+			src:  repoSource{Path: "https://github.com/foo/terraform-google-bp", SourceType: sourceTypeGit},
+			want: "https://github.com/foo/terraform-google-bp",
+		},
+		{
+			name:    "registry in a git repo",
+			src:     repoSource{Path: "foo/bp/google", SourceType: sourceTypeRegistry},
+			gitRepo: "https://gitlab.com/foo/terraform-google-bp",
+			want:    "https://gitlab.com/foo/terraform-google-bp",
+		},
+		{
+			name: "registry",
+			src:  repoSource{Path: "registry.terraform.io/foo/bp/google", SourceType: sourceTypeRegistry},
+			want: "https://github.com/foo/terraform-google-bp",
+		},
+		{
+			name: "oci",
+			src:  repoSource{Path: "us-docker.pkg.dev/foo/bps/bp", SourceType: sourceTypeOCI},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.gitRepo != "" {
+				dir = tempGitRepo(t, "")
+				r, err := git.PlainOpen(dir)
+				require.NoError(t, err)
+
+				_, err = r.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{tt.gitRepo}})
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.want, getSourceRepoUrl(dir, &tt.src))
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package bpmetadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const (
+	formatText  = "text"
+	formatJSON  = "json"
+	formatSARIF = "sarif"
+
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName     = "cft"
+	toolInfoURI  = "https://github.com/GoogleCloudPlatform/cloud-foundation-toolkit"
+
+	levelError   = "error"
+	levelWarning = "warning"
+)
+
+// validationFinding is a single validation error for a metadata file
+// along with the position of the offending YAML node.
+type validationFinding struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+
+	// Level is the severity of the finding. Findings are errors
+	// unless set otherwise.
+	Level string `json:"level,omitempty"`
+}
+
+// location formats the finding's position as file:line:col for logs
+func (f validationFinding) location() string {
+	if f.Line == 0 {
+		return f.File
+	}
+
+	return fmt.Sprintf("%s:%d:%d", f.File, f.Line, f.Column)
+}
+
+// validateFormat checks that the provided output format is supported
+func validateFormat(format string) error {
+	switch format {
+	case formatText, formatJSON, formatSARIF:
+		return nil
+	}
+
+	return fmt.Errorf("unsupported output format: %s. Supported formats are: %s, %s, %s", format, formatText, formatJSON, formatSARIF)
+}
+
+// fieldPosition returns the line and column for the deepest YAML node
+// in the metadata file at path "m" that matches the field path
+func fieldPosition(m string, field []string) (int, int) {
+	b, err := os.ReadFile(m)
+	if err != nil {
+		return 0, 0
+	}
+
+	rn, err := yaml.Parse(string(b))
+	if err != nil {
+		return 0, 0
+	}
+
+	return nodePosition(rn.YNode(), field)
+}
+
+// nodePosition walks a YAML node along the field path, where sequence
+// items are referenced by their index, and returns the position of the
+// deepest node found. Mapping values are reported at their key's position.
+func nodePosition(n *yaml.Node, field []string) (int, int) {
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+
+	line, col := n.Line, n.Column
+	for _, f := range field {
+		switch n.Kind {
+		case yaml.MappingNode:
+			var next *yaml.Node
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == f {
+					line, col = n.Content[i].Line, n.Content[i].Column
+					next = n.Content[i+1]
+					break
+				}
+			}
+
+			if next == nil {
+				return line, col
+			}
+
+			n = next
+		case yaml.SequenceNode:
+			i, err := strconv.Atoi(f)
+			if err != nil || i < 0 || i >= len(n.Content) {
+				return line, col
+			}
+
+			n = n.Content[i]
+			line, col = n.Line, n.Column
+		default:
+			return line, col
+		}
+	}
+
+	return line, col
+}
+
+// writeFindings writes all findings to w in the requested format.
+// Nothing is written for the text format since findings are logged
+// as they are found.
+func writeFindings(w io.Writer, findings []validationFinding, format, wdPath string) error {
+	// report paths relative to the working dir so that they can be
+	// mapped to files in code review
+	rel := make([]validationFinding, 0, len(findings))
+	for _, f := range findings {
+		if p, err := filepath.Rel(wdPath, f.File); err == nil {
+			f.File = p
+		}
+
+		rel = append(rel, f)
+	}
+
+	var out interface{}
+	switch format {
+	case formatJSON:
+		out = struct {
+			Findings []validationFinding `json:"findings"`
+		}{rel}
+	case formatSARIF:
+		out = toSARIF(rel)
+	default:
+		return nil
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// toSARIF converts findings to a SARIF log with a single run
+func toSARIF(findings []validationFinding) sarifLog {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:           toolName,
+				InformationURI: toolInfoURI,
+			},
+		},
+		Results: []sarifResult{},
+	}
+
+	rules := make(map[string]bool)
+	for _, f := range findings {
+		if !rules[f.Rule] {
+			rules[f.Rule] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: f.Rule})
+		}
+
+		loc := sarifLocation{
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(f.File)},
+			},
+		}
+
+		if f.Line > 0 {
+			loc.PhysicalLocation.Region = &sarifRegion{
+				StartLine:   f.Line,
+				StartColumn: f.Column,
+			}
+		}
+
+		msg := f.Message
+		if f.Field != "" {
+			msg = fmt.Sprintf("%s: %s", f.Field, f.Message)
+		}
+
+		level := f.Level
+		if level == "" {
+			level = levelError
+		}
+
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    f.Rule,
+			Level:     level,
+			Message:   sarifMessage{Text: msg},
+			Locations: []sarifLocation{loc},
+		})
+	}
+
+	return sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{run},
+	}
+}
@@ -0,0 +1,128 @@
+package bpmetadata
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func TestSchemaFindings(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		wantRule     string
+		wantLine     int
+		wantColumn   int
+		wantFindings int
+	}{
+		{
+			name:         "missing required property",
+			path:         "invalid-metadata.yaml",
+			wantRule:     "schema/required",
+			wantLine:     6,
+			wantColumn:   3,
+			wantFindings: 1,
+		},
+		{
+			name:         "unknown property in a list item",
+			path:         "invalid-metadata-w-enum.yaml",
+			wantRule:     "schema/additional_property_not_allowed",
+			wantLine:     12,
+			wantColumn:   7,
+			wantFindings: 2,
+		},
+	}
+
+	s := gojsonschema.NewReferenceLoader("file://schema/bpmetadataschema.json")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateMetadataYaml(path.Join(yamlTestDirPath, tt.path), s)
+			require.Error(t, err)
+			require.Len(t, got, tt.wantFindings)
+
+			var found bool
+			for _, f := range got {
+				if f.Rule != tt.wantRule {
+					continue
+				}
+
+				found = true
+				assert.Equal(t, tt.wantLine, f.Line)
+				assert.Equal(t, tt.wantColumn, f.Column)
+			}
+
+			assert.True(t, found, "no finding for rule %s in %v", tt.wantRule, got)
+		})
+	}
+}
+
+func TestWriteFindings(t *testing.T) {
+	findings := []validationFinding{
+		{
+			File:    "/bp/metadata.yaml",
+			Line:    12,
+			Column:  7,
+			Field:   "spec.info",
+			Rule:    "schema/required",
+			Message: "source is required",
+		},
+		{
+			File:    "/bp/modules/foo/metadata.display.yaml",
+			Field:   "spec.ui.input.variables.zone",
+			Rule:    "reference",
+			Message: "display variable \"zone\" does not exist in core metadata",
+		},
+	}
+
+	t.Run("text", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, writeFindings(&b, findings, formatText, "/bp"))
+		assert.Empty(t, b.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, writeFindings(&b, findings, formatJSON, "/bp"))
+
+		var got struct {
+			Findings []validationFinding `json:"findings"`
+		}
+		require.NoError(t, json.Unmarshal(b.Bytes(), &got))
+		require.Len(t, got.Findings, 2)
+		assert.Equal(t, "metadata.yaml", got.Findings[0].File)
+		assert.Equal(t, 12, got.Findings[0].Line)
+		assert.Equal(t, "modules/foo/metadata.display.yaml", got.Findings[1].File)
+	})
+
+	t.Run("sarif", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, writeFindings(&b, findings, formatSARIF, "/bp"))
+
+		var got sarifLog
+		require.NoError(t, json.Unmarshal(b.Bytes(), &got))
+		assert.Equal(t, sarifVersion, got.Version)
+		require.Len(t, got.Runs, 1)
+		assert.Len(t, got.Runs[0].Tool.Driver.Rules, 2)
+		require.Len(t, got.Runs[0].Results, 2)
+
+		r := got.Runs[0].Results[0]
+		assert.Equal(t, "schema/required", r.RuleID)
+		assert.Equal(t, "spec.info: source is required", r.Message.Text)
+		assert.Equal(t, "metadata.yaml", r.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+		assert.Equal(t, &sarifRegion{StartLine: 12, StartColumn: 7}, r.Locations[0].PhysicalLocation.Region)
+		assert.Nil(t, got.Runs[0].Results[1].Locations[0].PhysicalLocation.Region)
+	})
+}
+
+func TestValidateFormat(t *testing.T) {
+	for _, f := range []string{formatText, formatJSON, formatSARIF} {
+		assert.NoError(t, validateFormat(f))
+	}
+
+	assert.Error(t, validateFormat("xml"))
+}
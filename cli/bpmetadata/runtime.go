@@ -0,0 +1,229 @@
+package bpmetadata
+
+import (
+	"regexp"
+	"strings"
+
+	hcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// nameOutputTypes maps output name patterns to display output types.
+// Patterns are evaluated in order and the first match wins. Links are only
+// inferred from values since names such as self_link or url are also used
+// for API and gs:// URLs.
+var nameOutputTypes = []struct {
+	re  *regexp.Regexp
+	typ DisplayOutputType
+}{
+	{regexp.MustCompile(`^(.+_)?ssh(_command|_cmd)?$`), DisplayOutputSSHCommand},
+	{regexp.MustCompile(`^(.+_)?(ip|ip_address|ip_addr)$`), DisplayOutputIPAddress},
+}
+
+// descriptionOutputTypes maps output description patterns to display
+// output types. Patterns are evaluated in order and the first match wins.
+var descriptionOutputTypes = []struct {
+	re  *regexp.Regexp
+	typ DisplayOutputType
+}{
+	{regexp.MustCompile(`(?i)\bssh\b.*\bcommand\b|\bcommand\b.*\bssh\b`), DisplayOutputSSHCommand},
+	{regexp.MustCompile(`(?i)\bip( address)?\b`), DisplayOutputIPAddress},
+}
+
+// reSelfLink matches the names of outputs holding API URLs of resources
+var reSelfLink = regexp.MustCompile(`^(.+_)?self_link$`)
+
+// attrOutputTypes maps the last attribute of a resource reference used
+// as an output value to a display output type
+var attrOutputTypes = map[string]DisplayOutputType{
+	"nat_ip":     DisplayOutputIPAddress,
+	"network_ip": DisplayOutputIPAddress,
+	"ip_address": DisplayOutputIPAddress,
+	"address":    DisplayOutputIPAddress,
+}
+
+// linkResourceAttrs are the attributes of resources that hold http(s) URLs
+// that can be opened in a browser, keyed by resource type
+var linkResourceAttrs = map[string]map[string]bool{
+	"google_cloud_run_service":        {"url": true},
+	"google_cloud_run_v2_service":     {"uri": true},
+	"google_cloudfunctions_function":  {"https_trigger_url": true},
+	"google_cloudfunctions2_function": {"url": true, "uri": true},
+}
+
+// maxLocalDepth limits how many locals are followed to resolve a value
+const maxLocalDepth = 5
+
+// buildUIOutputFromOutputs adds a display output for each output that is
+// a link, IP address or SSH command. The type is inferred from the output
+// name, then its value expression and then its description. Sensitive
+// outputs are never shown and existing display outputs are kept as is.
+func buildUIOutputFromOutputs(outputs []BlueprintOutput, details *interfaceDetails, runtime *BlueprintUIOutput) {
+	for _, o := range outputs {
+		if _, hasDisplayOutput := runtime.Outputs[o.Name]; hasDisplayOutput || o.Sensitive {
+			continue
+		}
+
+		t := getOutputType(o, details.outputValues[o.Name], details.localValues)
+		if t == DisplayOutputUndefined {
+			continue
+		}
+
+		if runtime.Outputs == nil {
+			runtime.Outputs = make(map[string]*DisplayOutput)
+		}
+
+		runtime.Outputs[o.Name] = &DisplayOutput{
+			Name:               o.Name,
+			Title:              createTitleFromName(o.Name),
+			Type:               t,
+			OpenInNewTab:       t == DisplayOutputLink,
+			ShowInNotification: t == DisplayOutputLink || t == DisplayOutputSSHCommand,
+		}
+	}
+}
+
+// getOutputType infers how an output is displayed from its name, value
+// expression and description, in that order. Outputs are only links if
+// their value is an http(s) URL.
+func getOutputType(o BlueprintOutput, value hclsyntax.Expression, locals map[string]hclsyntax.Expression) DisplayOutputType {
+	for _, n := range nameOutputTypes {
+		if n.re.MatchString(o.Name) {
+			return n.typ
+		}
+	}
+
+	t := getValueOutputType(value, locals, 0)
+	if t == DisplayOutputLink && reSelfLink.MatchString(o.Name) {
+		return DisplayOutputUndefined
+	}
+
+	if t != DisplayOutputUndefined {
+		return t
+	}
+
+	for _, d := range descriptionOutputTypes {
+		if d.re.MatchString(o.Description) {
+			return d.typ
+		}
+	}
+
+	return DisplayOutputUndefined
+}
+
+// getValueOutputType infers the display output type from an output value
+// that is a string template e.g. "https://${...}", a reference to a
+// resource attribute e.g. google_cloud_run_service.svc.status[0].url or a
+// local with one of those as its value
+func getValueOutputType(expr hclsyntax.Expression, locals map[string]hclsyntax.Expression, depth int) DisplayOutputType {
+	switch e := expr.(type) {
+	case *hclsyntax.TemplateExpr:
+		return getLiteralOutputType(templatePrefix(e))
+	case *hclsyntax.TemplateWrapExpr:
+		return getValueOutputType(e.Wrapped, locals, depth)
+	case *hclsyntax.FunctionCallExpr:
+		// the format string of format() e.g. format("https://%s", ...)
+		if e.Name == "format" && len(e.Args) > 0 {
+			return getValueOutputType(e.Args[0], locals, depth)
+		}
+	case *hclsyntax.ScopeTraversalExpr:
+		if local, isLocal := localName(e.Traversal); isLocal {
+			if v, found := locals[local]; found && depth < maxLocalDepth {
+				return getValueOutputType(v, locals, depth+1)
+			}
+
+			return DisplayOutputUndefined
+		}
+
+		return getTraversalOutputType(e.Traversal)
+	case *hclsyntax.RelativeTraversalExpr:
+		return getTraversalOutputType(e.Traversal)
+	}
+
+	return DisplayOutputUndefined
+}
+
+// localName returns the name of the local if the traversal refers to a
+// whole local e.g. local.url
+func localName(t hcl.Traversal) (string, bool) {
+	if t.RootName() != "local" || len(t) != 2 {
+		return "", false
+	}
+
+	attr, ok := t[1].(hcl.TraverseAttr)
+	return attr.Name, ok
+}
+
+// templatePrefix returns the literal text a template starts with
+func templatePrefix(t *hclsyntax.TemplateExpr) string {
+	if len(t.Parts) == 0 {
+		return ""
+	}
+
+	lit, isLiteral := t.Parts[0].(*hclsyntax.LiteralValueExpr)
+	if !isLiteral || lit.Val.Type() != cty.String || lit.Val.IsNull() {
+		return ""
+	}
+
+	return lit.Val.AsString()
+}
+
+func getLiteralOutputType(s string) DisplayOutputType {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, "gcloud compute ssh"), strings.HasPrefix(s, "ssh "):
+		return DisplayOutputSSHCommand
+	case strings.HasPrefix(s, "https://"), strings.HasPrefix(s, "http://"):
+		return DisplayOutputLink
+	}
+
+	return DisplayOutputUndefined
+}
+
+// getTraversalOutputType infers the display output type from the last
+// attribute of a reference. References are only links if they are known
+// http(s) URL attributes of their resource type.
+func getTraversalOutputType(t hcl.Traversal) DisplayOutputType {
+	for i := len(t) - 1; i >= 0; i-- {
+		switch step := t[i].(type) {
+		case hcl.TraverseAttr:
+			if linkResourceAttrs[traversalResourceType(t)][step.Name] {
+				return DisplayOutputLink
+			}
+
+			if typ, ok := attrOutputTypes[step.Name]; ok {
+				return typ
+			}
+
+			return DisplayOutputUndefined
+		case hcl.TraverseIndex:
+			continue
+		default:
+			return DisplayOutputUndefined
+		}
+	}
+
+	return DisplayOutputUndefined
+}
+
+// traversalResourceType returns the resource type a reference starts with
+// e.g. google_cloud_run_service for google_cloud_run_service.svc.status[0].url
+// or data.google_cloud_run_service.svc.status[0].url
+func traversalResourceType(t hcl.Traversal) string {
+	if t.IsRelative() || len(t) == 0 {
+		return ""
+	}
+
+	if t.RootName() != "data" {
+		return t.RootName()
+	}
+
+	if len(t) > 1 {
+		if attr, ok := t[1].(hcl.TraverseAttr); ok {
+			return attr.Name
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,197 @@
+package bpmetadata
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const runtimeOutputsTf = `
+output "service_url" {
+  value = google_cloud_run_service.svc.status[0].url
+}
+
+output "console" {
+  value = "https://console.cloud.google.com/run?project=${var.project_id}"
+}
+
+output "connect" {
+  value = format("gcloud compute ssh %s --zone %s", google_compute_instance.vm.name, var.zone)
+}
+
+output "vm_external" {
+  value = google_compute_instance.vm.network_interface[0].access_config[0].nat_ip
+}
+
+output "dashboard" {
+  description = "Link to the monitoring dashboard"
+  value       = local.dashboard
+}
+
+output "docs" {
+  description = "Link to the docs"
+  value       = local.docs
+}
+
+output "bucket_url" {
+  value = google_storage_bucket.bucket.url
+}
+
+output "instance_self_link" {
+  description = "Link to the instance"
+  value       = google_compute_instance.vm.self_link
+}
+
+output "function_url" {
+  value = google_cloudfunctions_function.fn.https_trigger_url
+}
+
+locals {
+  dashboard = "https://console.cloud.google.com/monitoring/dashboards/${var.dashboard_id}"
+  docs      = var.docs_url
+}
+
+output "bucket_name" {
+  description = "Name of the bucket"
+  value       = google_storage_bucket.bucket.name
+}
+
+output "admin_url" {
+  sensitive = true
+  value     = "https://${google_compute_instance.vm.name}/admin"
+}
+`
+
+func TestUIOutputFromOutputs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(dir, "outputs.tf"), []byte(runtimeOutputsTf), 0644))
+	details, err := getInterfaceDetails(dir)
+	require.NoError(t, err)
+
+	outputs := []BlueprintOutput{
+		{Name: "service_url"},
+		{Name: "console"},
+		{Name: "connect"},
+		{Name: "vm_external"},
+		{Name: "dashboard", Description: "Link to the monitoring dashboard"},
+		{Name: "docs", Description: "Link to the docs"},
+		{Name: "bucket_url"},
+		{Name: "instance_self_link", Description: "Link to the instance"},
+		{Name: "function_url"},
+		{Name: "bucket_name", Description: "Name of the bucket"},
+		{Name: "admin_url", Sensitive: true},
+		{Name: "ssh_command"},
+	}
+
+	// hand-written outputs are kept
+	runtime := &BlueprintUIOutput{
+		Outputs: map[string]*DisplayOutput{
+			"ssh_command": {Name: "ssh_command", Title: "Connect to the VM"},
+		},
+	}
+
+	buildUIOutputFromOutputs(outputs, details, runtime)
+	assert.Equal(t, map[string]*DisplayOutput{
+		"service_url":  {Name: "service_url", Title: "Service Url", Type: DisplayOutputLink, OpenInNewTab: true, ShowInNotification: true},
+		"console":      {Name: "console", Title: "Console", Type: DisplayOutputLink, OpenInNewTab: true, ShowInNotification: true},
+		"connect":      {Name: "connect", Title: "Connect", Type: DisplayOutputSSHCommand, ShowInNotification: true},
+		"vm_external":  {Name: "vm_external", Title: "Vm External", Type: DisplayOutputIPAddress},
+		"dashboard":    {Name: "dashboard", Title: "Dashboard", Type: DisplayOutputLink, OpenInNewTab: true, ShowInNotification: true},
+		"function_url": {Name: "function_url", Title: "Function Url", Type: DisplayOutputLink, OpenInNewTab: true, ShowInNotification: true},
+		"ssh_command":  {Name: "ssh_command", Title: "Connect to the VM"},
+	}, runtime.Outputs)
+}
+
+func TestGetOutputType(t *testing.T) {
+	tests := []struct {
+		name   string
+		output BlueprintOutput
+		value  string
+		want   DisplayOutputType
+	}{
+		{
+			name:   "http url",
+			output: BlueprintOutput{Name: "website_url"},
+			value:  `"https://${var.domain}"`,
+			want:   DisplayOutputLink,
+		},
+		{
+			name:   "url name without a value",
+			output: BlueprintOutput{Name: "website_url"},
+			want:   DisplayOutputUndefined,
+		},
+		{
+			name:   "gs url",
+			output: BlueprintOutput{Name: "bucket_url"},
+			value:  `"gs://${google_storage_bucket.bucket.name}"`,
+			want:   DisplayOutputUndefined,
+		},
+		{
+			name:   "self link",
+			output: BlueprintOutput{Name: "network_self_link"},
+			value:  `"https://www.googleapis.com/compute/v1/${google_compute_network.vpc.id}"`,
+			want:   DisplayOutputUndefined,
+		},
+		{
+			name:   "cloud run v2 uri",
+			output: BlueprintOutput{Name: "service"},
+			value:  `data.google_cloud_run_v2_service.svc.uri`,
+			want:   DisplayOutputLink,
+		},
+		{
+			name:   "url attribute of other resources",
+			output: BlueprintOutput{Name: "repo"},
+			value:  `google_sourcerepo_repository.repo.url`,
+			want:   DisplayOutputUndefined,
+		},
+		{
+			name:   "ip suffix",
+			output: BlueprintOutput{Name: "external_ip"},
+			want:   DisplayOutputIPAddress,
+		},
+		{
+			name:   "ssh command",
+			output: BlueprintOutput{Name: "ssh_command"},
+			want:   DisplayOutputSSHCommand,
+		},
+		{
+			name:   "ssh command in description",
+			output: BlueprintOutput{Name: "connect", Description: "Command to SSH into the VM"},
+			want:   DisplayOutputSSHCommand,
+		},
+		{
+			name:   "ip address in description",
+			output: BlueprintOutput{Name: "lb", Description: "The IP address of the load balancer"},
+			want:   DisplayOutputIPAddress,
+		},
+		{
+			name:   "link in description",
+			output: BlueprintOutput{Name: "console", Description: "Link to the console"},
+			value:  `local.console`,
+			want:   DisplayOutputUndefined,
+		},
+		{
+			name:   "no match",
+			output: BlueprintOutput{Name: "project_id", Description: "The project ID"},
+			want:   DisplayOutputUndefined,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value hclsyntax.Expression
+			if tt.value != "" {
+				expr, diags := hclsyntax.ParseExpression([]byte(tt.value), "", hcl.InitialPos)
+				require.False(t, diags.HasErrors(), diags.Error())
+				value = expr
+			}
+
+			assert.Equal(t, tt.want, getOutputType(tt.output, value, nil))
+		})
+	}
+}
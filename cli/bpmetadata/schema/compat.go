@@ -0,0 +1,318 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	changeAdditive = "additive"
+	changeBreaking = "breaking"
+
+	defsRefPrefix = "#/$defs/"
+)
+
+// schemaNode is the subset of JSON schema keywords generated for
+// BlueprintMetadata that is relevant to compatibility
+type schemaNode struct {
+	Ref                  string                 `json:"$ref,omitempty"`
+	Defs                 map[string]*schemaNode `json:"$defs,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Properties           map[string]*schemaNode `json:"properties,omitempty"`
+	PatternProperties    map[string]*schemaNode `json:"patternProperties,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Items                *schemaNode            `json:"items,omitempty"`
+
+	// rejectAll is set for the boolean schema false
+	rejectAll bool
+}
+
+// UnmarshalJSON supports boolean schemas in addition to schema objects.
+// The boolean schema true allows any value like an empty schema.
+func (n *schemaNode) UnmarshalJSON(b []byte) error {
+	var allowAll bool
+	if err := json.Unmarshal(b, &allowAll); err == nil {
+		*n = schemaNode{rejectAll: !allowAll}
+		return nil
+	}
+
+	type node schemaNode
+	return json.Unmarshal(b, (*node)(n))
+}
+
+// schemaChange is a difference between two versions of the schema
+type schemaChange struct {
+	Kind        string
+	Path        string
+	Description string
+}
+
+func (c schemaChange) String() string {
+	return fmt.Sprintf("%s: %s: %s", c.Kind, c.Path, c.Description)
+}
+
+// checkSchemaCompatFile compares the generated schema with the schema
+// at baselinePath and returns the changes between them
+func checkSchemaCompatFile(baselinePath string, generated []byte) ([]schemaChange, error) {
+	b, err := os.ReadFile(baselinePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading baseline schema: %w", err)
+	}
+
+	return compareSchemas(b, generated)
+}
+
+// compareSchemas classifies the changes from the old to the new schema as
+// additive or breaking. A change is breaking if metadata that was valid
+// for the old schema may be invalid for the new one, or if a field that
+// consumers of the old schema rely on is no longer present.
+func compareSchemas(oldData, newData []byte) ([]schemaChange, error) {
+	var oldSchema, newSchema schemaNode
+	if err := json.Unmarshal(oldData, &oldSchema); err != nil {
+		return nil, fmt.Errorf("error parsing old schema: %w", err)
+	}
+
+	if err := json.Unmarshal(newData, &newSchema); err != nil {
+		return nil, fmt.Errorf("error parsing new schema: %w", err)
+	}
+
+	// definitions are only relevant while they are referenced
+	newRefs := make(map[string]bool)
+	collectRefs(&newSchema, newRefs)
+
+	var changes []schemaChange
+	changes = append(changes, compareSchemaNodes("$", &oldSchema, &newSchema)...)
+	for _, name := range sortedDefNames(oldSchema.Defs, newSchema.Defs) {
+		oldDef, newDef := oldSchema.Defs[name], newSchema.Defs[name]
+		defPath := defsRefPrefix + name
+		switch {
+		case newDef == nil && newRefs[defPath]:
+			changes = append(changes, schemaChange{changeBreaking, defPath, "definition removed while still referenced"})
+		case newDef == nil:
+			changes = append(changes, schemaChange{changeAdditive, defPath, "unreferenced definition removed"})
+		case oldDef == nil:
+			changes = append(changes, schemaChange{changeAdditive, defPath, "definition added"})
+		default:
+			changes = append(changes, compareSchemaNodes(defPath, oldDef, newDef)...)
+		}
+	}
+
+	return changes, nil
+}
+
+// collectRefs adds the references in a schema node and the nodes within it
+// to refs
+func collectRefs(n *schemaNode, refs map[string]bool) {
+	if n == nil {
+		return
+	}
+
+	if n.Ref != "" {
+		refs[n.Ref] = true
+	}
+
+	for _, children := range []map[string]*schemaNode{n.Defs, n.Properties, n.PatternProperties} {
+		for _, c := range children {
+			collectRefs(c, refs)
+		}
+	}
+
+	collectRefs(n.Items, refs)
+}
+
+// compareSchemaNodes compares the keywords of a schema node that affect
+// validation. Referenced definitions are compared separately by name.
+func compareSchemaNodes(nodePath string, oldNode, newNode *schemaNode) []schemaChange {
+	var changes []schemaChange
+	if oldNode.rejectAll != newNode.rejectAll {
+		kind := changeAdditive
+		if newNode.rejectAll {
+			kind = changeBreaking
+		}
+
+		changes = append(changes, schemaChange{kind, nodePath, fmt.Sprintf("boolean schema changed to %t", !newNode.rejectAll)})
+	}
+
+	if oldNode.Ref != newNode.Ref {
+		changes = append(changes, schemaChange{changeBreaking, nodePath, fmt.Sprintf("reference changed from %q to %q", oldNode.Ref, newNode.Ref)})
+	}
+
+	if oldNode.Type != newNode.Type {
+		changes = append(changes, schemaChange{changeBreaking, nodePath, fmt.Sprintf("type changed from %q to %q", oldNode.Type, newNode.Type)})
+	}
+
+	changes = append(changes, compareEnums(nodePath, oldNode.Enum, newNode.Enum)...)
+	changes = append(changes, compareAdditionalProperties(nodePath, oldNode.AdditionalProperties, newNode.AdditionalProperties)...)
+
+	oldRequired, newRequired := toSet(oldNode.Required), toSet(newNode.Required)
+	for _, name := range sortedDefNames(oldNode.Properties, newNode.Properties) {
+		propPath := nodePath + "." + name
+		oldProp, newProp := oldNode.Properties[name], newNode.Properties[name]
+		switch {
+		case newProp == nil:
+			changes = append(changes, schemaChange{changeBreaking, propPath, "property removed"})
+			continue
+		case oldProp == nil && newRequired[name]:
+			changes = append(changes, schemaChange{changeBreaking, propPath, "required property added"})
+			continue
+		case oldProp == nil:
+			changes = append(changes, schemaChange{changeAdditive, propPath, "property added"})
+			continue
+		case !oldRequired[name] && newRequired[name]:
+			changes = append(changes, schemaChange{changeBreaking, propPath, "property is now required"})
+		case oldRequired[name] && !newRequired[name]:
+			changes = append(changes, schemaChange{changeAdditive, propPath, "property is no longer required"})
+		}
+
+		changes = append(changes, compareSchemaNodes(propPath, oldProp, newProp)...)
+	}
+
+	for _, pattern := range sortedDefNames(oldNode.PatternProperties, newNode.PatternProperties) {
+		propPath := fmt.Sprintf("%s[%s]", nodePath, pattern)
+		oldProp, newProp := oldNode.PatternProperties[pattern], newNode.PatternProperties[pattern]
+		switch {
+		case newProp == nil:
+			changes = append(changes, schemaChange{changeBreaking, propPath, "pattern property removed"})
+		case oldProp == nil:
+			changes = append(changes, schemaChange{changeBreaking, propPath, "pattern property added"})
+		default:
+			changes = append(changes, compareSchemaNodes(propPath, oldProp, newProp)...)
+		}
+	}
+
+	switch {
+	case oldNode.Items != nil && newNode.Items != nil:
+		changes = append(changes, compareSchemaNodes(nodePath+"[]", oldNode.Items, newNode.Items)...)
+	case oldNode.Items != nil:
+		changes = append(changes, schemaChange{changeBreaking, nodePath + "[]", "item schema removed"})
+	case newNode.Items != nil:
+		changes = append(changes, schemaChange{changeBreaking, nodePath + "[]", "item schema added"})
+	}
+
+	return changes
+}
+
+// compareEnums treats new enum values as additive and removed values or
+// a new restriction to an enum as breaking
+func compareEnums(nodePath string, oldEnum, newEnum []interface{}) []schemaChange {
+	if len(oldEnum) == 0 && len(newEnum) == 0 {
+		return nil
+	}
+
+	if len(oldEnum) == 0 {
+		return []schemaChange{{changeBreaking, nodePath, "values restricted to an enum"}}
+	}
+
+	if len(newEnum) == 0 {
+		return []schemaChange{{changeAdditive, nodePath, "values are no longer restricted to an enum"}}
+	}
+
+	oldValues, newValues := enumSet(oldEnum), enumSet(newEnum)
+	var changes []schemaChange
+	for _, v := range sortedKeys(oldValues) {
+		if !newValues[v] {
+			changes = append(changes, schemaChange{changeBreaking, nodePath, fmt.Sprintf("enum value %s removed", v)})
+		}
+	}
+
+	for _, v := range sortedKeys(newValues) {
+		if !oldValues[v] {
+			changes = append(changes, schemaChange{changeAdditive, nodePath, fmt.Sprintf("enum value %s added", v)})
+		}
+	}
+
+	return changes
+}
+
+// compareAdditionalProperties compares whether properties that aren't
+// declared are allowed. They are allowed if the keyword isn't set.
+func compareAdditionalProperties(nodePath string, oldAllowed, newAllowed *bool) []schemaChange {
+	o, n := oldAllowed == nil || *oldAllowed, newAllowed == nil || *newAllowed
+	switch {
+	case o && !n:
+		return []schemaChange{{changeBreaking, nodePath, "additional properties are no longer allowed"}}
+	case !o && n:
+		return []schemaChange{{changeAdditive, nodePath, "additional properties are now allowed"}}
+	}
+
+	return nil
+}
+
+// hasBreakingChanges returns true if any of the changes is breaking
+func hasBreakingChanges(changes []schemaChange) bool {
+	for _, c := range changes {
+		if c.Kind == changeBreaking {
+			return true
+		}
+	}
+
+	return false
+}
+
+// formatSchemaChanges returns a report of the changes with breaking
+// changes listed first
+func formatSchemaChanges(changes []schemaChange) string {
+	if len(changes) == 0 {
+		return "no changes to the schema"
+	}
+
+	sorted := make([]schemaChange, len(changes))
+	copy(sorted, changes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Kind == changeBreaking && sorted[j].Kind != changeBreaking
+	})
+
+	var lines []string
+	for _, c := range sorted {
+		lines = append(lines, c.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func sortedDefNames(a, b map[string]*schemaNode) []string {
+	names := make(map[string]bool)
+	for n := range a {
+		names[n] = true
+	}
+
+	for n := range b {
+		names[n] = true
+	}
+
+	return sortedKeys(names)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func toSet(values []string) map[string]bool {
+	s := make(map[string]bool)
+	for _, v := range values {
+		s[v] = true
+	}
+
+	return s
+}
+
+func enumSet(values []interface{}) map[string]bool {
+	s := make(map[string]bool)
+	for _, v := range values {
+		b, _ := json.Marshal(v)
+		s[string(b)] = true
+	}
+
+	return s
+}
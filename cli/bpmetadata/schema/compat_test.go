@@ -0,0 +1,198 @@
+package main
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const baseSchema = `{
+  "$ref": "#/$defs/BlueprintMetadata",
+  "$defs": {
+    "BlueprintMetadata": {
+      "properties": {
+        "apiVersion": {"type": "string"},
+        "spec": {"$ref": "#/$defs/BlueprintSpec"}
+      },
+      "additionalProperties": false,
+      "type": "object",
+      "required": ["spec"]
+    },
+    "BlueprintSpec": {
+      "properties": {
+        "title": {"type": "string"},
+        "type": {"type": "string", "enum": ["A", "B"]},
+        "labels": {"patternProperties": {".*": {"type": "string"}}, "type": "object"},
+        "roles": {"items": {"type": "string"}, "type": "array"},
+        "defaultValue": true
+      },
+      "additionalProperties": false,
+      "type": "object"
+    }
+  }
+}`
+
+func TestCompareSchemas(t *testing.T) {
+	tests := []struct {
+		name      string
+		newSchema string
+		want      []schemaChange
+	}{
+		{
+			name:      "no changes",
+			newSchema: baseSchema,
+		},
+		{
+			name: "optional property added",
+			newSchema: replaceOnce(baseSchema, `"title": {"type": "string"},`,
+				`"title": {"type": "string"}, "version": {"type": "string"},`),
+			want: []schemaChange{
+				{changeAdditive, "#/$defs/BlueprintSpec.version", "property added"},
+			},
+		},
+		{
+			name: "required property added",
+			newSchema: replaceOnce(replaceOnce(baseSchema, `"apiVersion": {"type": "string"},`,
+				`"apiVersion": {"type": "string"}, "kind": {"type": "string"},`), `"required": ["spec"]`, `"required": ["spec", "kind"]`),
+			want: []schemaChange{
+				{changeBreaking, "#/$defs/BlueprintMetadata.kind", "required property added"},
+			},
+		},
+		{
+			name:      "property removed",
+			newSchema: replaceOnce(baseSchema, `"title": {"type": "string"},`, ""),
+			want: []schemaChange{
+				{changeBreaking, "#/$defs/BlueprintSpec.title", "property removed"},
+			},
+		},
+		{
+			name:      "property type changed",
+			newSchema: replaceOnce(baseSchema, `"roles": {"items": {"type": "string"}`, `"roles": {"items": {"type": "integer"}`),
+			want: []schemaChange{
+				{changeBreaking, "#/$defs/BlueprintSpec.roles[]", `type changed from "string" to "integer"`},
+			},
+		},
+		{
+			name: "property now required",
+			newSchema: replaceOnce(baseSchema, `"type": "object"
+    }
+  }`, `"type": "object", "required": ["title"]
+    }
+  }`),
+			want: []schemaChange{
+				{changeBreaking, "#/$defs/BlueprintSpec.title", "property is now required"},
+			},
+		},
+		{
+			name:      "property no longer required",
+			newSchema: replaceOnce(baseSchema, `"required": ["spec"]`, `"required": []`),
+			want: []schemaChange{
+				{changeAdditive, "#/$defs/BlueprintMetadata.spec", "property is no longer required"},
+			},
+		},
+		{
+			name:      "enum values changed",
+			newSchema: replaceOnce(baseSchema, `"enum": ["A", "B"]`, `"enum": ["A", "C"]`),
+			want: []schemaChange{
+				{changeBreaking, "#/$defs/BlueprintSpec.type", `enum value "B" removed`},
+				{changeAdditive, "#/$defs/BlueprintSpec.type", `enum value "C" added`},
+			},
+		},
+		{
+			name:      "map value type changed",
+			newSchema: replaceOnce(baseSchema, `{".*": {"type": "string"}}`, `{".*": {"type": "boolean"}}`),
+			want: []schemaChange{
+				{changeBreaking, "#/$defs/BlueprintSpec.labels[.*]", `type changed from "string" to "boolean"`},
+			},
+		},
+		{
+			name:      "boolean schema restricted",
+			newSchema: replaceOnce(baseSchema, `"defaultValue": true`, `"defaultValue": {"type": "string"}`),
+			want: []schemaChange{
+				{changeBreaking, "#/$defs/BlueprintSpec.defaultValue", `type changed from "" to "string"`},
+			},
+		},
+		{
+			name: "additional properties allowed",
+			newSchema: replaceOnce(baseSchema, `"additionalProperties": false,
+      "type": "object",
+      "required"`, `"type": "object",
+      "required"`),
+			want: []schemaChange{
+				{changeAdditive, "#/$defs/BlueprintMetadata", "additional properties are now allowed"},
+			},
+		},
+		{
+			name:      "definition renamed",
+			newSchema: strings.ReplaceAll(baseSchema, "BlueprintSpec", "BlueprintMetadataSpec"),
+			want: []schemaChange{
+				{changeBreaking, "#/$defs/BlueprintMetadata.spec", `reference changed from "#/$defs/BlueprintSpec" to "#/$defs/BlueprintMetadataSpec"`},
+				{changeAdditive, "#/$defs/BlueprintMetadataSpec", "definition added"},
+				{changeAdditive, "#/$defs/BlueprintSpec", "unreferenced definition removed"},
+			},
+		},
+		{
+			name: "referenced definition removed",
+			newSchema: replaceOnce(baseSchema, `,
+    "BlueprintSpec": {
+      "properties": {`, `,
+    "Unused": {
+      "properties": {`),
+			want: []schemaChange{
+				{changeBreaking, "#/$defs/BlueprintSpec", "definition removed while still referenced"},
+				{changeAdditive, "#/$defs/Unused", "definition added"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := compareSchemas([]byte(baseSchema), []byte(tt.newSchema))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGeneratedSchemaIsUpToDate(t *testing.T) {
+	sData, err := GenerateSchema()
+	require.NoError(t, err)
+
+	changes, err := checkSchemaCompatFile(schemaFileName, sData)
+	require.NoError(t, err)
+	assert.Empty(t, changes, "regenerate the schema:\n%s", formatSchemaChanges(changes))
+}
+
+func TestProcessExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	sData, err := GenerateSchema()
+	require.NoError(t, err)
+
+	same := path.Join(dir, "same.json")
+	require.NoError(t, os.WriteFile(same, sData, 0644))
+	assert.Equal(t, 0, process("", same))
+
+	// a baseline with a property the generated schema doesn't have
+	extra := path.Join(dir, "extra.json")
+	require.NoError(t, os.WriteFile(extra, []byte(replaceOnce(string(sData), `"properties": {`, `"properties": {
+        "removedField": {"type": "string"},`)), 0644))
+	assert.Equal(t, 2, process("", extra))
+
+	assert.Equal(t, 1, process("", path.Join(dir, "missing.json")))
+}
+
+func TestFormatSchemaChanges(t *testing.T) {
+	assert.Equal(t, "no changes to the schema", formatSchemaChanges(nil))
+	assert.Equal(t, "breaking: $.b: property removed\nadditive: $.a: property added", formatSchemaChanges([]schemaChange{
+		{changeAdditive, "$.a", "property added"},
+		{changeBreaking, "$.b", "property removed"},
+	}))
+}
+
+func replaceOnce(s, old, new string) string {
+	return strings.Replace(s, old, new, 1)
+}
@@ -15,19 +15,23 @@ var Log = log.New()
 func main() {
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr,
-			"USAGE: %s [-output=PATH]\n",
+			"USAGE: %s [-output=PATH] [-baseline=PATH]\n",
 			path.Base(os.Args[0]))
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
 	output := flag.String("output", "", "output path for generating the JSON schema definition")
+	baseline := flag.String("baseline", "", "path to the previously released JSON schema definition to check the generated schema for breaking changes against")
 	flag.Parse()
 
-	os.Exit(process(*output))
+	os.Exit(process(*output, *baseline))
 }
 
-func process(output string) int {
+// process generates the schema and optionally checks it for breaking
+// changes. It returns 1 if there is an error and 2 if there are breaking
+// changes, so that it can gate schema changes.
+func process(output, baseline string) int {
 	// get the working directory for the command
 	wdPath, err := os.Getwd()
 	if err != nil {
@@ -35,10 +39,34 @@ func process(output string) int {
 		return 1
 	}
 
-	if err := generateSchemaFile(output, wdPath); err != nil {
+	if output != "" || baseline == "" {
+		if err := generateSchemaFile(output, wdPath); err != nil {
+			Log.Error("error generating schema", "err", err)
+			return 1
+		}
+	}
+
+	if baseline == "" {
+		return 0
+	}
+
+	sData, err := GenerateSchema()
+	if err != nil {
 		Log.Error("error generating schema", "err", err)
 		return 1
 	}
 
+	changes, err := checkSchemaCompatFile(baseline, sData)
+	if err != nil {
+		Log.Error("error checking schema compatibility", "err", err)
+		return 1
+	}
+
+	fmt.Println(formatSchemaChanges(changes))
+	if hasBreakingChanges(changes) {
+		Log.Error("generated schema has breaking changes", "baseline", baseline)
+		return 2
+	}
+
 	return 0
 }
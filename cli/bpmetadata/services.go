@@ -0,0 +1,189 @@
+package bpmetadata
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+)
+
+// resourceServices maps prefixes of google resource and data source types
+// to the service API they require. The longest matching prefix wins and an
+// empty service marks types that don't require a specific API.
+var resourceServices = map[string]string{
+	"google_access_context_manager_": "accesscontextmanager.googleapis.com",
+	"google_alloydb_":                "alloydb.googleapis.com",
+	"google_apigee_":                 "apigee.googleapis.com",
+	"google_app_engine_":             "appengine.googleapis.com",
+	"google_artifact_registry_":      "artifactregistry.googleapis.com",
+	"google_bigquery_":               "bigquery.googleapis.com",
+	"google_bigquery_data_transfer_": "bigquerydatatransfer.googleapis.com",
+	"google_bigquery_reservation":    "bigqueryreservation.googleapis.com",
+	"google_bigtable_":               "bigtableadmin.googleapis.com",
+	"google_billing_":                "cloudbilling.googleapis.com",
+	"google_binary_authorization_":   "binaryauthorization.googleapis.com",
+	"google_certificate_manager_":    "certificatemanager.googleapis.com",
+	"google_client_":                 "",
+	"google_cloud_asset_":            "cloudasset.googleapis.com",
+	"google_cloud_identity_":         "cloudidentity.googleapis.com",
+	"google_cloud_run_":              "run.googleapis.com",
+	"google_cloud_scheduler_":        "cloudscheduler.googleapis.com",
+	"google_cloud_tasks_":            "cloudtasks.googleapis.com",
+	"google_cloudbuild_":             "cloudbuild.googleapis.com",
+	"google_cloudfunctions_":         "cloudfunctions.googleapis.com",
+	"google_cloudfunctions2_":        "cloudfunctions.googleapis.com",
+	"google_composer_":               "composer.googleapis.com",
+	"google_compute_":                "compute.googleapis.com",
+	"google_container_":              "container.googleapis.com",
+	"google_container_analysis_":     "containeranalysis.googleapis.com",
+	"google_container_registry":      "containerregistry.googleapis.com",
+	"google_data_catalog_":           "datacatalog.googleapis.com",
+	"google_data_fusion_":            "datafusion.googleapis.com",
+	"google_dataflow_":               "dataflow.googleapis.com",
+	"google_dataplex_":               "dataplex.googleapis.com",
+	"google_dataproc_":               "dataproc.googleapis.com",
+	"google_dataproc_metastore_":     "metastore.googleapis.com",
+	"google_datastream_":             "datastream.googleapis.com",
+	"google_dns_":                    "dns.googleapis.com",
+	"google_eventarc_":               "eventarc.googleapis.com",
+	"google_filestore_":              "file.googleapis.com",
+	"google_firebase_":               "firebase.googleapis.com",
+	"google_firestore_":              "firestore.googleapis.com",
+	"google_folder":                  "cloudresourcemanager.googleapis.com",
+	"google_gke_hub_":                "gkehub.googleapis.com",
+	"google_healthcare_":             "healthcare.googleapis.com",
+	"google_iam_":                    "iam.googleapis.com",
+	"google_iap_":                    "iap.googleapis.com",
+	"google_kms_":                    "cloudkms.googleapis.com",
+	"google_logging_":                "logging.googleapis.com",
+	"google_memcache_":               "memcache.googleapis.com",
+	"google_monitoring_":             "monitoring.googleapis.com",
+	"google_network_connectivity_":   "networkconnectivity.googleapis.com",
+	"google_network_services_":       "networkservices.googleapis.com",
+	"google_notebooks_":              "notebooks.googleapis.com",
+	"google_org_policy_":             "orgpolicy.googleapis.com",
+	"google_organization":            "cloudresourcemanager.googleapis.com",
+	"google_privateca_":              "privateca.googleapis.com",
+	"google_project":                 "cloudresourcemanager.googleapis.com",
+	"google_project_service":         "serviceusage.googleapis.com",
+	"google_pubsub_":                 "pubsub.googleapis.com",
+	"google_redis_":                  "redis.googleapis.com",
+	"google_secret_manager_":         "secretmanager.googleapis.com",
+	"google_service_account":         "iam.googleapis.com",
+	"google_service_networking_":     "servicenetworking.googleapis.com",
+	"google_sourcerepo_":             "sourcerepo.googleapis.com",
+	"google_spanner_":                "spanner.googleapis.com",
+	"google_sql_":                    "sqladmin.googleapis.com",
+	"google_storage_":                "storage.googleapis.com",
+	"google_storage_transfer_":       "storagetransfer.googleapis.com",
+	"google_tags_":                   "cloudresourcemanager.googleapis.com",
+	"google_vertex_ai_":              "aiplatform.googleapis.com",
+	"google_vpc_access_":             "vpcaccess.googleapis.com",
+	"google_workflows_":              "workflows.googleapis.com",
+	"google_workstations_":           "workstations.googleapis.com",
+}
+
+// serviceAliases are services that are satisfied by activating any
+// of the listed alternatives
+var serviceAliases = map[string][]string{
+	"storage.googleapis.com": {"storage-api.googleapis.com", "storage-component.googleapis.com"},
+}
+
+// getResourceServices returns the services required by the google resources
+// and data sources in the module at configPath along with all local modules
+// it calls
+func getResourceServices(configPath string) ([]string, error) {
+	services := make(map[string]bool)
+	err := collectResourceServices(configPath, services, make(map[string]bool))
+	if err != nil {
+		return nil, err
+	}
+
+	var s []string
+	for svc := range services {
+		s = append(s, svc)
+	}
+
+	sort.Strings(s)
+	return s, nil
+}
+
+func collectResourceServices(configPath string, services, visited map[string]bool) error {
+	configPath = filepath.Clean(configPath)
+	if visited[configPath] {
+		return nil
+	}
+
+	visited[configPath] = true
+	mod, diags := tfconfig.LoadModule(configPath)
+	err := hasTfconfigErrors(diags)
+	if err != nil {
+		return err
+	}
+
+	for _, resources := range []map[string]*tfconfig.Resource{mod.ManagedResources, mod.DataResources} {
+		for _, r := range resources {
+			if svc := resourceService(r.Type); svc != "" {
+				services[svc] = true
+			}
+		}
+	}
+
+	for _, c := range mod.ModuleCalls {
+		if !strings.HasPrefix(c.Source, "./") && !strings.HasPrefix(c.Source, "../") {
+			continue
+		}
+
+		err := collectResourceServices(filepath.Join(configPath, c.Source), services, visited)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resourceService returns the service required by a resource type
+func resourceService(resourceType string) string {
+	var match, svc string
+	for prefix, s := range resourceServices {
+		if strings.HasPrefix(resourceType, prefix) && len(prefix) > len(match) {
+			match, svc = prefix, s
+		}
+	}
+
+	return svc
+}
+
+// mergeServices adds the services required by resources to the services
+// activated in the setup config and returns the merged list along with
+// the services that weren't activated
+func mergeServices(activated, required []string) ([]string, []string) {
+	isActivated := make(map[string]bool)
+	for _, s := range activated {
+		isActivated[s] = true
+	}
+
+	merged := append([]string{}, activated...)
+	var missing []string
+	for _, s := range required {
+		if isActivated[s] {
+			continue
+		}
+
+		aliased := false
+		for _, a := range serviceAliases[s] {
+			aliased = aliased || isActivated[a]
+		}
+
+		if aliased {
+			continue
+		}
+
+		merged = append(merged, s)
+		missing = append(missing, s)
+	}
+
+	return merged, missing
+}
@@ -0,0 +1,88 @@
+package bpmetadata
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceServices(t *testing.T) {
+	got, err := getResourceServices(path.Join(tfTestdataPath, "services"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"cloudresourcemanager.googleapis.com",
+		"compute.googleapis.com",
+		"secretmanager.googleapis.com",
+		"sqladmin.googleapis.com",
+		"storage.googleapis.com",
+	}, got)
+}
+
+func TestResourceService(t *testing.T) {
+	tests := []struct {
+		resourceType string
+		want         string
+	}{
+		{"google_compute_instance", "compute.googleapis.com"},
+		{"google_project_iam_member", "cloudresourcemanager.googleapis.com"},
+		{"google_project_service", "serviceusage.googleapis.com"},
+		{"google_service_account_key", "iam.googleapis.com"},
+		{"google_bigquery_data_transfer_config", "bigquerydatatransfer.googleapis.com"},
+		{"google_dataproc_cluster", "dataproc.googleapis.com"},
+		{"google_dataproc_metastore_service", "metastore.googleapis.com"},
+		{"google_client_openid_userinfo", ""},
+		{"random_id", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.resourceType, func(t *testing.T) {
+			assert.Equal(t, tt.want, resourceService(tt.resourceType))
+		})
+	}
+}
+
+func TestMergeServices(t *testing.T) {
+	tests := []struct {
+		name        string
+		activated   []string
+		required    []string
+		wantMerged  []string
+		wantMissing []string
+	}{
+		{
+			name:       "all activated",
+			activated:  []string{"compute.googleapis.com", "iam.googleapis.com"},
+			required:   []string{"compute.googleapis.com"},
+			wantMerged: []string{"compute.googleapis.com", "iam.googleapis.com"},
+		},
+		{
+			name:       "activated through alias",
+			activated:  []string{"storage-api.googleapis.com"},
+			required:   []string{"storage.googleapis.com"},
+			wantMerged: []string{"storage-api.googleapis.com"},
+		},
+		{
+			name:        "missing activation",
+			activated:   []string{"iam.googleapis.com"},
+			required:    []string{"compute.googleapis.com", "sqladmin.googleapis.com"},
+			wantMerged:  []string{"iam.googleapis.com", "compute.googleapis.com", "sqladmin.googleapis.com"},
+			wantMissing: []string{"compute.googleapis.com", "sqladmin.googleapis.com"},
+		},
+		{
+			name:        "no activate_apis",
+			required:    []string{"compute.googleapis.com"},
+			wantMerged:  []string{"compute.googleapis.com"},
+			wantMissing: []string{"compute.googleapis.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, missing := mergeServices(tt.activated, tt.required)
+			assert.Equal(t, tt.wantMerged, merged)
+			assert.Equal(t, tt.wantMissing, missing)
+		})
+	}
+}
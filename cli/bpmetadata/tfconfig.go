@@ -6,11 +6,13 @@ import (
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strings"
 
 	hcl "github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+	"github.com/zclconf/go-cty/cty"
 )
 
 const (
@@ -20,6 +22,7 @@ const (
 type blueprintVersion struct {
 	moduleVersion     string
 	requiredTfVersion string
+	requiredProviders []BlueprintProvider
 }
 
 var rootSchema = &hcl.BodySchema{
@@ -40,9 +43,72 @@ var rootSchema = &hcl.BodySchema{
 			Type:       "module",
 			LabelNames: []string{"name"},
 		},
+		{
+			Type:       "variable",
+			LabelNames: []string{"name"},
+		},
+	},
+}
+
+var variableDefaultSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{
+			Name: "default",
+		},
+	},
+}
+
+var iamResourceSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{
+			Name: "role",
+		},
+		{
+			Name: "for_each",
+		},
+		{
+			Name: "count",
+		},
 	},
 }
 
+const (
+	roleLevelOrganization   = "Organization"
+	roleLevelFolder         = "Folder"
+	roleLevelBillingAccount = "BillingAccount"
+	roleLevelProject        = "Project"
+)
+
+// roleLevels is the order in which roles are listed in the requirements
+var roleLevels = []string{
+	roleLevelOrganization,
+	roleLevelFolder,
+	roleLevelBillingAccount,
+	roleLevelProject,
+}
+
+// roleLevelTokens maps tokens in the names of locals holding roles
+// to the level the roles are granted at
+var roleLevelTokens = map[string]string{
+	"org":          roleLevelOrganization,
+	"organization": roleLevelOrganization,
+	"folder":       roleLevelFolder,
+	"billing":      roleLevelBillingAccount,
+	"project":      roleLevelProject,
+}
+
+// iamResourceLevels maps IAM resources to the level they grant roles at
+var iamResourceLevels = map[string]string{
+	"google_organization_iam_member":     roleLevelOrganization,
+	"google_organization_iam_binding":    roleLevelOrganization,
+	"google_folder_iam_member":           roleLevelFolder,
+	"google_folder_iam_binding":          roleLevelFolder,
+	"google_billing_account_iam_member":  roleLevelBillingAccount,
+	"google_billing_account_iam_binding": roleLevelBillingAccount,
+	"google_project_iam_member":          roleLevelProject,
+	"google_project_iam_binding":         roleLevelProject,
+}
+
 var metaSchema = &hcl.BodySchema{
 	Blocks: []hcl.BlockHeaderSchema{
 		{
@@ -113,9 +179,32 @@ func getBlueprintVersion(configPath string) (*blueprintVersion, error) {
 	return &blueprintVersion{
 		moduleVersion:     modName,
 		requiredTfVersion: requiredCore,
+		requiredProviders: getRequiredProviders(hclModule.RequiredProviders),
 	}, nil
 }
 
+// getRequiredProviders converts the required providers of a module
+// sorted by name
+func getRequiredProviders(reqs map[string]*tfconfig.ProviderRequirement) []BlueprintProvider {
+	var providers []BlueprintProvider
+	for name, r := range reqs {
+		p := BlueprintProvider{
+			Name:    name,
+			Source:  r.Source,
+			Version: strings.Join(r.VersionConstraints, ", "),
+		}
+
+		for _, a := range r.ConfigurationAliases {
+			p.ConfigurationAliases = append(p.ConfigurationAliases, a.Name+"."+a.Alias)
+		}
+
+		providers = append(providers, p)
+	}
+
+	sort.Slice(providers, func(i, j int) bool { return providers[i].Name < providers[j].Name })
+	return providers
+}
+
 // parseBlueprintVersion gets the blueprint version from the provided config
 // from the provider_meta block
 func parseBlueprintVersion(versionsFile *hcl.File, diags hcl.Diagnostics) (string, error) {
@@ -192,9 +281,18 @@ func getBlueprintInterfaces(configPath string) (*BlueprintInterface, error) {
 		return nil, err
 	}
 
+	details, err := getInterfaceDetails(configPath)
+	if err != nil {
+		return nil, err
+	}
+
 	var variables []BlueprintVariable
 	for _, val := range mod.Variables {
 		v := getBlueprintVariable(val)
+		if n, declared := details.nullable[v.Name]; declared {
+			v.Nullable = &n
+		}
+
 		variables = append(variables, v)
 	}
 
@@ -204,6 +302,9 @@ func getBlueprintInterfaces(configPath string) (*BlueprintInterface, error) {
 	var outputs []BlueprintOutput
 	for _, val := range mod.Outputs {
 		o := getBlueprintOutput(val)
+		if expr, hasValue := details.outputValues[o.Name]; hasValue {
+			o.TypeInfo = outputType(expr, variables)
+		}
 
 		outputs = append(outputs, o)
 	}
@@ -219,13 +320,28 @@ func getBlueprintInterfaces(configPath string) (*BlueprintInterface, error) {
 
 // build variable
 func getBlueprintVariable(modVar *tfconfig.Variable) BlueprintVariable {
-	return BlueprintVariable{
+	v := BlueprintVariable{
 		Name:         modVar.Name,
 		Description:  modVar.Description,
 		DefaultValue: modVar.Default,
 		Required:     modVar.Required,
 		VarType:      modVar.Type,
+		Sensitive:    modVar.Sensitive,
+	}
+
+	if modVar.Type == "" {
+		return v
 	}
+
+	// type info is left unset for type constraints that can't be parsed
+	t, err := parseTypeConstraint(modVar.Type)
+	if err != nil {
+		Log.Warn("unable to parse type for variable", "variable", modVar.Name, "err", err)
+		return v
+	}
+
+	v.TypeInfo = t
+	return v
 }
 
 // build output
@@ -233,12 +349,15 @@ func getBlueprintOutput(modOut *tfconfig.Output) BlueprintOutput {
 	return BlueprintOutput{
 		Name:        modOut.Name,
 		Description: modOut.Description,
+		Sensitive:   modOut.Sensitive,
 	}
 }
 
 // getBlueprintRequirements gets the services and roles associated
-// with the blueprint
-func getBlueprintRequirements(rolesConfigPath, servicesConfigPath string) (*BlueprintRequirements, error) {
+// with the blueprint. Services required by the resources in the blueprint
+// at configPath are added to the services activated in the setup config.
+// The locals and module blocks holding roles and services are taken from cfg.
+func getBlueprintRequirements(rolesConfigPath, servicesConfigPath, configPath string, cfg *metadataConfig) (*BlueprintRequirements, error) {
 	//parse blueprint roles
 	p := hclparse.NewParser()
 	rolesFile, diags := p.ParseHCLFile(rolesConfigPath)
@@ -247,7 +366,7 @@ func getBlueprintRequirements(rolesConfigPath, servicesConfigPath string) (*Blue
 		return nil, err
 	}
 
-	r, err := parseBlueprintRoles(rolesFile)
+	r, err := parseBlueprintRoles(rolesFile, cfg.RolesLocals)
 	if err != nil {
 		return nil, err
 	}
@@ -259,69 +378,280 @@ func getBlueprintRequirements(rolesConfigPath, servicesConfigPath string) (*Blue
 		return nil, err
 	}
 
-	s, err := parseBlueprintServices(servicesFile)
+	s, err := parseBlueprintServices(servicesFile, cfg.ServicesModules)
+	if err != nil {
+		return nil, err
+	}
+
+	rs, err := getResourceServices(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	s, missing := mergeServices(s, rs)
+	for _, svc := range missing {
+		Log.Warn("service is required by blueprint resources but not activated in the setup config", "service", svc, "config", servicesConfigPath)
+	}
+
+	deps, err := getModuleDependencies(configPath)
 	if err != nil {
 		return nil, err
 	}
 
+	// providers are only required if versions.tf declares them
+	var providers []BlueprintProvider
+	if v, err := getBlueprintVersion(filepath.Join(configPath, tfVersionsFileName)); err == nil {
+		providers = v.requiredProviders
+	}
+
 	return &BlueprintRequirements{
-		Roles:    r,
-		Services: s,
+		Roles:        r,
+		Services:     s,
+		Dependencies: deps,
+		Providers:    providers,
 	}, nil
 }
 
-// parseBlueprintRoles gets the roles required for the blueprint to be provisioned
-func parseBlueprintRoles(rolesFile *hcl.File) ([]BlueprintRoles, error) {
-	var r []BlueprintRoles
+// parseBlueprintRoles gets the roles required for the blueprint to be provisioned.
+// Roles are read from lists in locals and from IAM member resources. The
+// level a role must be granted at is taken from the IAM resource that grants
+// it and otherwise inferred from the name of the local e.g. org_required_roles.
+// Only the locals in localKeys are considered if it is set.
+func parseBlueprintRoles(rolesFile *hcl.File, localKeys []string) ([]BlueprintRoles, error) {
 	iamContent, _, diags := rolesFile.Body.PartialContent(rootSchema)
 	err := hasHclErrors(diags)
 	if err != nil {
 		return nil, err
 	}
 
+	var localNames []string
+	localRanges := make(map[string]hcl.Range)
+	localRoles := make(map[string][]string)
+	localLevels := make(map[string][]string)
+	varRoles := make(map[string][]string)
+	varLevels := make(map[string][]string)
+	levelRoles := make(map[string][]string)
 	for _, block := range iamContent.Blocks {
-		if block.Type != "locals" {
-			continue
-		}
+		switch block.Type {
+		case "variable":
+			varAttrs, _, diags := block.Body.PartialContent(variableDefaultSchema)
+			err := hasHclErrors(diags)
+			if err != nil {
+				return nil, err
+			}
 
-		iamAttrs, diags := block.Body.JustAttributes()
-		err := hasHclErrors(diags)
-		if err != nil {
-			return nil, err
-		}
+			if def, defined := varAttrs.Attributes["default"]; defined {
+				if roles, isRoleList := roleList(def.Expr); isRoleList {
+					varRoles[block.Labels[0]] = roles
+				}
+			}
+
+		case "locals":
+			iamAttrs, diags := block.Body.JustAttributes()
+			err := hasHclErrors(diags)
+			if err != nil {
+				return nil, err
+			}
+
+			for k, attr := range iamAttrs {
+				if len(localKeys) > 0 && !contains(localKeys, k) {
+					continue
+				}
+
+				roles, isRoleList := roleList(attr.Expr)
+				if !isRoleList {
+					continue
+				}
+
+				localNames = append(localNames, k)
+				localRanges[k] = attr.Range
+				localRoles[k] = roles
+			}
+
+		case "resource":
+			level, isIAMResource := iamResourceLevels[block.Labels[0]]
+			if !isIAMResource {
+				continue
+			}
+
+			iamResContent, _, diags := block.Body.PartialContent(iamResourceSchema)
+			err := hasHclErrors(diags)
+			if err != nil {
+				return nil, err
+			}
+
+			roleAttr, defined := iamResContent.Attributes["role"]
+			if !defined {
+				continue
+			}
+
+			// role = "roles/..."
+			var role string
+			if len(roleAttr.Expr.Variables()) == 0 {
+				if gohcl.DecodeExpression(roleAttr.Expr, nil, &role) == nil {
+					levelRoles[level] = appendUnique(levelRoles[level], role)
+				}
 
-		for k, _ := range iamAttrs {
-			var iamRoles []string
-			attrValue, _ := iamAttrs[k].Expr.Value(nil)
-			if !attrValue.Type().IsTupleType() {
 				continue
 			}
 
-			ie := attrValue.ElementIterator()
-			for ie.Next() {
-				_, v := ie.Element()
-				iamRoles = append(iamRoles, v.AsString())
+			// the list of roles granted is referenced by the for_each e.g.
+			// for_each = toset(local.org_roles) with role = each.value or by
+			// the role e.g. count = length(local.org_roles) with
+			// role = local.org_roles[count.index]
+			var listExpr hcl.Expression
+			if forEachAttr, defined := iamResContent.Attributes["for_each"]; defined && refersTo(roleAttr.Expr, "each") {
+				listExpr = forEachAttr.Expr
+			} else if _, defined := iamResContent.Attributes["count"]; defined && refersTo(roleAttr.Expr, "count") {
+				listExpr = roleAttr.Expr
+			} else {
+				continue
 			}
 
-			containerRoles := BlueprintRoles{
-				// TODO: (b/248123274) no good way to associate granularity yet
-				Level: "Project",
-				Roles: iamRoles,
+			for _, l := range refNames(listExpr, "local") {
+				localLevels[l] = appendUnique(localLevels[l], level)
 			}
 
-			r = append(r, containerRoles)
+			for _, v := range refNames(listExpr, "var") {
+				varLevels[v] = appendUnique(varLevels[v], level)
+			}
 		}
+	}
 
-		// because we're only interested in the top-level locals block
-		break
+	// variables with a default list of roles are only considered if
+	// they are granted
+	var varNames []string
+	for v := range varLevels {
+		varNames = append(varNames, v)
+	}
+	sort.Strings(varNames)
+
+	for _, v := range varNames {
+		for _, level := range varLevels[v] {
+			for _, role := range varRoles[v] {
+				levelRoles[level] = appendUnique(levelRoles[level], role)
+			}
+		}
+	}
+
+	// process locals in the order they are defined in
+	sort.SliceStable(localNames, func(i, j int) bool {
+		return localRanges[localNames[i]].Start.Byte < localRanges[localNames[j]].Start.Byte
+	})
+
+	for _, l := range localNames {
+		levels, granted := localLevels[l]
+		if !granted {
+			levels = []string{roleLevelFromName(l)}
+		}
+
+		for _, level := range levels {
+			for _, role := range localRoles[l] {
+				levelRoles[level] = appendUnique(levelRoles[level], role)
+			}
+		}
+	}
+
+	var r []BlueprintRoles
+	for _, level := range roleLevels {
+		if len(levelRoles[level]) == 0 {
+			continue
+		}
+
+		r = append(r, BlueprintRoles{
+			Level: level,
+			Roles: levelRoles[level],
+		})
 	}
 
 	return r, nil
 }
 
+// roleList returns the roles if expr is a constant list of roles
+func roleList(expr hcl.Expression) ([]string, bool) {
+	if len(expr.Variables()) > 0 {
+		return nil, false
+	}
+
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() || !val.Type().IsTupleType() || val.LengthInt() == 0 {
+		return nil, false
+	}
+
+	var roles []string
+	for ie := val.ElementIterator(); ie.Next(); {
+		_, v := ie.Element()
+		if v.IsNull() || !v.Type().Equals(cty.String) || !strings.Contains(v.AsString(), "roles/") {
+			return nil, false
+		}
+
+		roles = append(roles, v.AsString())
+	}
+
+	return roles, true
+}
+
+// roleLevelFromName infers the level from the name of a local holding
+// roles e.g. org_required_roles. Roles are granted on the project by default.
+func roleLevelFromName(name string) string {
+	for _, token := range strings.Split(strings.ToLower(name), "_") {
+		if level, isLevel := roleLevelTokens[token]; isLevel {
+			return level
+		}
+	}
+
+	return roleLevelProject
+}
+
+// refNames returns the names of all locals or variables referenced in
+// expr, depending on root i.e. "local" or "var"
+func refNames(expr hcl.Expression, root string) []string {
+	var refs []string
+	for _, t := range expr.Variables() {
+		if t.RootName() != root || len(t) < 2 {
+			continue
+		}
+
+		if attr, isAttr := t[1].(hcl.TraverseAttr); isAttr {
+			refs = append(refs, attr.Name)
+		}
+	}
+
+	return refs
+}
+
+func refersTo(expr hcl.Expression, root string) bool {
+	for _, t := range expr.Variables() {
+		if t.RootName() == root {
+			return true
+		}
+	}
+
+	return false
+}
+
+func appendUnique(s []string, v string) []string {
+	if contains(s, v) {
+		return s
+	}
+
+	return append(s, v)
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+
+	return false
+}
+
 // parseBlueprintServices gets the gcp api services required for the blueprint
-// to be provisioned
-func parseBlueprintServices(servicesFile *hcl.File) ([]string, error) {
+// to be provisioned. Services are read from the module blocks in moduleNames
+// if it is set, else from the first module that activates services.
+func parseBlueprintServices(servicesFile *hcl.File, moduleNames []string) ([]string, error) {
 	var s []string
 	servicesContent, _, diags := servicesFile.Body.PartialContent(rootSchema)
 	err := hasHclErrors(diags)
@@ -330,7 +660,7 @@ func parseBlueprintServices(servicesFile *hcl.File) ([]string, error) {
 	}
 
 	for _, block := range servicesContent.Blocks {
-		if block.Type != "module" {
+		if block.Type != "module" || (len(moduleNames) > 0 && !contains(moduleNames, block.Labels[0])) {
 			continue
 		}
 
@@ -341,15 +671,22 @@ func parseBlueprintServices(servicesFile *hcl.File) ([]string, error) {
 			return nil, err
 		}
 
+		// services are also derived from resources so a module
+		// without activate_apis isn't an error
 		apisAttr, defined := moduleContent.Attributes["activate_apis"]
 		if !defined {
-			return nil, fmt.Errorf("activate_apis not defined for project module")
+			continue
 		}
 
-		gohcl.DecodeExpression(apisAttr.Expr, nil, &s)
+		var apis []string
+		gohcl.DecodeExpression(apisAttr.Expr, nil, &apis)
+		s = append(s, apis...)
 
-		// because we're only interested in the top-level modules block
-		break
+		// because we're only interested in the top-level project module
+		// unless modules are configured
+		if len(moduleNames) == 0 {
+			break
+		}
 	}
 
 	return s, nil
@@ -14,6 +14,46 @@ const (
 	interfaces     = "sample-module"
 )
 
+var nodePoolsType = &BlueprintType{
+	Kind: TypeKindList,
+	ElementType: &BlueprintType{
+		Kind: TypeKindObject,
+		Attributes: []BlueprintTypeAttribute{
+			{
+				Name: "disks",
+				Type: &BlueprintType{
+					Kind:       TypeKindTuple,
+					TupleTypes: []*BlueprintType{{Kind: TypeKindString}, {Kind: TypeKindNumber}},
+				},
+				Optional:     true,
+				DefaultValue: []interface{}{"pd-standard", float64(100)},
+			},
+			{
+				Name: "labels",
+				Type: &BlueprintType{
+					Kind:        TypeKindMap,
+					ElementType: &BlueprintType{Kind: TypeKindString},
+				},
+				Optional: true,
+			},
+			{
+				Name: "name",
+				Type: &BlueprintType{Kind: TypeKindString},
+			},
+			{
+				Name:         "node_count",
+				Type:         &BlueprintType{Kind: TypeKindNumber},
+				Optional:     true,
+				DefaultValue: float64(1),
+			},
+		},
+	},
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
 func TestTFInterfaces(t *testing.T) {
 	varTests := []struct {
 		name            string
@@ -22,6 +62,9 @@ func TestTFInterfaces(t *testing.T) {
 		wantVarType     string
 		wantDefault     interface{}
 		wantRequired    bool
+		wantSensitive   bool
+		wantNullable    *bool
+		wantTypeInfo    *BlueprintType
 	}{
 		{
 			name:            "just name and description",
@@ -36,6 +79,7 @@ func TestTFInterfaces(t *testing.T) {
 			wantVarType:     "string",
 			wantDefault:     "some description",
 			wantRequired:    false,
+			wantTypeInfo:    &BlueprintType{Kind: TypeKindString},
 		},
 		{
 			name:            "with required as fasle",
@@ -44,6 +88,25 @@ func TestTFInterfaces(t *testing.T) {
 			wantVarType:     "bool",
 			wantDefault:     true,
 			wantRequired:    false,
+			wantTypeInfo:    &BlueprintType{Kind: TypeKindBool},
+		},
+		{
+			name:            "sensitive",
+			varName:         "secret",
+			wantDescription: "Secret for the cluster",
+			wantVarType:     "string",
+			wantRequired:    true,
+			wantSensitive:   true,
+			wantTypeInfo:    &BlueprintType{Kind: TypeKindString},
+		},
+		{
+			name:            "not nullable with optional attributes",
+			varName:         "node_pools",
+			wantDescription: "List of node pools",
+			wantVarType:     "list(object({\n    name       = string\n    node_count = optional(number, 1)\n    labels     = optional(map(string))\n    disks      = optional(tuple([string, number]), [\"pd-standard\", 100])\n  }))",
+			wantRequired:    true,
+			wantNullable:    boolPtr(false),
+			wantTypeInfo:    nodePoolsType,
 		},
 	}
 
@@ -51,6 +114,8 @@ func TestTFInterfaces(t *testing.T) {
 		name            string
 		outName         string
 		wantDescription string
+		wantSensitive   bool
+		wantTypeInfo    *BlueprintType
 	}{
 		{
 			name:            "just name and description",
@@ -61,6 +126,19 @@ func TestTFInterfaces(t *testing.T) {
 			name:            "more than just name and description",
 			outName:         "endpoint",
 			wantDescription: "Cluster endpoint",
+			wantSensitive:   true,
+		},
+		{
+			name:            "type from variable",
+			outName:         "node_pools",
+			wantDescription: "Node pools of the cluster",
+			wantTypeInfo:    nodePoolsType,
+		},
+		{
+			name:            "type from conversion",
+			outName:         "node_count",
+			wantDescription: "No. of nodes in the cluster",
+			wantTypeInfo:    &BlueprintType{Kind: TypeKindNumber},
 		},
 	}
 
@@ -74,6 +152,9 @@ func TestTFInterfaces(t *testing.T) {
 				DefaultValue: tt.wantDefault,
 				Required:     tt.wantRequired,
 				VarType:      tt.wantVarType,
+				Sensitive:    tt.wantSensitive,
+				Nullable:     tt.wantNullable,
+				TypeInfo:     tt.wantTypeInfo,
 			})
 		})
 	}
@@ -83,6 +164,8 @@ func TestTFInterfaces(t *testing.T) {
 			assert.Contains(t, got.Outputs, BlueprintOutput{
 				Name:        tt.outName,
 				Description: tt.wantDescription,
+				Sensitive:   tt.wantSensitive,
+				TypeInfo:    tt.wantTypeInfo,
 			})
 		})
 	}
@@ -165,6 +248,7 @@ func TestTFServices(t *testing.T) {
 	tests := []struct {
 		name         string
 		configName   string
+		moduleNames  []string
 		wantServices []string
 	}{
 		{
@@ -194,13 +278,34 @@ func TestTFServices(t *testing.T) {
 				"gkehub.googleapis.com",
 			},
 		},
+		{
+			name:       "no activate_apis",
+			configName: "setup-no-apis.tf",
+		},
+		{
+			name:         "first module with apis",
+			configName:   "setup-multi.tf",
+			wantServices: []string{"compute.googleapis.com", "iam.googleapis.com"},
+		},
+		{
+			name:         "configured module",
+			configName:   "setup-multi.tf",
+			moduleNames:  []string{"host_project"},
+			wantServices: []string{"dns.googleapis.com"},
+		},
+		{
+			name:         "multiple configured modules",
+			configName:   "setup-multi.tf",
+			moduleNames:  []string{"project", "host_project"},
+			wantServices: []string{"compute.googleapis.com", "iam.googleapis.com", "dns.googleapis.com"},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			p := hclparse.NewParser()
 			content, _ := p.ParseHCLFile(path.Join(tfTestdataPath, tt.configName))
-			got, err := parseBlueprintServices(content)
+			got, err := parseBlueprintServices(content, tt.moduleNames)
 			require.NoError(t, err)
 			assert.Equal(t, got, tt.wantServices)
 		})
@@ -211,6 +316,7 @@ func TestTFRoles(t *testing.T) {
 	tests := []struct {
 		name       string
 		configName string
+		localKeys  []string
 		wantRoles  []BlueprintRoles
 	}{
 		{
@@ -232,15 +338,131 @@ func TestTFRoles(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:       "roles at different levels",
+			configName: "iam-levels.tf",
+			wantRoles: []BlueprintRoles{
+				{
+					Level: "Organization",
+					Roles: []string{
+						"roles/orgpolicy.policyAdmin",
+						"roles/resourcemanager.organizationAdmin",
+					},
+				},
+				{
+					Level: "Folder",
+					Roles: []string{
+						"roles/compute.xpnAdmin",
+						"roles/resourcemanager.folderAdmin",
+					},
+				},
+				{
+					Level: "BillingAccount",
+					Roles: []string{
+						"roles/billing.user",
+					},
+				},
+				{
+					Level: "Project",
+					Roles: []string{
+						"roles/compute.admin",
+						"roles/iam.serviceAccountUser",
+					},
+				},
+			},
+		},
+		{
+			name:       "roles granted with count",
+			configName: "iam-levels-count.tf",
+			wantRoles: []BlueprintRoles{
+				{
+					Level: "Organization",
+					Roles: []string{
+						"roles/resourcemanager.organizationAdmin",
+						"roles/orgpolicy.policyAdmin",
+					},
+				},
+				{
+					Level: "Folder",
+					Roles: []string{
+						"roles/resourcemanager.folderAdmin",
+					},
+				},
+				{
+					Level: "Project",
+					Roles: []string{
+						"roles/compute.admin",
+						"roles/iam.serviceAccountUser",
+					},
+				},
+			},
+		},
+		{
+			name:       "configured locals",
+			configName: "iam-levels.tf",
+			localKeys:  []string{"org_required_roles"},
+			wantRoles: []BlueprintRoles{
+				{
+					Level: "Organization",
+					Roles: []string{
+						"roles/orgpolicy.policyAdmin",
+					},
+				},
+				{
+					Level: "Folder",
+					Roles: []string{
+						"roles/compute.xpnAdmin",
+					},
+				},
+				{
+					Level: "BillingAccount",
+					Roles: []string{
+						"roles/billing.user",
+					},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			p := hclparse.NewParser()
 			content, _ := p.ParseHCLFile(path.Join(tfTestdataPath, tt.configName))
-			got, err := parseBlueprintRoles(content)
+			got, err := parseBlueprintRoles(content, tt.localKeys)
 			require.NoError(t, err)
 			assert.Equal(t, got, tt.wantRoles)
 		})
 	}
 }
+
+func TestTFInterfacesWithJSONAndUnknownTypes(t *testing.T) {
+	got, err := getBlueprintInterfaces(path.Join(tfTestdataPath, "json-interfaces"))
+	require.NoError(t, err)
+
+	// type info and nullable are read from JSON configs while a type
+	// constraint or nullable that can't be parsed is skipped
+	namesType := &BlueprintType{Kind: TypeKindList, ElementType: &BlueprintType{Kind: TypeKindString}}
+	assert.Equal(t, []BlueprintVariable{
+		{
+			Name:        "labels",
+			Description: "Labels for the resources",
+			VarType:     "unknown(string)",
+			Required:    true,
+		},
+		{
+			Name:        "names",
+			Description: "Names of the resources",
+			VarType:     "list(string)",
+			Required:    true,
+			Nullable:    boolPtr(false),
+			TypeInfo:    namesType,
+		},
+	}, got.Variables)
+	assert.Equal(t, []BlueprintOutput{
+		{
+			Name:        "names",
+			Description: "Names of the resources",
+			TypeInfo:    namesType,
+		},
+	}, got.Outputs)
+}
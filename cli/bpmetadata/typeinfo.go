@@ -0,0 +1,382 @@
+package bpmetadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	hcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// conversionTypes maps type conversion functions to the primitive
+// type they return
+var conversionTypes = map[string]TypeKind{
+	"tostring": TypeKindString,
+	"tonumber": TypeKindNumber,
+	"tobool":   TypeKindBool,
+}
+
+// interfaceDetails holds the attributes of variables and outputs that
+// aren't exposed by tfconfig.
+type interfaceDetails struct {
+	// nullable is keyed by the names of variables that explicitly
+	// declare nullable
+	nullable map[string]bool
+
+	// outputValues are the value expressions keyed by output name
+	outputValues map[string]hclsyntax.Expression
+
+	// localValues are the value expressions keyed by local name
+	localValues map[string]hclsyntax.Expression
+}
+
+// interfaceSchema is the schema of the blocks read from JSON configs
+var interfaceSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{
+			Type:       "variable",
+			LabelNames: []string{"name"},
+		},
+		{
+			Type:       "output",
+			LabelNames: []string{"name"},
+		},
+		{
+			Type: "locals",
+		},
+	},
+}
+
+// getInterfaceDetails parses the variable, output and locals blocks of the
+// Terraform configs at configPath, including JSON configs
+func getInterfaceDetails(configPath string) (*interfaceDetails, error) {
+	files, err := filepath.Glob(filepath.Join(configPath, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	jsonFiles, err := filepath.Glob(filepath.Join(configPath, "*.tf.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	d := &interfaceDetails{
+		nullable:     make(map[string]bool),
+		outputValues: make(map[string]hclsyntax.Expression),
+		localValues:  make(map[string]hclsyntax.Expression),
+	}
+
+	p := hclparse.NewParser()
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		file, diags := p.ParseHCL(b, filepath.Base(f))
+		err = hasHclErrors(diags)
+		if err != nil {
+			return nil, err
+		}
+
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		for _, block := range body.Blocks {
+			if block.Type == "locals" {
+				for name, attr := range block.Body.Attributes {
+					d.localValues[name] = attr.Expr
+				}
+
+				continue
+			}
+
+			if len(block.Labels) != 1 {
+				continue
+			}
+
+			switch block.Type {
+			case "variable":
+				if attr, defined := block.Body.Attributes["nullable"]; defined {
+					v, isConst := constValue(attr.Expr)
+					d.setNullable(block.Labels[0], v, isConst)
+				}
+			case "output":
+				if attr, defined := block.Body.Attributes["value"]; defined {
+					d.outputValues[block.Labels[0]] = attr.Expr
+				}
+			}
+		}
+	}
+
+	for _, f := range jsonFiles {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		file, diags := p.ParseJSON(b, filepath.Base(f))
+		err = hasHclErrors(diags)
+		if err != nil {
+			return nil, err
+		}
+
+		err = d.addJSONConfig(file.Body, b)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", filepath.Base(f), err)
+		}
+	}
+
+	return d, nil
+}
+
+// addJSONConfig adds the details from the body of a JSON config. Values
+// of outputs and locals are only read from strings, which are templates.
+func (d *interfaceDetails) addJSONConfig(body hcl.Body, src []byte) error {
+	content, _, diags := body.PartialContent(interfaceSchema)
+	err := hasHclErrors(diags)
+	if err != nil {
+		return err
+	}
+
+	for _, block := range content.Blocks {
+		attrs, diags := block.Body.JustAttributes()
+		err := hasHclErrors(diags)
+		if err != nil {
+			return err
+		}
+
+		switch block.Type {
+		case "locals":
+			for name, attr := range attrs {
+				if expr := jsonTemplateExpr(attr.Expr, src); expr != nil {
+					d.localValues[name] = expr
+				}
+			}
+		case "variable":
+			if attr, defined := attrs["nullable"]; defined {
+				v, diags := attr.Expr.Value(nil)
+				d.setNullable(block.Labels[0], v, !diags.HasErrors() && v.IsWhollyKnown())
+			}
+		case "output":
+			if attr, defined := attrs["value"]; defined {
+				if expr := jsonTemplateExpr(attr.Expr, src); expr != nil {
+					d.outputValues[block.Labels[0]] = expr
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// setNullable records nullable for a variable if it is declared as a
+// constant bool, which Terraform requires
+func (d *interfaceDetails) setNullable(varName string, v cty.Value, isConst bool) {
+	if !isConst || v.IsNull() || !v.Type().Equals(cty.Bool) {
+		Log.Warn("ignoring nullable for variable since it isn't a constant bool", "variable", varName)
+		return
+	}
+
+	d.nullable[varName] = v.True()
+}
+
+// jsonTemplateExpr parses the string of a JSON expression as a template
+// e.g. "${var.name}" or returns nil if it isn't a string. A template
+// that is a single interpolation is unwrapped.
+func jsonTemplateExpr(expr hcl.Expression, src []byte) hclsyntax.Expression {
+	var tmpl string
+	if err := json.Unmarshal(expr.Range().SliceBytes(src), &tmpl); err != nil {
+		return nil
+	}
+
+	parsed, diags := hclsyntax.ParseTemplate([]byte(tmpl), expr.Range().Filename, expr.Range().Start)
+	if diags.HasErrors() {
+		return nil
+	}
+
+	if w, isWrap := parsed.(*hclsyntax.TemplateWrapExpr); isWrap {
+		return w.Wrapped
+	}
+
+	return parsed
+}
+
+// parseTypeConstraint parses a Terraform type constraint e.g.
+// map(object({name = string})) into a structured type
+func parseTypeConstraint(constraint string) (*BlueprintType, error) {
+	expr, diags := hclsyntax.ParseExpression([]byte(constraint), "", hcl.InitialPos)
+	err := hasHclErrors(diags)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeFromExpr(expr)
+}
+
+// typeFromExpr builds a structured type from a type constraint expression
+func typeFromExpr(expr hclsyntax.Expression) (*BlueprintType, error) {
+	if kw := hcl.ExprAsKeyword(expr); kw != "" {
+		switch k := TypeKind(kw); k {
+		case TypeKindString, TypeKindNumber, TypeKindBool, TypeKindAny:
+			return &BlueprintType{Kind: k}, nil
+		case TypeKindList, TypeKindSet, TypeKindMap:
+			// legacy collection types without an element type
+			return &BlueprintType{Kind: k, ElementType: &BlueprintType{Kind: TypeKindAny}}, nil
+		}
+
+		return nil, fmt.Errorf("unknown type keyword %q", kw)
+	}
+
+	call, isCall := expr.(*hclsyntax.FunctionCallExpr)
+	if !isCall {
+		return nil, fmt.Errorf("invalid type constraint at %s", expr.Range())
+	}
+
+	if len(call.Args) != 1 {
+		return nil, fmt.Errorf("type constructor %s requires exactly one argument", call.Name)
+	}
+
+	switch k := TypeKind(call.Name); k {
+	case TypeKindList, TypeKindSet, TypeKindMap:
+		et, err := typeFromExpr(call.Args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		return &BlueprintType{Kind: k, ElementType: et}, nil
+	case TypeKindTuple:
+		tuple, isTuple := call.Args[0].(*hclsyntax.TupleConsExpr)
+		if !isTuple {
+			return nil, fmt.Errorf("tuple type constructor requires a list of element types")
+		}
+
+		t := &BlueprintType{Kind: k}
+		for _, e := range tuple.Exprs {
+			et, err := typeFromExpr(e)
+			if err != nil {
+				return nil, err
+			}
+
+			t.TupleTypes = append(t.TupleTypes, et)
+		}
+
+		return t, nil
+	case TypeKindObject:
+		obj, isObj := call.Args[0].(*hclsyntax.ObjectConsExpr)
+		if !isObj {
+			return nil, fmt.Errorf("object type constructor requires a map of attribute types")
+		}
+
+		t := &BlueprintType{Kind: k}
+		for _, item := range obj.Items {
+			attr, err := attributeFromItem(item)
+			if err != nil {
+				return nil, err
+			}
+
+			t.Attributes = append(t.Attributes, attr)
+		}
+
+		sort.SliceStable(t.Attributes, func(i, j int) bool { return t.Attributes[i].Name < t.Attributes[j].Name })
+		return t, nil
+	}
+
+	return nil, fmt.Errorf("unknown type constructor %q", call.Name)
+}
+
+// attributeFromItem builds an object attribute from an item of an object
+// type constructor, unwrapping optional(type, default) if present
+func attributeFromItem(item hclsyntax.ObjectConsItem) (BlueprintTypeAttribute, error) {
+	attr := BlueprintTypeAttribute{}
+	attr.Name = hcl.ExprAsKeyword(item.KeyExpr)
+	if attr.Name == "" {
+		k, isConst := constValue(item.KeyExpr)
+		if !isConst || k.IsNull() || !k.Type().Equals(cty.String) {
+			return attr, fmt.Errorf("invalid object attribute name at %s", item.KeyExpr.Range())
+		}
+
+		attr.Name = k.AsString()
+	}
+
+	typeExpr := item.ValueExpr
+	if call, isCall := typeExpr.(*hclsyntax.FunctionCallExpr); isCall && call.Name == "optional" {
+		if len(call.Args) < 1 || len(call.Args) > 2 {
+			return attr, fmt.Errorf("optional for attribute %s requires a type and an optional default", attr.Name)
+		}
+
+		attr.Optional = true
+		typeExpr = call.Args[0]
+		if len(call.Args) == 2 {
+			def, err := defaultValue(call.Args[1])
+			if err != nil {
+				return attr, fmt.Errorf("invalid default for attribute %s: %w", attr.Name, err)
+			}
+
+			attr.DefaultValue = def
+		}
+	}
+
+	t, err := typeFromExpr(typeExpr)
+	if err != nil {
+		return attr, err
+	}
+
+	attr.Type = t
+	return attr, nil
+}
+
+// defaultValue evaluates a constant default value the same way tfconfig
+// does for variable defaults
+func defaultValue(expr hclsyntax.Expression) (interface{}, error) {
+	v, isConst := constValue(expr)
+	if !isConst {
+		return nil, fmt.Errorf("default must be a constant value")
+	}
+
+	if v.IsNull() {
+		return nil, nil
+	}
+
+	b, err := ctyjson.Marshal(v, v.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	var def interface{}
+	err = json.Unmarshal(b, &def)
+	return def, err
+}
+
+// outputType infers the type of an output value. Only direct references
+// to variables and primitive type conversions are considered.
+func outputType(expr hclsyntax.Expression, vars []BlueprintVariable) *BlueprintType {
+	if call, isCall := expr.(*hclsyntax.FunctionCallExpr); isCall {
+		if k, isConversion := conversionTypes[call.Name]; isConversion {
+			return &BlueprintType{Kind: k}
+		}
+
+		return nil
+	}
+
+	name := varRefName(expr)
+	if name == "" {
+		return nil
+	}
+
+	for _, v := range vars {
+		if v.Name == name {
+			return v.TypeInfo
+		}
+	}
+
+	return nil
+}
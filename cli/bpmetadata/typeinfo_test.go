@@ -0,0 +1,95 @@
+package bpmetadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTypeConstraint(t *testing.T) {
+	tests := []struct {
+		name       string
+		constraint string
+		want       *BlueprintType
+		wantErr    bool
+	}{
+		{
+			name:       "primitive",
+			constraint: "number",
+			want:       &BlueprintType{Kind: TypeKindNumber},
+		},
+		{
+			name:       "legacy collection",
+			constraint: "map",
+			want:       &BlueprintType{Kind: TypeKindMap, ElementType: &BlueprintType{Kind: TypeKindAny}},
+		},
+		{
+			name:       "nested collections",
+			constraint: "map(set(string))",
+			want: &BlueprintType{
+				Kind: TypeKindMap,
+				ElementType: &BlueprintType{
+					Kind:        TypeKindSet,
+					ElementType: &BlueprintType{Kind: TypeKindString},
+				},
+			},
+		},
+		{
+			name:       "object with quoted names and optional attributes",
+			constraint: `object({"b" = optional(bool, true), a = optional(object({x = number}), {x = 1}), c = optional(string, null)})`,
+			want: &BlueprintType{
+				Kind: TypeKindObject,
+				Attributes: []BlueprintTypeAttribute{
+					{
+						Name: "a",
+						Type: &BlueprintType{
+							Kind:       TypeKindObject,
+							Attributes: []BlueprintTypeAttribute{{Name: "x", Type: &BlueprintType{Kind: TypeKindNumber}}},
+						},
+						Optional:     true,
+						DefaultValue: map[string]interface{}{"x": float64(1)},
+					},
+					{
+						Name:         "b",
+						Type:         &BlueprintType{Kind: TypeKindBool},
+						Optional:     true,
+						DefaultValue: true,
+					},
+					{
+						Name:     "c",
+						Type:     &BlueprintType{Kind: TypeKindString},
+						Optional: true,
+					},
+				},
+			},
+		},
+		{
+			name:       "unknown type",
+			constraint: "list(strin)",
+			wantErr:    true,
+		},
+		{
+			name:       "non constant default",
+			constraint: "object({a = optional(string, var.a)})",
+			wantErr:    true,
+		},
+		{
+			name:       "tuple without types",
+			constraint: "tuple(string)",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTypeConstraint(tt.constraint)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
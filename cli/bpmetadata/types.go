@@ -67,7 +67,7 @@ type BlueprintInfo struct {
 	OrgPolicyChecks []BlueprintOrgPolicyCheck `json:"orgPolicyChecks,omitempty" yaml:"orgPolicyChecks,omitempty"`
 
 	// A configuration of fixed and dynamic GCP quotas that apply to the blueprint.
-	// Manually authored.
+	// Estimated from compute resources and can be manually authored.
 	QuotaDetails []BlueprintQuotaDetail `json:"quotaDetails,omitempty" yaml:"quotaDetails,omitempty"`
 
 	// Details on the author producing the blueprint.
@@ -88,11 +88,18 @@ type BlueprintRepoDetail struct {
 	// Can be manually overridden with a custom URL if needed.
 	Repo string `json:"repo" yaml:"repo"`
 
-	// Set as "git" for now until more types are supported.
+	// Type of the source i.e. one of "git", "registry" for Terraform
+	// registry addresses e.g. terraform-google-modules/cloud-storage/google
+	// or "oci" for OCI/kpt package references. Defaults to "git".
 	SourceType string `json:"sourceType" yaml:"sourceType"`
 
 	// optional directory location for a submodule
 	Dir string `json:"dir,omitempty" yaml:"dir,omitempty"`
+
+	// Autogenerated: the tag of the checked out commit for git sources.
+	// Empty if the commit isn't tagged.
+	// The version for registry sources and the tag or digest for OCI sources.
+	Ref string `json:"ref,omitempty" yaml:"ref,omitempty"`
 }
 
 type BlueprintActuationTool struct {
@@ -174,7 +181,7 @@ type BlueprintCloudProduct struct {
 // BlueprintOrgPolicyCheck defines GCP org policies to be checked
 // for successful deployment
 type BlueprintOrgPolicyCheck struct {
-	// Id for the policy e.g. "compute-vmExternalIpAccess"
+	// Id for the policy e.g. "compute.vmExternalIpAccess"
 	PolicyId string `json:"policyId" yaml:"policyId"`
 
 	// If not set, it is assumed any version of this org policy
@@ -276,8 +283,11 @@ type BlueprintSupport struct {
 // BlueprintContent defines the detail for blueprint related content such as
 // related documentation, diagrams, examples etc.
 type BlueprintContent struct {
-	// Diagrams are manually entered.
-	Architecture  BlueprintArchitecture  `json:"architecture,omitempty" yaml:"architecture,omitempty"`
+	Architecture BlueprintArchitecture `json:"architecture,omitempty" yaml:"architecture,omitempty"`
+
+	// Diagrams are discovered from images in README.md and, for root modules,
+	// image files in the assets/ dir. Manually entered diagrams are kept and
+	// autogenerated ones that are no longer found are removed.
 	Diagrams      []BlueprintDiagram     `json:"diagrams,omitempty" yaml:"diagrams,omitempty"`
 	Documentation []BlueprintListContent `json:"documentation,omitempty" yaml:"documentation,omitempty"`
 	SubBlueprints []BlueprintMiscContent `json:"subBlueprints,omitempty" yaml:"subBlueprints,omitempty"`
@@ -297,11 +307,63 @@ type BlueprintInterface struct {
 type BlueprintRequirements struct {
 	Roles    []BlueprintRoles `json:"roles,omitempty" yaml:"roles,omitempty"`
 	Services []string         `json:"services,omitempty" yaml:"services,omitempty"`
+
+	// Dependencies are the external modules used by the blueprint.
+	// Autogenerated: From the module blocks in the blueprint's config
+	// with a remote source.
+	Dependencies []BlueprintDependency `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
+
+	// Providers are the providers required by the blueprint.
+	// Autogenerated: From the `required_providers` in the `terraform`
+	// block of versions.tf.
+	Providers []BlueprintProvider `json:"providers,omitempty" yaml:"providers,omitempty"`
+}
+
+// BlueprintProvider is a provider required by a blueprint.
+type BlueprintProvider struct {
+	// Local name of the provider e.g. "google-beta".
+	Name string `json:"name" yaml:"name"`
+
+	// Source address of the provider e.g. "hashicorp/google-beta".
+	Source string `json:"source,omitempty" yaml:"source,omitempty"`
+
+	// Version constraint of the provider e.g. ">= 4.4.0, < 5.0".
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+
+	// Configuration aliases the blueprint expects to be passed
+	// e.g. "google.us-central1".
+	ConfigurationAliases []string `json:"configurationAliases,omitempty" yaml:"configurationAliases,omitempty"`
+}
+
+// BlueprintDependency is an external module used by a blueprint.
+type BlueprintDependency struct {
+	// Source of the module as set in the module block e.g.
+	// "terraform-google-modules/network/google".
+	Source string `json:"source" yaml:"source"`
+
+	// Version constraint of the module e.g. "~> 7.0". Only set for
+	// registry modules, which are otherwise unpinned.
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
 }
 
 type BlueprintMiscContent struct {
 	Name     string `json:"name" yaml:"name"`
 	Location string `json:"location,omitempty" yaml:"location,omitempty"`
+
+	// Modules are the blueprint and sub-blueprints used by an example
+	// through its module blocks. Only set for examples.
+	Modules []BlueprintExampleModule `json:"modules,omitempty" yaml:"modules,omitempty"`
+}
+
+// BlueprintExampleModule is the blueprint or a sub-blueprint used by an
+// example along with the inputs the example sets for it.
+type BlueprintExampleModule struct {
+	// Location of the module relative to the root module i.e. "." for
+	// the root module or e.g. "modules/private-cluster" for a sub-blueprint.
+	Location string `json:"location" yaml:"location"`
+
+	// Inputs are the names of the variables set by the example.
+	Inputs []string `json:"inputs,omitempty" yaml:"inputs,omitempty"`
 }
 
 type BlueprintArchitecture struct {
@@ -309,7 +371,8 @@ type BlueprintArchitecture struct {
 	Description []string `json:"description" yaml:"description"`
 }
 
-// BlueprintDiagram is manually entered.
+// BlueprintDiagram is an image of the blueprint. Local diagrams are named by
+// their path relative to the root module and URLs are used as is.
 type BlueprintDiagram struct {
 	Name        string `json:"name" yaml:"name"`
 	AltText     string `json:"altText,omitempty" yaml:"altText,omitempty"`
@@ -318,7 +381,7 @@ type BlueprintDiagram struct {
 
 type BlueprintListContent struct {
 	Title string `json:"title" yaml:"title"`
-	URL   string `json:"url,omitempty" yaml:url,omitempty"`
+	URL   string `json:"url,omitempty" yaml:"url,omitempty"`
 }
 
 type BlueprintVariable struct {
@@ -327,6 +390,17 @@ type BlueprintVariable struct {
 	VarType      string      `json:"varType,omitempty" yaml:"varType,omitempty"`
 	DefaultValue interface{} `json:"defaultValue,omitempty" yaml:"defaultValue,omitempty"`
 	Required     bool        `json:"required,omitempty" yaml:"required,omitempty"`
+
+	// Sensitive is set if the variable is marked as sensitive.
+	Sensitive bool `json:"sensitive,omitempty" yaml:"sensitive,omitempty"`
+
+	// Nullable is only set if it is explicitly declared for the variable.
+	// Variables are nullable by default.
+	Nullable *bool `json:"nullable,omitempty" yaml:"nullable,omitempty"`
+
+	// TypeInfo is the structured form of VarType.
+	// Autogenerated from the variable's type constraint.
+	TypeInfo *BlueprintType `json:"typeInfo,omitempty" yaml:"typeInfo,omitempty"`
 }
 
 // BlueprintVariableGroup is manually entered.
@@ -339,9 +413,61 @@ type BlueprintVariableGroup struct {
 type BlueprintOutput struct {
 	Name        string `json:"name" yaml:"name"`
 	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	// Sensitive is set if the output is marked as sensitive.
+	Sensitive bool `json:"sensitive,omitempty" yaml:"sensitive,omitempty"`
+
+	// TypeInfo is the type of the output value. It is only set when the
+	// type can be inferred from the value i.e. if it is a variable or a
+	// type conversion e.g. tostring().
+	TypeInfo *BlueprintType `json:"typeInfo,omitempty" yaml:"typeInfo,omitempty"`
+}
+
+type TypeKind string
+
+const (
+	TypeKindString TypeKind = "string"
+	TypeKindNumber TypeKind = "number"
+	TypeKindBool   TypeKind = "bool"
+	TypeKindAny    TypeKind = "any"
+	TypeKindList   TypeKind = "list"
+	TypeKindSet    TypeKind = "set"
+	TypeKindMap    TypeKind = "map"
+	TypeKindTuple  TypeKind = "tuple"
+	TypeKindObject TypeKind = "object"
+)
+
+// BlueprintType is a structured Terraform type constraint e.g.
+// list(object({name=string, ttl=optional(number, 60)})).
+type BlueprintType struct {
+	Kind TypeKind `json:"kind" yaml:"kind" jsonschema:"enum=string,enum=number,enum=bool,enum=any,enum=list,enum=set,enum=map,enum=tuple,enum=object"`
+
+	// ElementType is the type of the elements for lists, sets and maps.
+	ElementType *BlueprintType `json:"elementType,omitempty" yaml:"elementType,omitempty"`
+
+	// TupleTypes are the types of the elements of a tuple in order.
+	TupleTypes []*BlueprintType `json:"tupleTypes,omitempty" yaml:"tupleTypes,omitempty"`
+
+	// Attributes of an object sorted by name.
+	Attributes []BlueprintTypeAttribute `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+// BlueprintTypeAttribute is an attribute of an object type.
+type BlueprintTypeAttribute struct {
+	Name string         `json:"name" yaml:"name"`
+	Type *BlueprintType `json:"type" yaml:"type"`
+
+	// Optional is set for attributes declared with optional().
+	Optional bool `json:"optional,omitempty" yaml:"optional,omitempty"`
+
+	// DefaultValue is the default for an optional attribute if one
+	// is declared.
+	DefaultValue interface{} `json:"defaultValue,omitempty" yaml:"defaultValue,omitempty"`
 }
 
 type BlueprintRoles struct {
+	// Level is where the roles must be granted i.e. one of
+	// Organization, Folder, BillingAccount or Project.
 	Level string   `json:"level" yaml:"level"`
 	Roles []string `json:"roles" yaml:"roles"`
 }
@@ -23,6 +23,10 @@ type BlueprintUIInput struct {
 
 // Additional display specific metadata pertaining to a particular
 // input variable.
+// Validation related fields i.e. RegExValidation, lengths, items, min/max
+// and EnumValueLabels are autogenerated from the conditions in the variable's
+// validation blocks and refreshed on every run, unless they are manually
+// authored.
 type DisplayVariable struct {
 	// The variable name from the corresponding standard metadata file.
 	Name string `json:"name" yaml:"name"`
@@ -57,11 +61,14 @@ type DisplayVariable struct {
 	// Max length for string values.
 	MaximumLength int `json:"maxLength,omitempty" yaml:"maxLength,omitempty"`
 
-	// Minimum value for numeric types.
-	Minimum int `json:"min,omitempty" yaml:"min,omitempty"`
+	// Minimum value for numeric types. Unset if the value isn't bounded.
+	Minimum *int `json:"min,omitempty" yaml:"min,omitempty"`
 
-	// Max value for numeric types.
-	Maximum int `json:"max,omitempty" yaml:"max,omitempty"`
+	// Max value for numeric types. Unset if the value isn't bounded.
+	Maximum *int `json:"max,omitempty" yaml:"max,omitempty"`
+
+	// Labels for enum values.
+	EnumValueLabels []ValueLabel `json:"enumValueLabels,omitempty" yaml:"enumValueLabels,omitempty"`
 
 	// The name of a section to which this variable belongs.
 	// variables belong to the root section if this field is
@@ -69,6 +76,8 @@ type DisplayVariable struct {
 	Section string `json:"section,omitempty" yaml:"section,omitempty"`
 
 	// UI extension associated with the input variable.
+	// Autogenerated: Proposed from the variable's name, type and the resource
+	// arguments it is passed to, unless manually authored.
 	// E.g. for rendering a GCE machine type selector:
 	//
 	// xGoogleProperty:
@@ -80,6 +89,12 @@ type DisplayVariable struct {
 	XGoogleProperty GooglePropertyExtension `json:"xGoogleProperty,omitempty" yaml:"xGoogleProperty,omitempty"`
 }
 
+// A label for an enum value of an input variable.
+type ValueLabel struct {
+	Label string `json:"label" yaml:"label"`
+	Value string `json:"value" yaml:"value"`
+}
+
 // A logical group of variables. [Section][]s may also be grouped into
 // sub-sections.
 type DisplaySection struct {
@@ -108,6 +123,40 @@ type BlueprintUIOutput struct {
 
 	// List of suggested actions to take.
 	SuggestedActions []UIActionItem `json:"suggestedActions,omitempty" yaml:"suggestedActions,omitempty"`
+
+	// Outputs shown once the blueprint is deployed, keyed by the name
+	// of the output in the core metadata.
+	Outputs map[string]*DisplayOutput `json:"outputs,omitempty" yaml:"outputs,omitempty"`
+}
+
+type DisplayOutputType string
+
+const (
+	DisplayOutputUndefined  DisplayOutputType = "OT_UNDEFINED"
+	DisplayOutputLink       DisplayOutputType = "OT_LINK"
+	DisplayOutputIPAddress  DisplayOutputType = "OT_IP_ADDRESS"
+	DisplayOutputSSHCommand DisplayOutputType = "OT_SSH_COMMAND"
+)
+
+// DisplayOutput describes how an output of the blueprint is shown
+// once it is deployed.
+type DisplayOutput struct {
+	// The name of the output, matching BlueprintOutput.Name.
+	Name string `json:"name" yaml:"name"`
+
+	// Visible title for the output.
+	Title string `json:"title,omitempty" yaml:"title,omitempty"`
+
+	// How the output value is shown e.g. as a link or a copyable
+	// SSH command.
+	Type DisplayOutputType `json:"type,omitempty" yaml:"type,omitempty" jsonschema:"enum=OT_LINK,enum=OT_IP_ADDRESS,enum=OT_SSH_COMMAND,enum=OT_UNDEFINED"`
+
+	// Open a link output in a new tab rather than the current one.
+	OpenInNewTab bool `json:"openInNewTab,omitempty" yaml:"openInNewTab,omitempty"`
+
+	// Show the output in the notification shown once the blueprint
+	// is deployed.
+	ShowInNotification bool `json:"showInNotification,omitempty" yaml:"showInNotification,omitempty"`
 }
 
 // An item appearing in a list of required or suggested steps.
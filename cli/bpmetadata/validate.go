@@ -3,9 +3,11 @@ package bpmetadata
 import (
 	_ "embed"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
+	"strings"
 
 	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/cli/util"
 	"github.com/xeipuuv/gojsonschema"
@@ -15,10 +17,17 @@ import (
 //go:embed schema/bpmetadataschema.json
 var s []byte
 
+// contextDelimiter is used to split the path for schema errors since
+// keys in metadata (e.g. annotations) may contain dots
+const contextDelimiter = "\x1f"
+
 // validateMetadata validates the metadata files for the provided
 // blueprint path. This validation occurs for top-level blueprint
-// metadata and blueprints in the modules/ folder, if present
-func validateMetadata(bpPath, wdPath string) error {
+// metadata and blueprints in the modules/ folder, if present.
+// Validation results are written to w in the provided format, including
+// errors that stop a file from being validated so that the report is
+// always complete.
+func validateMetadata(w io.Writer, bpPath, wdPath, format string) error {
 	// load schema from the binary
 	schemaLoader := gojsonschema.NewStringLoader(string(s))
 
@@ -27,9 +36,38 @@ func validateMetadata(bpPath, wdPath string) error {
 		bpPath = path.Join(wdPath, bpPath)
 	}
 
+	var vErrs []error
+	var findings []validationFinding
+
+	// addResult records the findings and error of a validation step. An
+	// error without findings is reported as a finding for the file.
+	addResult := func(file, rule string, f []validationFinding, err error) {
+		findings = append(findings, f...)
+		if err == nil {
+			return
+		}
+
+		vErrs = append(vErrs, err)
+		if len(f) == 0 {
+			findings = append(findings, validationFinding{File: file, Rule: rule, Message: err.Error()})
+		}
+	}
+
+	cfg, err := loadMetadataConfig(bpPath)
+	if err != nil {
+		cfgPath, _ := findMetadataConfig(bpPath)
+		if cfgPath == "" {
+			cfgPath = path.Join(bpPath, metadataConfigFileName)
+		}
+
+		Log.Error("metadata config validation failed", "err", err)
+		addResult(cfgPath, "config", nil, err)
+		cfg = defaultMetadataConfig()
+	}
+
 	moduleDirs := []string{bpPath}
-	modulesPath := path.Join(bpPath, modulesPath)
-	_, err := os.Stat(modulesPath)
+	modulesPath := path.Join(bpPath, cfg.ModulesPath)
+	_, err = os.Stat(modulesPath)
 	if err == nil {
 		subModuleDirs, err := util.WalkTerraformDirs(modulesPath)
 		if err != nil {
@@ -39,7 +77,6 @@ func validateMetadata(bpPath, wdPath string) error {
 		moduleDirs = append(moduleDirs, subModuleDirs...)
 	}
 
-	var vErrs []error
 	for _, d := range moduleDirs {
 		// validate core metadata
 		core := path.Join(d, metadataFileName)
@@ -51,12 +88,29 @@ func validateMetadata(bpPath, wdPath string) error {
 			continue
 		}
 
-		err = validateMetadataYaml(core, schemaLoader)
+		f, err := validateMetadataYaml(core, schemaLoader)
 		if err != nil {
-			vErrs = append(vErrs, err)
 			Log.Error("core metadata validation failed", "err", err)
 		}
 
+		addResult(core, "schema", f, err)
+
+		// validate references across core and display metadata
+		f, err = validateMetadataReferences(d)
+		if err != nil {
+			Log.Error("metadata reference validation failed", "err", err)
+		}
+
+		addResult(core, "reference", f, err)
+
+		// validate local assets referenced by core metadata, which are
+		// only reported as warnings
+		findings = append(findings, validateMetadataAssets(d, bpPath)...)
+
+		// validate that external modules are pinned, which is only
+		// reported as warnings
+		findings = append(findings, validateMetadataDependencies(d)...)
+
 		// validate display metadata
 		disp := path.Join(d, metadataDisplayFileName)
 		_, err = os.Stat(disp)
@@ -67,11 +121,44 @@ func validateMetadata(bpPath, wdPath string) error {
 			continue
 		}
 
-		err = validateMetadataYaml(disp, schemaLoader)
+		f, err = validateMetadataYaml(disp, schemaLoader)
 		if err != nil {
-			vErrs = append(vErrs, err)
 			Log.Error("display metadata validation failed", "err", err)
 		}
+
+		addResult(disp, "schema", f, err)
+	}
+
+	// validate that the provider versions required across the root
+	// module and submodules can be satisfied together
+	core := path.Join(bpPath, metadataFileName)
+	f, err := validateProviderConstraints(bpPath, moduleDirs, cfg.ModulesPath)
+	if err != nil {
+		Log.Error("provider validation failed", "err", err)
+	}
+
+	addResult(core, "provider", f, err)
+
+	// validate that the versions declared across the blueprint agree,
+	// reported at the version in core metadata
+	if err := checkVersionConsistency(bpPath, cfg); err != nil {
+		versionField := []string{"spec", "info", "version"}
+		line, col := fieldPosition(core, versionField)
+		f := validationFinding{
+			File:    core,
+			Line:    line,
+			Column:  col,
+			Field:   strings.Join(versionField, "."),
+			Rule:    "version",
+			Message: err.Error(),
+		}
+
+		Log.Error("version validation error", "path", f.location(), "err", f.Message)
+		addResult(core, "version", []validationFinding{f}, err)
+	}
+
+	if err := writeFindings(w, findings, format, wdPath); err != nil {
+		return fmt.Errorf("error writing validation results: %w", err)
 	}
 
 	if len(vErrs) > 0 {
@@ -81,12 +168,13 @@ func validateMetadata(bpPath, wdPath string) error {
 	return nil
 }
 
-// validateMetadata validates an individual yaml file present at path "m"
-func validateMetadataYaml(m string, schema gojsonschema.JSONLoader) error {
+// validateMetadataYaml validates an individual yaml file present at path "m"
+// and returns a finding for each schema error with its position in the file
+func validateMetadataYaml(m string, schema gojsonschema.JSONLoader) ([]validationFinding, error) {
 	// prepare metadata for validation by converting it from YAML to JSON
 	mBytes, err := convertYamlToJson(m)
 	if err != nil {
-		return fmt.Errorf("yaml to json conversion failed for metadata at path %s. error: %s", m, err)
+		return nil, fmt.Errorf("yaml to json conversion failed for metadata at path %s. error: %s", m, err)
 	}
 
 	// load metadata from the path
@@ -95,19 +183,51 @@ func validateMetadataYaml(m string, schema gojsonschema.JSONLoader) error {
 	// validate metadata against the schema
 	result, err := gojsonschema.Validate(schema, yamlLoader)
 	if err != nil {
-		return fmt.Errorf("metadata validation failed for %s. error: %s", m, err)
+		return nil, fmt.Errorf("metadata validation failed for %s. error: %s", m, err)
 	}
 
 	if !result.Valid() {
+		var findings []validationFinding
 		for _, e := range result.Errors() {
-			Log.Error("validation error", "err", e)
+			f := schemaFinding(m, e)
+			Log.Error("validation error", "path", f.location(), "err", e)
+			findings = append(findings, f)
 		}
 
-		return fmt.Errorf("metdata validation failed for: %s", m)
+		return findings, fmt.Errorf("metdata validation failed for: %s", m)
 	}
 
 	Log.Info("metadata is valid", "path", m)
-	return nil
+	return nil, nil
+}
+
+// schemaFinding maps a schema error for the metadata at path "m" back
+// to the position of the offending node in the YAML file
+func schemaFinding(m string, e gojsonschema.ResultError) validationFinding {
+	// the context is a path from the document root e.g. (root).spec.info
+	var field []string
+	if e.Context() != nil {
+		field = strings.Split(e.Context().String(contextDelimiter), contextDelimiter)
+		if len(field) > 0 && field[0] == gojsonschema.STRING_CONTEXT_ROOT {
+			field = field[1:]
+		}
+	}
+
+	// errors for a specific property (e.g. additional properties) are
+	// reported against the parent object, so point at the property instead
+	if p, ok := e.Details()["property"].(string); ok && e.Type() != "required" {
+		field = append(field, p)
+	}
+
+	line, col := fieldPosition(m, field)
+	return validationFinding{
+		File:    m,
+		Line:    line,
+		Column:  col,
+		Field:   e.Field(),
+		Rule:    "schema/" + e.Type(),
+		Message: e.Description(),
+	}
 }
 
 // prepares metadata bytes for validation since direct
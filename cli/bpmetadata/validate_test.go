@@ -1,9 +1,14 @@
 package bpmetadata
 
 import (
+	"bytes"
+	"encoding/json"
+	"os"
 	"path"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/xeipuuv/gojsonschema"
 )
 
@@ -48,7 +53,7 @@ func TestValidateMetadata(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := validateMetadataYaml(path.Join(yamlTestDirPath, tt.path), s)
+			_, err := validateMetadataYaml(path.Join(yamlTestDirPath, tt.path), s)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("validateMetadataYaml() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -56,3 +61,70 @@ func TestValidateMetadata(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateMetadataReportsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		format   string
+		wantFile string
+		wantRule string
+	}{
+		{
+			name:     "unparseable metadata",
+			files:    map[string]string{metadataFileName: "spec: [\n"},
+			format:   formatJSON,
+			wantFile: metadataFileName,
+			wantRule: "schema",
+		},
+		{
+			name: "invalid config",
+			files: map[string]string{
+				metadataConfigFileName: "rolesfile: iam.tf\n",
+				metadataFileName:       "apiVersion: blueprints.cloud.google.com/v1alpha1\n",
+			},
+			format:   formatSARIF,
+			wantFile: metadataConfigFileName,
+			wantRule: "config",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.MkdirAll(path.Join(dir, ".git"), 0755))
+			for name, content := range tt.files {
+				require.NoError(t, os.MkdirAll(path.Dir(path.Join(dir, name)), 0755))
+				require.NoError(t, os.WriteFile(path.Join(dir, name), []byte(content), 0644))
+			}
+
+			var b bytes.Buffer
+			assert.Error(t, validateMetadata(&b, dir, dir, tt.format))
+
+			// a well-formed report is written even though the file
+			// couldn't be validated
+			var files, rules []string
+			if tt.format == formatSARIF {
+				var log sarifLog
+				require.NoError(t, json.Unmarshal(b.Bytes(), &log))
+				for _, r := range log.Runs[0].Results {
+					files = append(files, r.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+					rules = append(rules, r.RuleID)
+				}
+			} else {
+				var report struct {
+					Findings []validationFinding `json:"findings"`
+				}
+
+				require.NoError(t, json.Unmarshal(b.Bytes(), &report))
+				for _, f := range report.Findings {
+					files = append(files, f.File)
+					rules = append(rules, f.Rule)
+				}
+			}
+
+			assert.Contains(t, files, tt.wantFile)
+			assert.Contains(t, rules, tt.wantRule)
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package bpmetadata
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/cli/util"
+)
+
+const (
+	releasePleaseManifestFileName = ".release-please-manifest.json"
+	changelogFileName             = "CHANGELOG.md"
+)
+
+// changelogVersionRegEx matches release headings in a changelog
+// e.g. "## [1.2.0](https://github.com/foo/bar/compare/v1.1.0...v1.2.0)"
+// or "## 1.2.0 (2023-01-01)"
+var changelogVersionRegEx = regexp.MustCompile(`^#{1,3}\s+\[?v?([0-9]+\.[0-9]+\.[0-9]+[0-9A-Za-z.+-]*)\]?`)
+
+// getFallbackVersion resolves the version for a blueprint that doesn't
+// declare one through provider_meta. The version is read from the nearest
+// semver tag with the blueprint's tagPrefix, the release-please manifest
+// and the CHANGELOG in that order and an empty string is returned if none
+// of them have one.
+func getFallbackVersion(rootPath, tagPrefix string) string {
+	resolvers := []struct {
+		name    string
+		resolve func(string) (string, error)
+	}{
+		{name: "git tag", resolve: func(p string) (string, error) { return getTagVersion(p, tagPrefix) }},
+		{name: releasePleaseManifestFileName, resolve: getManifestVersion},
+		{name: changelogFileName, resolve: getChangelogVersion},
+	}
+
+	for _, r := range resolvers {
+		v, err := r.resolve(rootPath)
+		if err != nil {
+			Log.Debug("unable to resolve blueprint version", "source", r.name, "err", err)
+			continue
+		}
+
+		if v != "" {
+			Log.Info("blueprint version not set in provider_meta, using fallback", "source", r.name, "version", v)
+			return v
+		}
+	}
+
+	return ""
+}
+
+// getTagVersion returns the version from the nearest semver tag with the
+// given prefix. Packages fetched with kpt use the ref in their Kptfile since
+// the repo they are in isn't the source of the package.
+func getTagVersion(rootPath, tagPrefix string) (string, error) {
+	kptSrc, err := getKptSource(rootPath)
+	if err != nil {
+		return "", err
+	}
+
+	if kptSrc != nil {
+		if kptSrc.SourceType == sourceTypeGit && util.IsSemver(kptSrc.Ref) {
+			return strings.TrimPrefix(kptSrc.Ref, "v"), nil
+		}
+
+		return "", nil
+	}
+
+	tag, err := gitTags.NearestSemverTag(rootPath, tagPrefix)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimPrefix(strings.TrimPrefix(tag, tagPrefix), "v"), nil
+}
+
+// getTagPrefix returns the prefix of the release tags for the blueprint at
+// rootPath. Unless set in the config, blueprints at the root of a repo are
+// tagged without a prefix and ones in a monorepo with the name of their dir
+// e.g. "foo-v1.2.0".
+func getTagPrefix(rootPath string, cfg *metadataConfig) string {
+	if cfg.TagPrefix != "" {
+		return cfg.TagPrefix
+	}
+
+	absPath, err := filepath.Abs(rootPath)
+	if err != nil {
+		return ""
+	}
+
+	repoRoot, err := util.GetRepoRoot(absPath)
+	if err != nil {
+		return ""
+	}
+
+	rel, err := filepath.Rel(repoRoot, absPath)
+	if err != nil || rel == "." {
+		return ""
+	}
+
+	return filepath.Base(rel) + "-"
+}
+
+// getManifestVersion returns the version of the blueprint at rootPath from
+// the nearest release-please manifest in rootPath or its parents up to the
+// root of the repo. Manifests are keyed by the path of each package
+// relative to the manifest.
+func getManifestVersion(rootPath string) (string, error) {
+	dir := path.Clean(rootPath)
+	for {
+		b, err := os.ReadFile(path.Join(dir, releasePleaseManifestFileName))
+		if err == nil {
+			manifest := make(map[string]string)
+			if err := json.Unmarshal(b, &manifest); err != nil {
+				return "", fmt.Errorf("unable to parse %s: %w", releasePleaseManifestFileName, err)
+			}
+
+			pkg, err := filepath.Rel(dir, rootPath)
+			if err != nil {
+				return "", err
+			}
+
+			return strings.TrimPrefix(manifest[pkg], "v"), nil
+		}
+
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("unable to read %s: %w", releasePleaseManifestFileName, err)
+		}
+
+		// stop at the root of the repo
+		if _, err := os.Stat(path.Join(dir, ".git")); err == nil {
+			return "", nil
+		}
+
+		parent := path.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+
+		dir = parent
+	}
+}
+
+// getChangelogVersion returns the version of the latest release in the
+// CHANGELOG at rootPath
+func getChangelogVersion(rootPath string) (string, error) {
+	f, err := os.Open(path.Join(rootPath, changelogFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s: %w", changelogFileName, err)
+	}
+
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if m := changelogVersionRegEx.FindStringSubmatch(line); m != nil {
+			return m[1], nil
+		}
+	}
+
+	return "", s.Err()
+}
+
+// checkVersionConsistency checks that the versions declared for the
+// blueprint at rootPath agree. These include the provider_meta versions
+// of the root module and its submodules, the version in their metadata
+// on disk and the semver tag with the blueprint's prefix on the commit that
+// is checked out, if any. The tag is only considered on tagged commits so
+// that release changes that bump versions can be checked before they are
+// tagged.
+func checkVersionConsistency(rootPath string, cfg *metadataConfig) error {
+	versions := make(map[string][]string)
+	add := func(version, source string) {
+		version = strings.TrimPrefix(version, "v")
+		if version != "" {
+			versions[version] = append(versions[version], source)
+		}
+	}
+
+	modPaths := []string{rootPath}
+	modulesPathForBp := path.Join(rootPath, cfg.ModulesPath)
+	if _, err := os.Stat(modulesPathForBp); err == nil {
+		moduleDirs, err := util.WalkTerraformDirs(modulesPathForBp)
+		if err != nil {
+			return err
+		}
+
+		modPaths = append(modPaths, moduleDirs...)
+	}
+
+	for _, modPath := range modPaths {
+		name := getBpSubmoduleName(modPath, cfg.ModulesPath)
+		if name == "" {
+			name = "root module"
+		}
+
+		versionsFile := path.Join(modPath, tfVersionsFileName)
+		if _, err := os.Stat(versionsFile); err == nil {
+			v, err := getBlueprintVersion(versionsFile)
+			if err != nil {
+				return fmt.Errorf("error reading version for %s: %w", name, err)
+			}
+
+			add(v.moduleVersion, fmt.Sprintf("provider_meta of %s", name))
+		}
+
+		if _, err := os.Stat(path.Join(modPath, metadataFileName)); err == nil {
+			bpObj, err := UnmarshalMetadata(modPath, metadataFileName)
+			if err != nil {
+				return fmt.Errorf("error reading %s for %s: %w", metadataFileName, name, err)
+			}
+
+			add(bpObj.Spec.Info.Version, fmt.Sprintf("%s of %s", metadataFileName, name))
+		}
+	}
+
+	if kptSrc, err := getKptSource(rootPath); err == nil && kptSrc == nil {
+		prefix := getTagPrefix(rootPath, cfg)
+		if tag, err := gitTags.HeadSemverTag(rootPath, prefix); err == nil && tag != "" {
+			add(strings.TrimPrefix(tag, prefix), fmt.Sprintf("git tag %s", tag))
+		}
+	}
+
+	if len(versions) <= 1 {
+		return nil
+	}
+
+	var found []string
+	for v, sources := range versions {
+		found = append(found, fmt.Sprintf("  %s: %s", v, strings.Join(sources, ", ")))
+	}
+
+	sort.Strings(found)
+	return fmt.Errorf("inconsistent versions for blueprint at path: %s\n%s", rootPath, strings.Join(found, "\n"))
+}
@@ -0,0 +1,254 @@
+package bpmetadata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const versionsTfTemplate = `terraform {
+  required_version = ">= 0.13"
+  provider_meta "google" {
+    module_name = "blueprints/terraform/terraform-google-bp%s/v%s"
+  }
+}
+`
+
+func TestGetFallbackVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		tag       string
+		manifest  string
+		changelog string
+		kptfile   string
+		want      string
+	}{
+		{
+			name: "none",
+			want: "",
+		},
+		{
+			name:      "changelog",
+			changelog: "# Changelog\n\n## [1.2.0](https://github.com/foo/bar/compare/v1.1.0...v1.2.0) (2023-01-01)\n\n## [1.1.0](https://github.com/foo/bar/compare/v1.0.0...v1.1.0)\n",
+			want:      "1.2.0",
+		},
+		{
+			name:      "plain changelog heading",
+			changelog: "# Changelog\n\n### v0.3.1 (2023-01-01)\n",
+			want:      "0.3.1",
+		},
+		{
+			name:      "manifest over changelog",
+			manifest:  `{".": "1.3.0"}`,
+			changelog: "## [1.2.0]\n",
+			want:      "1.3.0",
+		},
+		{
+			name:      "tag over manifest",
+			tag:       "v1.4.0",
+			manifest:  `{".": "1.3.0"}`,
+			changelog: "## [1.2.0]\n",
+			want:      "1.4.0",
+		},
+		{
+			name: "kpt ref over tag",
+			tag:  "v1.4.0",
+			kptfile: `upstreamLock:
+  type: git
+  git:
+    repo: https://github.com/foo/terraform-google-bp
+    ref: v2.0.0
+`,
+			want: "2.0.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := tempGitRepo(t, tt.tag)
+			files := map[string]string{
+				releasePleaseManifestFileName: tt.manifest,
+				changelogFileName:             tt.changelog,
+				kptFileName:                   tt.kptfile,
+			}
+
+			for name, content := range files {
+				if content != "" {
+					require.NoError(t, os.WriteFile(path.Join(dir, name), []byte(content), 0644))
+				}
+			}
+
+			assert.Equal(t, tt.want, getFallbackVersion(dir, ""))
+		})
+	}
+}
+
+func TestGetManifestVersion(t *testing.T) {
+	dir := tempGitRepo(t, "")
+	bpPath := path.Join(dir, "blueprints", "bp")
+	require.NoError(t, os.MkdirAll(bpPath, 0755))
+	require.NoError(t, os.WriteFile(path.Join(dir, releasePleaseManifestFileName), []byte(`{".": "9.0.0", "blueprints/bp": "0.2.0"}`), 0644))
+
+	got, err := getManifestVersion(bpPath)
+	require.NoError(t, err)
+	assert.Equal(t, "0.2.0", got)
+}
+
+func TestCheckVersionConsistency(t *testing.T) {
+	tests := []struct {
+		name           string
+		tag            string
+		rootVersion    string
+		modVersion     string
+		metaVersion    string
+		wantErrSources []string
+	}{
+		{
+			name:        "consistent",
+			tag:         "v1.0.0",
+			rootVersion: "1.0.0",
+			modVersion:  "1.0.0",
+			metaVersion: "1.0.0",
+		},
+		{
+			name:        "untagged",
+			rootVersion: "1.1.0",
+			modVersion:  "1.1.0",
+		},
+		{
+			name:           "submodule disagrees",
+			rootVersion:    "1.1.0",
+			modVersion:     "1.0.0",
+			wantErrSources: []string{"1.0.0: provider_meta of bar", "1.1.0: provider_meta of root module"},
+		},
+		{
+			name:           "tag disagrees",
+			tag:            "v1.0.0",
+			rootVersion:    "1.1.0",
+			modVersion:     "1.1.0",
+			wantErrSources: []string{"1.0.0: git tag v1.0.0"},
+		},
+		{
+			name:           "metadata disagrees",
+			rootVersion:    "1.1.0",
+			modVersion:     "1.1.0",
+			metaVersion:    "1.0.0",
+			wantErrSources: []string{"1.0.0: metadata.yaml of root module"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := tempGitRepo(t, tt.tag)
+			modPath := path.Join(dir, modulesPath, "bar")
+			require.NoError(t, os.MkdirAll(modPath, 0755))
+			require.NoError(t, os.WriteFile(path.Join(dir, tfVersionsFileName), []byte(fmt.Sprintf(versionsTfTemplate, "", tt.rootVersion)), 0644))
+			require.NoError(t, os.WriteFile(path.Join(modPath, tfVersionsFileName), []byte(fmt.Sprintf(versionsTfTemplate, ":bar", tt.modVersion)), 0644))
+			if tt.metaVersion != "" {
+				bpObj := &BlueprintMetadata{
+					ResourceMeta: yaml.ResourceMeta{TypeMeta: yaml.TypeMeta{APIVersion: metadataApiVersion, Kind: metadataKind}},
+					Spec:         BlueprintMetadataSpec{Info: BlueprintInfo{Version: tt.metaVersion}},
+				}
+				require.NoError(t, WriteMetadata(bpObj, dir, metadataFileName))
+			}
+
+			err := checkVersionConsistency(dir, defaultMetadataConfig())
+			if len(tt.wantErrSources) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			for _, s := range tt.wantErrSources {
+				assert.Contains(t, err.Error(), s)
+			}
+		})
+	}
+}
+
+func TestGetTagPrefix(t *testing.T) {
+	dir := tempGitRepo(t, "")
+	bpPath := path.Join(dir, "blueprints", "bp")
+	require.NoError(t, os.MkdirAll(bpPath, 0755))
+
+	assert.Equal(t, "", getTagPrefix(dir, defaultMetadataConfig()))
+	assert.Equal(t, "bp-", getTagPrefix(bpPath, defaultMetadataConfig()))
+	assert.Equal(t, "bp/", getTagPrefix(bpPath, &metadataConfig{TagPrefix: "bp/"}))
+}
+
+func TestMonorepoVersions(t *testing.T) {
+	dir := tempGitRepo(t, "v9.0.0")
+	r, err := git.PlainOpen(dir)
+	require.NoError(t, err)
+	head, err := r.Head()
+	require.NoError(t, err)
+	_, err = r.CreateTag("bp-v1.0.0", head.Hash(), nil)
+	require.NoError(t, err)
+
+	bpPath := path.Join(dir, "blueprints", "bp")
+	require.NoError(t, os.MkdirAll(bpPath, 0755))
+	require.NoError(t, os.WriteFile(path.Join(bpPath, tfVersionsFileName), []byte(fmt.Sprintf(versionsTfTemplate, "", "1.0.0")), 0644))
+
+	// tags of other packages in the repo are ignored
+	cfg := defaultMetadataConfig()
+	assert.Equal(t, "1.0.0", getFallbackVersion(bpPath, getTagPrefix(bpPath, cfg)))
+	assert.NoError(t, checkVersionConsistency(bpPath, cfg))
+
+	require.NoError(t, os.WriteFile(path.Join(bpPath, tfVersionsFileName), []byte(fmt.Sprintf(versionsTfTemplate, "", "1.1.0")), 0644))
+	err = checkVersionConsistency(bpPath, cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1.0.0: git tag bp-v1.0.0")
+
+	// inconsistent versions fail validation and are reported at the
+	// version in core metadata
+	require.NoError(t, os.WriteFile(path.Join(bpPath, metadataFileName), []byte("spec:\n  info:\n    version: 1.1.0\n"), 0644))
+	var b bytes.Buffer
+	assert.Error(t, validateMetadata(&b, bpPath, dir, formatJSON))
+
+	var report struct {
+		Findings []validationFinding `json:"findings"`
+	}
+
+	require.NoError(t, json.Unmarshal(b.Bytes(), &report))
+	var versionFindings []validationFinding
+	for _, f := range report.Findings {
+		if f.Rule == "version" {
+			versionFindings = append(versionFindings, f)
+		}
+	}
+
+	require.Len(t, versionFindings, 1)
+	assert.Equal(t, "blueprints/bp/metadata.yaml", versionFindings[0].File)
+	assert.Equal(t, "spec.info.version", versionFindings[0].Field)
+	assert.Equal(t, 3, versionFindings[0].Line)
+}
+
+// tempGitRepo creates a git repo with a single commit that is tagged
+// if a tag is provided
+func tempGitRepo(t *testing.T, tag string) string {
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	h, err := w.Commit("initial", &git.CommitOptions{Author: &object.Signature{Name: "foo", Email: "foo@example.com"}})
+	require.NoError(t, err)
+
+	if tag != "" {
+		_, err = r.CreateTag(tag, h, nil)
+		require.NoError(t, err)
+	}
+
+	return dir
+}
@@ -0,0 +1,6 @@
+resource "google_storage_bucket" "bucket" {
+  project                     = var.project_id
+  name                        = var.name
+  location                    = var.location
+  uniform_bucket_level_access = true
+}
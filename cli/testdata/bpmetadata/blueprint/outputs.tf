@@ -0,0 +1,4 @@
+output "bucket_url" {
+  description = "The URL of the bucket."
+  value       = google_storage_bucket.bucket.url
+}
@@ -0,0 +1,6 @@
+locals {
+  int_required_roles = [
+    "roles/storage.admin",
+    "roles/iam.serviceAccountUser",
+  ]
+}
@@ -0,0 +1,8 @@
+module "project" {
+  source  = "terraform-google-modules/project-factory/google"
+  version = "~> 15.0"
+
+  activate_apis = [
+    "storage-api.googleapis.com",
+  ]
+}
@@ -0,0 +1,20 @@
+variable "project_id" {
+  description = "The ID of the project to create the bucket in."
+  type        = string
+}
+
+variable "name" {
+  description = "The name of the bucket."
+  type        = string
+
+  validation {
+    condition     = length(var.name) >= 3 && length(var.name) <= 63
+    error_message = "The name must be between 3 and 63 characters."
+  }
+}
+
+variable "location" {
+  description = "The location of the bucket."
+  type        = string
+  default     = "US"
+}
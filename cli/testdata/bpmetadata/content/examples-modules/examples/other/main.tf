@@ -0,0 +1,4 @@
+module "local" {
+  source = "./local"
+  name   = "local"
+}
@@ -0,0 +1,21 @@
+module "network" {
+  source  = "terraform-google-modules/network/google"
+  version = "~> 7.0"
+
+  project_id   = var.project_id
+  network_name = "gke-network"
+}
+
+module "gke" {
+  source  = "terraform-google-modules/kubernetes-engine/google//modules/private-cluster"
+  version = "~> 27.0"
+
+  project_id = var.project_id
+  network    = module.network.network_name
+  depends_on = [module.network]
+}
+
+module "nested" {
+  source = "../../modules/private-cluster/modules/nested"
+  name   = "nested"
+}
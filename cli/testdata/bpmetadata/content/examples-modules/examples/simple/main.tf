@@ -0,0 +1,11 @@
+module "gke" {
+  source  = "../.."
+  project = var.project_id
+  region  = "us-central1"
+}
+
+module "gke_beta" {
+  source = "../../"
+  name   = "beta"
+  region = "us-east1"
+}
@@ -0,0 +1,33 @@
+resource "google_compute_instance" "vm" {
+  name         = "vm"
+  zone         = var.location
+  machine_type = var.vm_type
+
+  boot_disk {
+    initialize_params {
+      image = var.image
+      type  = var.boot_disk_type
+      size  = var.boot_disk_size
+    }
+  }
+
+  network_interface {
+    subnetwork = var.subnet_self_link
+  }
+
+  service_account {
+    email  = var.sa
+    scopes = ["cloud-platform"]
+  }
+}
+
+resource "google_compute_firewall" "allow" {
+  name    = "allow"
+  network = var.vpc
+}
+
+resource "google_storage_bucket_object" "startup" {
+  name   = "startup.sh"
+  bucket = var.vm_type
+  source = "startup.sh"
+}
@@ -0,0 +1,42 @@
+variable "folder_roles" {
+  type = list(string)
+  default = [
+    "roles/resourcemanager.folderAdmin",
+  ]
+}
+
+locals {
+  int_required_roles = [
+    "roles/compute.admin",
+    "roles/iam.serviceAccountUser",
+  ]
+
+  admin_roles = [
+    "roles/resourcemanager.organizationAdmin",
+    "roles/orgpolicy.policyAdmin",
+  ]
+}
+
+resource "google_organization_iam_member" "admin" {
+  count = length(local.admin_roles)
+
+  org_id = var.org_id
+  role   = local.admin_roles[count.index]
+  member = "serviceAccount:${google_service_account.int_test.email}"
+}
+
+resource "google_folder_iam_member" "folder" {
+  count = length(var.folder_roles)
+
+  folder = var.folder_id
+  role   = element(var.folder_roles, count.index)
+  member = "serviceAccount:${google_service_account.int_test.email}"
+}
+
+resource "google_project_iam_member" "int_test" {
+  count = length(local.int_required_roles)
+
+  project = var.project_id
+  role    = local.int_required_roles[count.index]
+  member  = "serviceAccount:${google_service_account.int_test.email}"
+}
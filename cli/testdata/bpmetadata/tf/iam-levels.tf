@@ -0,0 +1,46 @@
+locals {
+  int_required_roles = [
+    "roles/compute.admin",
+    "roles/iam.serviceAccountUser",
+  ]
+
+  org_required_roles = [
+    "roles/orgpolicy.policyAdmin",
+  ]
+
+  folder_required_roles = [
+    "roles/resourcemanager.folderAdmin",
+  ]
+
+  billing_roles = [
+    "roles/billing.user",
+  ]
+
+  admin_roles = [
+    "roles/resourcemanager.organizationAdmin",
+  ]
+
+  apis = [
+    "compute.googleapis.com",
+  ]
+}
+
+resource "google_organization_iam_member" "admin" {
+  for_each = toset(local.admin_roles)
+
+  org_id = var.org_id
+  role   = each.value
+  member = "serviceAccount:${google_service_account.int_test.email}"
+}
+
+resource "google_folder_iam_member" "xpn" {
+  folder = var.folder_id
+  role   = "roles/compute.xpnAdmin"
+  member = "serviceAccount:${google_service_account.int_test.email}"
+}
+
+resource "google_billing_account_iam_member" "int_billing_user" {
+  billing_account_id = var.billing_account
+  role               = "roles/billing.user"
+  member             = "serviceAccount:${google_service_account.int_test.email}"
+}
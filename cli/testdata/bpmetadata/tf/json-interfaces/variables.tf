@@ -0,0 +1,5 @@
+variable "labels" {
+  description = "Labels for the resources"
+  type        = unknown(string)
+  nullable    = var.labels_nullable
+}
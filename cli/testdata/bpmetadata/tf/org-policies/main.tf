@@ -0,0 +1,50 @@
+resource "google_compute_instance" "vm" {
+  name           = "vm"
+  machine_type   = "e2-medium"
+  zone           = var.zone
+  can_ip_forward = false
+
+  boot_disk {
+    initialize_params {
+      image = "debian-cloud/debian-11"
+    }
+  }
+
+  network_interface {
+    network = "default"
+
+    dynamic "access_config" {
+      for_each = var.public_ip ? [1] : []
+      content {}
+    }
+  }
+}
+
+resource "google_storage_bucket" "bucket" {
+  name                        = "bucket"
+  location                    = "US"
+  uniform_bucket_level_access = true
+}
+
+module "keys" {
+  source = "./modules/keys"
+}
+
+resource "google_sql_database_instance" "private" {
+  name             = "private"
+  database_version = "POSTGRES_15"
+  region           = "us-central1"
+
+  settings {
+    tier = "db-f1-micro"
+
+    ip_configuration {
+      ipv4_enabled    = false
+      private_network = "default"
+
+      authorized_networks {
+        value = "10.0.0.0/8"
+      }
+    }
+  }
+}
@@ -0,0 +1,7 @@
+resource "google_service_account" "sa" {
+  account_id = "sa"
+}
+
+resource "google_service_account_key" "key" {
+  service_account_id = google_service_account.sa.name
+}
@@ -0,0 +1,8 @@
+variable "zone" {
+  type = string
+}
+
+variable "public_ip" {
+  type    = bool
+  default = false
+}
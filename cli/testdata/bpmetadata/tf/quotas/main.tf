@@ -0,0 +1,45 @@
+resource "google_compute_instance" "web" {
+  count        = var.web_count
+  name         = "web-${count.index}"
+  machine_type = "n2-standard-4"
+  zone         = "us-central1-a"
+
+  boot_disk {
+    initialize_params {
+      image = "debian-cloud/debian-11"
+      size  = 20
+      type  = "pd-balanced"
+    }
+  }
+
+  network_interface {
+    network = "default"
+  }
+}
+
+resource "google_compute_instance_template" "workers" {
+  machine_type = var.worker_machine_type
+
+  disk {
+    source_image = "debian-cloud/debian-11"
+    disk_size_gb = 50
+  }
+
+  network_interface {
+    network = "default"
+  }
+}
+
+resource "google_compute_disk" "data" {
+  for_each = toset(["a", "b", "c"])
+  name     = "data-${each.key}"
+  size     = var.data_disk_size
+  type     = "pd-ssd"
+  zone     = "us-central1-a"
+}
+
+resource "google_compute_disk" "scratch" {
+  name = "scratch"
+  size = 100
+  zone = "us-central1-a"
+}
@@ -0,0 +1,13 @@
+variable "web_count" {
+  type    = number
+  default = 2
+}
+
+variable "worker_machine_type" {
+  type    = string
+  default = "e2-medium"
+}
+
+variable "data_disk_size" {
+  type = number
+}
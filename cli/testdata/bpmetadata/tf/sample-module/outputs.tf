@@ -35,3 +35,13 @@ output "endpoint" {
     google_container_node_pool.pools,
   ]
 }
+
+output "node_pools" {
+  description = "Node pools of the cluster"
+  value       = var.node_pools
+}
+
+output "node_count" {
+  description = "No. of nodes in the cluster"
+  value       = tonumber(local.node_count)
+}
@@ -32,3 +32,20 @@ variable "regional" {
   description = "Whether is a regional cluster"
   default     = true
 }
+
+variable "node_pools" {
+  description = "List of node pools"
+  type = list(object({
+    name       = string
+    node_count = optional(number, 1)
+    labels     = optional(map(string))
+    disks      = optional(tuple([string, number]), ["pd-standard", 100])
+  }))
+  nullable = false
+}
+
+variable "secret" {
+  description = "Secret for the cluster"
+  type        = string
+  sensitive   = true
+}
@@ -0,0 +1,25 @@
+data "google_client_config" "default" {}
+
+data "google_compute_zones" "available" {
+  project = var.project_id
+}
+
+resource "google_storage_bucket" "bucket" {
+  name     = "bucket"
+  location = "US"
+}
+
+resource "google_project_iam_member" "member" {
+  project = var.project_id
+  role    = "roles/viewer"
+  member  = "user:jane@example.com"
+}
+
+module "db" {
+  source = "./modules/db"
+}
+
+module "network" {
+  source  = "terraform-google-modules/network/google"
+  version = "~> 7.0"
+}
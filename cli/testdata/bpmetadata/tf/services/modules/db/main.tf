@@ -0,0 +1,8 @@
+resource "google_sql_database_instance" "db" {
+  name             = "db"
+  database_version = "POSTGRES_14"
+}
+
+resource "google_secret_manager_secret" "password" {
+  secret_id = "db-password"
+}
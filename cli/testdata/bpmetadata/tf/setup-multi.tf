@@ -0,0 +1,18 @@
+module "project" {
+  source  = "terraform-google-modules/project-factory/google"
+  version = "~> 13.0"
+
+  activate_apis = [
+    "compute.googleapis.com",
+    "iam.googleapis.com",
+  ]
+}
+
+module "host_project" {
+  source  = "terraform-google-modules/project-factory/google"
+  version = "~> 13.0"
+
+  activate_apis = [
+    "dns.googleapis.com",
+  ]
+}
@@ -0,0 +1,4 @@
+module "project" {
+  source  = "terraform-google-modules/project-factory/google"
+  version = "~> 13.0"
+}
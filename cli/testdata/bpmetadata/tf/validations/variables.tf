@@ -0,0 +1,135 @@
+variable "name" {
+  description = "The name of the instance"
+  type        = string
+
+  validation {
+    condition     = can(regex("^[a-z][-a-z0-9]*$", var.name)) && length(var.name) <= 30
+    error_message = "The name must start with a letter and be at most 30 characters."
+  }
+
+  validation {
+    condition     = length(var.name) > 3
+    error_message = "The name must be longer than 3 characters."
+  }
+}
+
+variable "bucket_prefix" {
+  description = "Prefix for bucket names"
+  type        = string
+
+  validation {
+    condition     = length(regexall("^[a-z0-9-]+$", var.bucket_prefix)) > 0
+    error_message = "The prefix may only contain lowercase letters, numbers and dashes."
+  }
+}
+
+variable "node_count" {
+  description = "The number of nodes"
+  type        = number
+
+  validation {
+    condition     = (var.node_count >= 1) && (10 >= var.node_count)
+    error_message = "The number of nodes must be between 1 and 10."
+  }
+}
+
+variable "tier" {
+  description = "The service tier"
+  type        = string
+
+  validation {
+    condition     = contains(["BASIC", "STANDARD_HA"], var.tier)
+    error_message = "The tier must be one of BASIC or STANDARD_HA."
+  }
+}
+
+variable "zones" {
+  description = "The zones to deploy to"
+  type        = list(string)
+
+  validation {
+    condition     = length(var.zones) >= 1 && length(var.zones) < 4
+    error_message = "Between 1 and 3 zones must be provided."
+  }
+}
+
+variable "labels" {
+  description = "Labels for resources"
+  type        = map(string)
+  default     = {}
+
+  validation {
+    condition     = var.labels == null || length(var.labels) <= 64
+    error_message = "At most 64 labels are supported."
+  }
+}
+
+variable "disk_size_gb" {
+  description = "The size of the data disk, no disk is created if 0"
+  type        = number
+
+  validation {
+    condition     = var.disk_size_gb >= 0
+    error_message = "The disk size must not be negative."
+  }
+}
+
+variable "account_id" {
+  description = "The id of the service account"
+  type        = string
+
+  validation {
+    condition     = can(regex("^[a-z]", var.account_id))
+    error_message = "The id must start with a letter."
+  }
+
+  validation {
+    condition     = can(regex("[a-z0-9]$", var.account_id))
+    error_message = "The id must end with a letter or number."
+  }
+}
+
+variable "mode" {
+  description = "The deployment mode"
+  type        = string
+
+  validation {
+    condition     = var.mode == "default" || length(var.mode) > 8
+    error_message = "The mode must be default or longer than 8 characters."
+  }
+}
+
+variable "instance_name" {
+  description = "The name of the VM instance"
+  type        = string
+
+  validation {
+    condition     = length(var.instance_name) >= 3 && length(var.instance_name) <= 63
+    error_message = "The name must be between 3 and 63 characters."
+  }
+
+  validation {
+    condition     = length(var.instance_name) >= 5 && length(var.instance_name) < 100
+    error_message = "The name must be between 5 and 99 characters."
+  }
+}
+
+variable "environment" {
+  description = "The environment, in any case"
+  type        = string
+
+  validation {
+    condition     = can(regex("(?i)^(dev|prod)$", var.environment))
+    error_message = "The environment must be dev or prod."
+  }
+
+  validation {
+    condition     = can(regex("^[[:alpha:]]+$", var.environment))
+    error_message = "The environment may only contain letters."
+  }
+
+  validation {
+    condition     = can(regex("^\\w{3,4}$", var.environment))
+    error_message = "The environment must be 3 or 4 characters."
+  }
+}
@@ -0,0 +1,17 @@
+terraform {
+  required_version = ">= 1.3"
+
+  required_providers {
+    google = {
+      source                = "hashicorp/google"
+      version               = ">= 4.64, < 6"
+      configuration_aliases = [google.us, google.eu]
+    }
+    random = {
+      version = "~> 3.0"
+    }
+    kubernetes = {
+      source = "hashicorp/kubernetes"
+    }
+  }
+}
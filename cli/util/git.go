@@ -3,15 +3,33 @@ package util
 import (
 	"fmt"
 	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
 )
 
 const defaultRemote = "origin"
 
+// scpLikeURL matches scp-like remotes e.g. git@github.com:foo/bar.git
+var scpLikeURL = regexp.MustCompile(`^(?:[\w.-]+@)?([\w.-]+\.[\w.-]+):(.+)$`)
+
+// semverRegEx matches semantic versions with an optional "v" prefix
+var semverRegEx = regexp.MustCompile(`^v?([0-9]+)\.([0-9]+)\.([0-9]+)(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
 // getRepoName finds upstream repo name from a given repo directory
 func GetRepoName(repoUrl string) (string, error) {
+	repoUrl, err := NormalizeRepoUrl(repoUrl)
+	if err != nil {
+		return "", err
+	}
+
 	u, err := url.Parse(repoUrl)
 	if err != nil {
 		return "", fmt.Errorf("malformed repo URL: %w", err)
@@ -24,23 +42,361 @@ func GetRepoName(repoUrl string) (string, error) {
 	return repoName, nil
 }
 
-// getRepoName finds upstream repo name from a given repo directory
+// GetRepoUrl finds the upstream repo URL from a given repo directory.
+// The "origin" remote is preferred and the first remote by name is
+// used otherwise.
 func GetRepoUrl(dir string) (string, error) {
 	opt := &git.PlainOpenOptions{DetectDotGit: true}
 	r, err := git.PlainOpenWithOptions(dir, opt)
 	if err != nil {
 		return "", fmt.Errorf("error opening git dir %s: %w", dir, err)
 	}
-	rm, err := r.Remote(defaultRemote)
+
+	remotes, err := r.Remotes()
 	if err != nil {
-		return "", fmt.Errorf("error finding remote %s in git dir %s: %w", defaultRemote, dir, err)
+		return "", fmt.Errorf("error listing remotes in git dir %s: %w", dir, err)
+	}
+
+	if len(remotes) == 0 {
+		return "", fmt.Errorf("no remotes found in git dir %s", dir)
 	}
 
+	sort.SliceStable(remotes, func(i, j int) bool {
+		ni, nj := remotes[i].Config().Name, remotes[j].Config().Name
+		if ni == defaultRemote || nj == defaultRemote {
+			return ni == defaultRemote
+		}
+
+		return ni < nj
+	})
+
 	// validate remote URL
-	remoteURL, err := url.Parse(rm.Config().URLs[0])
+	remoteURL, err := NormalizeRepoUrl(remotes[0].Config().URLs[0])
 	if err != nil {
 		return "", fmt.Errorf("error parsing remote URL: %w", err)
 	}
 
-	return remoteURL.String(), nil
+	return remoteURL, nil
+}
+
+// NormalizeRepoUrl converts SSH and scp-like remotes to https URLs so that
+// they can be browsed e.g. git@github.com:foo/bar.git is converted to
+// https://github.com/foo/bar.git. Credentials are removed from https URLs.
+func NormalizeRepoUrl(repoUrl string) (string, error) {
+	if !strings.Contains(repoUrl, "://") {
+		if m := scpLikeURL.FindStringSubmatch(repoUrl); m != nil {
+			return fmt.Sprintf("https://%s/%s", m[1], strings.TrimPrefix(m[2], "/")), nil
+		}
+	}
+
+	u, err := url.Parse(repoUrl)
+	if err != nil {
+		return "", fmt.Errorf("malformed repo URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "ssh", "git", "git+ssh":
+		// ports are specific to the SSH endpoint
+		u.Scheme = "https"
+		u.Host = u.Hostname()
+		u.User = nil
+	case "https", "http":
+		u.User = nil
+	}
+
+	return u.String(), nil
+}
+
+// GetRepoRef returns the tag for the commit checked out in the given repo
+// directory or an empty string if it isn't tagged. The highest semver tag
+// is returned if there are several.
+func GetRepoRef(dir string) (string, error) {
+	var c *TagCache
+	return c.RepoRef(dir)
+}
+
+// RepoRef is GetRepoRef with the tags of the repo resolved through the cache.
+func (c *TagCache) RepoRef(dir string) (string, error) {
+	c.lock()
+	defer c.unlock()
+	t, err := c.repoTags(dir)
+	if err != nil {
+		return "", err
+	}
+
+	// the commit hash isn't recorded since committing the metadata
+	// would move HEAD and leave it stale
+	headTags := t.byCommit[t.head]
+	if len(headTags) == 0 {
+		return "", nil
+	}
+
+	ref := headTags[0]
+	for _, t := range headTags[1:] {
+		if compareTags(t, ref) > 0 {
+			ref = t
+		}
+	}
+
+	return ref, nil
+}
+
+// compareTags orders tags by semver with tags that aren't semver lower
+// than the ones that are
+func compareTags(a, b string) int {
+	switch sa, sb := IsSemver(a), IsSemver(b); {
+	case sa && !sb:
+		return 1
+	case !sa && sb:
+		return -1
+	}
+
+	return CompareSemver(a, b)
+}
+
+// GetRepoRoot returns the root of the worktree of the repo that the given
+// directory is in
+func GetRepoRoot(dir string) (string, error) {
+	opt := &git.PlainOpenOptions{DetectDotGit: true}
+	r, err := git.PlainOpenWithOptions(dir, opt)
+	if err != nil {
+		return "", fmt.Errorf("error opening git dir %s: %w", dir, err)
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("error opening worktree of git dir %s: %w", dir, err)
+	}
+
+	return w.Filesystem.Root(), nil
+}
+
+// GetHeadSemverTag returns the semver tag with the given prefix e.g.
+// "foo-v1.2.0" for "foo-" on the commit checked out in the given repo
+// directory or an empty string if there isn't one. The highest version is
+// returned if there are several.
+func GetHeadSemverTag(dir, prefix string) (string, error) {
+	var c *TagCache
+	return c.HeadSemverTag(dir, prefix)
+}
+
+// HeadSemverTag is GetHeadSemverTag with the tags of the repo resolved
+// through the cache.
+func (c *TagCache) HeadSemverTag(dir, prefix string) (string, error) {
+	c.lock()
+	defer c.unlock()
+	t, err := c.repoTags(dir)
+	if err != nil {
+		return "", err
+	}
+
+	return highestSemverTag(t.byCommit[t.head], prefix), nil
+}
+
+// GetNearestSemverTag returns the semver tag with the given prefix e.g.
+// v1.2.0 for no prefix on the closest commit reachable from HEAD in the
+// given repo directory or an empty string if there isn't one. The highest
+// version is returned if a commit has several semver tags.
+func GetNearestSemverTag(dir, prefix string) (string, error) {
+	var c *TagCache
+	return c.NearestSemverTag(dir, prefix)
+}
+
+// NearestSemverTag is GetNearestSemverTag with the tags of the repo and the
+// nearest tag for each prefix resolved through the cache.
+func (c *TagCache) NearestSemverTag(dir, prefix string) (string, error) {
+	c.lock()
+	defer c.unlock()
+	t, err := c.repoTags(dir)
+	if err != nil {
+		return "", err
+	}
+
+	if nearest, ok := t.nearest[prefix]; ok {
+		return nearest, nil
+	}
+
+	commits, err := t.repo.Log(&git.LogOptions{From: t.head})
+	if err != nil {
+		return "", fmt.Errorf("error reading history in git dir %s: %w", dir, err)
+	}
+
+	// the history is only read up to the nearest tagged commit
+	nearest := ""
+	err = commits.ForEach(func(c *object.Commit) error {
+		nearest = highestSemverTag(t.byCommit[c.Hash], prefix)
+		if nearest != "" {
+			return storer.ErrStop
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error reading history in git dir %s: %w", dir, err)
+	}
+
+	t.nearest[prefix] = nearest
+	return nearest, nil
+}
+
+// highestSemverTag returns the tag with the highest version out of the
+// tags that are a semver with the given prefix
+func highestSemverTag(tags []string, prefix string) string {
+	highest := ""
+	for _, t := range tags {
+		if !strings.HasPrefix(t, prefix) || !IsSemver(strings.TrimPrefix(t, prefix)) {
+			continue
+		}
+
+		if highest == "" || CompareSemver(strings.TrimPrefix(t, prefix), strings.TrimPrefix(highest, prefix)) > 0 {
+			highest = t
+		}
+	}
+
+	return highest
+}
+
+// IsSemver checks if a version is a semantic version with an optional
+// "v" prefix e.g. v1.2.0 or 1.2.0-beta.1
+func IsSemver(version string) bool {
+	return semverRegEx.MatchString(version)
+}
+
+// CompareSemver compares two semantic versions and returns -1, 0 or 1 if a
+// is lower than, equal to or higher than b. Pre-release versions are lower
+// than the release and are compared lexically.
+func CompareSemver(a, b string) int {
+	ma, mb := semverRegEx.FindStringSubmatch(a), semverRegEx.FindStringSubmatch(b)
+	if ma == nil || mb == nil {
+		return strings.Compare(a, b)
+	}
+
+	for i := 1; i <= 3; i++ {
+		na, _ := strconv.Atoi(ma[i])
+		nb, _ := strconv.Atoi(mb[i])
+		if na != nb {
+			if na < nb {
+				return -1
+			}
+
+			return 1
+		}
+	}
+
+	switch {
+	case ma[4] == mb[4]:
+		return 0
+	case ma[4] == "":
+		return 1
+	case mb[4] == "":
+		return -1
+	}
+
+	return strings.Compare(ma[4], mb[4])
+}
+
+// TagCache caches the tags of git repos so that they are resolved once per
+// repo e.g. for every blueprint in a monorepo. Tags created after they are
+// resolved aren't seen, so a cache is meant to be used for a single run.
+// A nil cache resolves tags on every call. It is safe for concurrent use.
+type TagCache struct {
+	mu    sync.Mutex
+	repos map[string]*repoTags
+}
+
+// NewTagCache returns an empty tag cache
+func NewTagCache() *TagCache {
+	return &TagCache{repos: make(map[string]*repoTags)}
+}
+
+// repoTags holds the tags of a repo checked out at a commit
+type repoTags struct {
+	repo     *git.Repository
+	head     plumbing.Hash
+	byCommit map[plumbing.Hash][]string
+
+	// nearest are the nearest semver tags reachable from head by prefix
+	nearest map[string]string
+}
+
+func (c *TagCache) lock() {
+	if c != nil {
+		c.mu.Lock()
+	}
+}
+
+func (c *TagCache) unlock() {
+	if c != nil {
+		c.mu.Unlock()
+	}
+}
+
+// repoTags returns the tags of the repo that the given directory is in.
+// The cache must be locked.
+func (c *TagCache) repoTags(dir string) (*repoTags, error) {
+	opt := &git.PlainOpenOptions{DetectDotGit: true}
+	r, err := git.PlainOpenWithOptions(dir, opt)
+	if err != nil {
+		return nil, fmt.Errorf("error opening git dir %s: %w", dir, err)
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return nil, fmt.Errorf("error resolving HEAD in git dir %s: %w", dir, err)
+	}
+
+	root := dir
+	if w, err := r.Worktree(); err == nil {
+		root = w.Filesystem.Root()
+	}
+
+	key := root + "@" + head.Hash().String()
+	if c != nil {
+		if t, ok := c.repos[key]; ok {
+			return t, nil
+		}
+	}
+
+	byCommit, err := tagsByCommit(r)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving tags in git dir %s: %w", dir, err)
+	}
+
+	t := &repoTags{repo: r, head: head.Hash(), byCommit: byCommit, nearest: make(map[string]string)}
+	if c != nil {
+		c.repos[key] = t
+	}
+
+	return t, nil
+}
+
+// tagsByCommit maps commits to the names of the tags pointing at them
+func tagsByCommit(r *git.Repository) (map[plumbing.Hash][]string, error) {
+	tags, err := r.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	commitTags := make(map[plumbing.Hash][]string)
+	err = tags.ForEach(func(t *plumbing.Reference) error {
+		hash := t.Hash()
+
+		// annotated tags point to a tag object instead of the commit
+		tagObj, err := r.TagObject(hash)
+		if err == nil {
+			c, err := tagObj.Commit()
+			if err != nil {
+				return nil
+			}
+
+			hash = c.Hash
+		} else if err != plumbing.ErrObjectNotFound {
+			return err
+		}
+
+		commitTags[hash] = append(commitTags[hash], t.Name().Short())
+		return nil
+	})
+
+	return commitTags, err
 }